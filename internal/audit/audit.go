@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+// Entry describes one audited action.
+type Entry struct {
+	OrganizationID *uint
+	Action         string
+	TargetType     string
+	TargetID       string
+	SubjectID      *uint // user the action concerns, when it is not the actor
+	Metadata       map[string]any
+}
+
+// Record stores an entry. The actor, IP address and user agent are taken
+// from the request; the actor stays empty for unauthenticated requests.
+// While impersonating, the actor is the real caller and the impersonated
+// user is stored as the subject.
+func Record(db *gorm.DB, c *fiber.Ctx, entry Entry) error {
+	log := models.AuditLog{
+		OrganizationID: entry.OrganizationID,
+		Action:         entry.Action,
+		TargetType:     entry.TargetType,
+		TargetID:       entry.TargetID,
+		SubjectID:      entry.SubjectID,
+		IPAddress:      c.IP(),
+		UserAgent:      c.Get(fiber.HeaderUserAgent),
+	}
+	if claims, err := jwt.GetClaimsFromFiberContext(c); err == nil {
+		log.ActorID = &claims.UserID
+		if claims.Act != nil {
+			log.ActorID = &claims.Act.UserID
+			log.SubjectID = &claims.UserID
+		}
+	}
+	if len(entry.Metadata) > 0 {
+		metadata, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit metadata: %w", err)
+		}
+		log.Metadata = string(metadata)
+	}
+	return db.Create(&log).Error
+}
@@ -0,0 +1,117 @@
+package authz
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"gorm.io/gorm"
+)
+
+// Project permissions.
+const (
+	PermProjectManage    = "project.manage"
+	PermTicketView       = "ticket.view"
+	PermTicketCreate     = "ticket.create"
+	PermTicketEdit       = "ticket.edit"
+	PermTicketTransition = "ticket.transition"
+	PermTicketAssign     = "ticket.assign"
+	PermTicketDelete     = "ticket.delete"
+	PermTicketComment    = "ticket.comment"
+	PermTimelogView      = "timelog.view"
+	PermTimelogLog       = "timelog.log"
+	PermSprintManage     = "sprint.manage"
+)
+
+// Permissions lists every permission a scheme can grant.
+var Permissions = []string{
+	PermProjectManage,
+	PermTicketView,
+	PermTicketCreate,
+	PermTicketEdit,
+	PermTicketTransition,
+	PermTicketAssign,
+	PermTicketDelete,
+	PermTicketComment,
+	PermTimelogView,
+	PermTimelogLog,
+	PermSprintManage,
+}
+
+// Scheme maps project roles to the permissions they grant. Besides the
+// built-in roles it may define its own, e.g. "qa" or "contractor", which
+// project members can then be given.
+type Scheme map[string][]string
+
+// DefaultScheme applies to projects when neither the project nor its
+// organization has chosen a scheme.
+var DefaultScheme = Scheme{
+	ProjectMember: {
+		PermTicketView, PermTicketCreate, PermTicketEdit, PermTicketTransition,
+		PermTicketAssign, PermTicketComment, PermTimelogView, PermTimelogLog, PermSprintManage,
+	},
+	ProjectViewer: {PermTicketView, PermTimelogView},
+}
+
+// IsPermission reports whether p is a known permission.
+func IsPermission(p string) bool {
+	return slices.Contains(Permissions, p)
+}
+
+// Grants returns the permissions of role under the scheme. Project owners
+// and admins always hold every permission, so a scheme cannot lock them out.
+func (s Scheme) Grants(role string) []string {
+	switch role {
+	case "":
+		return nil
+	case ProjectOwner, ProjectAdmin:
+		return Permissions
+	case "developer":
+		role = ProjectMember
+	}
+	return s[role]
+}
+
+// HasRole reports whether project members can be given role under the
+// scheme: a built-in role or one the scheme defines.
+func (s Scheme) HasRole(role string) bool {
+	switch role {
+	case ProjectOwner, ProjectAdmin, ProjectMember, ProjectViewer:
+		return true
+	}
+	_, ok := s[role]
+	return ok
+}
+
+// ParseScheme decodes the roles of a stored permission scheme.
+func ParseScheme(scheme *models.PermissionScheme) (Scheme, error) {
+	roles := Scheme{}
+	if scheme.Roles == "" {
+		return roles, nil
+	}
+	err := json.Unmarshal([]byte(scheme.Roles), &roles)
+	return roles, err
+}
+
+// SchemeFor returns the scheme in force for a project: its own, else the
+// default scheme of its organization, else DefaultScheme.
+func SchemeFor(db *gorm.DB, project *models.Project) (Scheme, error) {
+	var scheme models.PermissionScheme
+	var err error
+	switch {
+	case project.PermissionSchemeID != nil:
+		err = db.First(&scheme, *project.PermissionSchemeID).Error
+	case project.OrganizationID != nil:
+		err = db.Where("organization_id = ? AND is_default = ?", *project.OrganizationID, true).First(&scheme).Error
+	default:
+		return DefaultScheme, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultScheme, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseScheme(&scheme)
+}
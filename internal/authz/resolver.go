@@ -0,0 +1,148 @@
+package authz
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"gorm.io/gorm"
+)
+
+// Resolver answers role questions for one user and remembers the answers,
+// so a request that checks the same organization or project several times
+// looks the membership up only once. It is not safe for concurrent use.
+type Resolver struct {
+	db       *gorm.DB
+	userID   uint
+	orgs     map[uint]string
+	projects map[uint]string
+	schemes  map[uint]Scheme
+}
+
+// NewResolver returns a resolver for userID.
+func NewResolver(db *gorm.DB, userID uint) *Resolver {
+	return &Resolver{
+		db:       db,
+		userID:   userID,
+		orgs:     map[uint]string{},
+		projects: map[uint]string{},
+		schemes:  map[uint]Scheme{},
+	}
+}
+
+// OrgRole returns the user's role in the organization, or "" if the user is
+// not an active member; invited members have no role until they accept.
+// Service account bots have the role of their account.
+func (r *Resolver) OrgRole(orgID uint) (string, error) {
+	if role, ok := r.orgs[orgID]; ok {
+		return role, nil
+	}
+
+	var member models.OrganizationMember
+	err := r.db.Select("organization_members.role").Scopes(ActiveMembers).
+		Where("organization_members.organization_id = ? AND organization_members.user_id = ?", orgID, r.userID).
+		First(&member).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	role := member.Role
+
+	if role == "" {
+		var account models.ServiceAccount
+		err := r.db.Select("role").Where("organization_id = ? AND user_id = ? AND disabled_at IS NULL", orgID, r.userID).First(&account).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+		role = account.Role
+	}
+
+	r.orgs[orgID] = role
+	return role, nil
+}
+
+// ProjectRole returns the user's effective role in the project, or "" if
+// the user may not see it:
+//   - the project owner and the organization's owners and admins are
+//     project owners and admins respectively;
+//   - project members have their project role;
+//   - other organization members, but not guests, can view projects that
+//     are not private.
+//
+// Projects of an organization are only open to its active members, so
+// ownership and project membership lapse when someone leaves.
+func (r *Resolver) ProjectRole(project *models.Project) (string, error) {
+	if role, ok := r.projects[project.ID]; ok {
+		return role, nil
+	}
+
+	role, err := r.projectRole(project)
+	if err != nil {
+		return "", err
+	}
+	r.projects[project.ID] = role
+	return role, nil
+}
+
+func (r *Resolver) projectRole(project *models.Project) (string, error) {
+	var orgRole string
+	if project.OrganizationID != nil {
+		var err error
+		if orgRole, err = r.OrgRole(*project.OrganizationID); err != nil {
+			return "", err
+		}
+		if orgRole == "" {
+			return "", nil
+		}
+	}
+
+	if project.OwnerID == r.userID {
+		return ProjectOwner, nil
+	}
+	if OrgRoleAtLeast(orgRole, OrgAdmin) {
+		return ProjectAdmin, nil
+	}
+
+	var member models.ProjectMember
+	err := r.db.Select("role").Where("project_id = ? AND user_id = ?", project.ID, r.userID).First(&member).Error
+	if err == nil {
+		return member.Role, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	if !project.IsPrivate && OrgRoleAtLeast(orgRole, OrgMember) {
+		return ProjectViewer, nil
+	}
+	return "", nil
+}
+
+// CanViewProject reports whether the user may see the project at all.
+func (r *Resolver) CanViewProject(project *models.Project) (bool, error) {
+	role, err := r.ProjectRole(project)
+	return role != "", err
+}
+
+// Permissions returns what the user may do in the project under the
+// project's permission scheme. It is empty for projects the user cannot see.
+func (r *Resolver) Permissions(project *models.Project) ([]string, error) {
+	role, err := r.ProjectRole(project)
+	if err != nil || role == "" {
+		return nil, err
+	}
+
+	scheme, ok := r.schemes[project.ID]
+	if !ok {
+		if scheme, err = SchemeFor(r.db, project); err != nil {
+			return nil, err
+		}
+		r.schemes[project.ID] = scheme
+	}
+	return scheme.Grants(role), nil
+}
+
+// Can reports whether the user holds permission in the project.
+func (r *Resolver) Can(project *models.Project, permission string) (bool, error) {
+	permissions, err := r.Permissions(project)
+	return slices.Contains(permissions, permission), err
+}
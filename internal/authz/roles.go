@@ -0,0 +1,80 @@
+// Package authz resolves what a user may do in an organization or project.
+package authz
+
+import "fmt"
+
+// Organization roles.
+const (
+	OrgOwner  = "owner"
+	OrgAdmin  = "admin"
+	OrgMember = "member"
+	OrgGuest  = "guest"
+)
+
+// Project roles.
+const (
+	ProjectOwner  = "owner"
+	ProjectAdmin  = "admin"
+	ProjectMember = "member"
+	ProjectViewer = "viewer"
+)
+
+// Organization member statuses, the names in member_statuses.
+const (
+	MemberActive  = "active"
+	MemberInvited = "invited"
+)
+
+// Roles are ranked so that every role includes the ones below it.
+var (
+	orgRoleRank = map[string]int{
+		OrgGuest:  1,
+		OrgMember: 2,
+		OrgAdmin:  3,
+		OrgOwner:  4,
+	}
+	projectRoleRank = map[string]int{
+		ProjectViewer: 1,
+		ProjectMember: 2,
+		"developer":   2, // earlier name of member
+		ProjectAdmin:  3,
+		ProjectOwner:  4,
+	}
+)
+
+// OrgRoleAtLeast reports whether role includes min. An empty role, i.e. no
+// membership, includes nothing.
+func OrgRoleAtLeast(role, min string) bool {
+	return role != "" && orgRoleRank[role] >= orgRoleRank[min]
+}
+
+// ProjectRoleAtLeast reports whether role includes min. Custom roles of a
+// permission scheme rank as viewers; what else they may do is up to the
+// scheme.
+func ProjectRoleAtLeast(role, min string) bool {
+	if role == "" {
+		return false
+	}
+	rank, ok := projectRoleRank[role]
+	if !ok {
+		rank = projectRoleRank[ProjectViewer]
+	}
+	return rank >= projectRoleRank[min]
+}
+
+// MustOrgRole panics on an unknown organization role. Route guards call it
+// at setup so that a typo cannot silently deny or allow everything.
+func MustOrgRole(role string) string {
+	if _, ok := orgRoleRank[role]; !ok {
+		panic(fmt.Sprintf("authz: unknown organization role %q", role))
+	}
+	return role
+}
+
+// MustProjectRole panics on an unknown project role.
+func MustProjectRole(role string) string {
+	if _, ok := projectRoleRank[role]; !ok {
+		panic(fmt.Sprintf("authz: unknown project role %q", role))
+	}
+	return role
+}
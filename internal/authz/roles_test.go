@@ -0,0 +1,42 @@
+package authz
+
+import "testing"
+
+func TestOrgRoleAtLeast(t *testing.T) {
+	tests := []struct {
+		role, min string
+		want      bool
+	}{
+		{OrgOwner, OrgAdmin, true},
+		{OrgAdmin, OrgAdmin, true},
+		{OrgAdmin, OrgOwner, false},
+		{OrgMember, OrgAdmin, false},
+		{OrgGuest, OrgMember, false},
+		{"", OrgGuest, false},
+	}
+	for _, tt := range tests {
+		if got := OrgRoleAtLeast(tt.role, tt.min); got != tt.want {
+			t.Errorf("OrgRoleAtLeast(%q, %q) = %t, want %t", tt.role, tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestProjectRoleAtLeast(t *testing.T) {
+	tests := []struct {
+		role, min string
+		want      bool
+	}{
+		{ProjectOwner, ProjectAdmin, true},
+		{ProjectAdmin, ProjectOwner, false},
+		{ProjectMember, ProjectAdmin, false},
+		{"developer", ProjectMember, true},
+		{"qa", ProjectViewer, true}, // custom roles rank as viewers
+		{"qa", ProjectMember, false},
+		{"", ProjectViewer, false},
+	}
+	for _, tt := range tests {
+		if got := ProjectRoleAtLeast(tt.role, tt.min); got != tt.want {
+			t.Errorf("ProjectRoleAtLeast(%q, %q) = %t, want %t", tt.role, tt.min, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package authz
+
+import (
+	"gorm.io/gorm"
+)
+
+// orgIDsWithRole selects the organizations in which userID holds one of
+// roles, as an active member or through a service account.
+func orgIDsWithRole(db *gorm.DB, userID uint, roles []string) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Raw(
+		`SELECT organization_id FROM organization_members WHERE user_id = ? AND role IN ?
+		AND status_id IN (SELECT id FROM member_statuses WHERE name = ?)
+		UNION SELECT organization_id FROM service_accounts WHERE user_id = ? AND role IN ? AND disabled_at IS NULL AND deleted_at IS NULL`,
+		userID, roles, MemberActive, userID, roles,
+	)
+}
+
+// ActiveMembers limits a query on organization members to those whose
+// membership is active, leaving out pending invitations.
+func ActiveMembers(db *gorm.DB) *gorm.DB {
+	return db.Joins("JOIN member_statuses ON member_statuses.id = organization_members.status_id").
+		Where("member_statuses.name = ?", MemberActive)
+}
+
+// MemberOrganizations limits a query on organizations to the ones userID
+// belongs to, as a member or through a service account.
+func MemberOrganizations(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("organizations.id IN (?)", orgIDsWithRole(db, userID, []string{OrgOwner, OrgAdmin, OrgMember, OrgGuest}))
+	}
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotFound is returned by Get when the key does not exist or has expired.
+var ErrNotFound = errors.New("cache: key not found")
+
+// Store is a small key/value store with per-key expiry. It is backed by
+// Redis when configured and by an in-process map otherwise.
+type Store interface {
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX stores the value only if the key does not exist yet and
+	// reports whether it did so.
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	Get(ctx context.Context, key string) (string, error)
+	// Incr increments a counter and returns the new value. The ttl is only
+	// applied when the counter is created.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Default is the store used by the application.
+var Default Store = NewMemoryStore()
+
+// Init selects the Redis store when a client is available.
+func Init(client *redis.Client) {
+	if client != nil {
+		Default = NewRedisStore(client)
+	}
+}
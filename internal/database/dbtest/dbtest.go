@@ -0,0 +1,70 @@
+// Package dbtest connects integration tests to a Postgres database. Tests
+// using it are skipped unless TEST_DATABASE_URL names a database they may
+// write to.
+package dbtest
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	once  sync.Once
+	db    *gorm.DB
+	dbErr error
+)
+
+// Open returns the migrated test database with the default statuses.
+func Open(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	once.Do(func() {
+		db, dbErr = gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if dbErr == nil {
+			dbErr = migrate(db)
+		}
+	})
+	if dbErr != nil {
+		t.Fatalf("test database: %v", dbErr)
+	}
+	return db
+}
+
+func migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(models.All()...); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	// Same order as scripts/migration.go, so active keeps ID 1
+	for _, name := range []string{"active", "inactive", "suspended", "pending_verification"} {
+		if err := db.Where(models.UserStatus{Name: name}).Attrs(models.UserStatus{DisplayName: name}).
+			FirstOrCreate(&models.UserStatus{}).Error; err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{"active", "invited", "suspended", "inactive"} {
+		if err := db.Where(models.MemberStatus{Name: name}).Attrs(models.MemberStatus{DisplayName: name}).
+			FirstOrCreate(&models.MemberStatus{}).Error; err != nil {
+			return err
+		}
+	}
+	return db.Where(models.OrganizationStatus{Name: "active"}).Attrs(models.OrganizationStatus{DisplayName: "active"}).
+		FirstOrCreate(&models.OrganizationStatus{}).Error
+}
+
+// Unique returns prefix with a suffix that differs between test runs, for
+// emails, slugs and other unique columns.
+func Unique(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, time.Now().UnixNano())
+}
@@ -56,6 +56,12 @@ func NewManager(ctx context.Context, config *Config) (*Manager, error) {
 
 // initPrimaryDB initializes the primary PostgreSQL database
 func (m *Manager) initPrimaryDB() error {
+	// Share the connection Init opened from DATABASE_URL
+	if DB != nil {
+		m.Primary = DB
+		return nil
+	}
+
 	db, err := m.openPostgreSQL(&m.config.Primary)
 	if err != nil {
 		return fmt.Errorf("failed to open primary database: %w", err)
@@ -66,8 +72,14 @@ func (m *Manager) initPrimaryDB() error {
 	return nil
 }
 
-// initRedis initializes Redis connection
+// initRedis initializes Redis connection. Without a host the cache
+// stays nil and callers fall back to an in-process store.
 func (m *Manager) initRedis() error {
+	if m.config.Redis.Host == "" {
+		log.Println("Redis not configured, using in-memory cache")
+		return nil
+	}
+
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     m.config.Redis.Addr(),
 		Password: m.config.Redis.Password,
@@ -79,6 +91,7 @@ func (m *Manager) initRedis() error {
 	defer cancel()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
 		return fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is an opaque, rotating refresh token. Only the SHA-256 hash of
+// the token is stored. Every token issued from the same login shares a FamilyID.
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	FamilyID  string     `json:"family_id" gorm:"not null;index;size:36"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	DeviceID  string     `json:"device_id" gorm:"size:100;index"`
+	UserAgent string     `json:"user_agent" gorm:"size:500"`
+	IPAddress string     `json:"ip_address" gorm:"size:45"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at" gorm:"index"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// UserSession is one login of a user on a device. Its ID is the FamilyID of
+// the refresh tokens issued for it and the sid claim of its access tokens.
+type UserSession struct {
+	ID         string     `json:"id" gorm:"primaryKey;size:36"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	DeviceID   string     `json:"device_id" gorm:"size:100;index"`
+	UserAgent  string     `json:"user_agent" gorm:"size:500"`
+	IPAddress  string     `json:"ip_address" gorm:"size:45"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"index"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// PasswordResetToken is a single-use token sent by the forgot-password flow.
+// Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt    *time.Time `json:"used_at"`
+	RequestIP string     `json:"request_ip" gorm:"size:45"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// RecoveryCode is a single-use fallback for a user's second factor.
+// Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;size:64;index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// PersonalAccessToken lets scripts call the API as the user with a limited
+// set of scopes. Only the SHA-256 hash of the token is stored; TokenPrefix
+// keeps the first characters so users can tell their tokens apart.
+type PersonalAccessToken struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Name        string     `json:"name" gorm:"not null;size:100"`
+	TokenPrefix string     `json:"token_prefix" gorm:"not null;size:16"`
+	TokenHash   string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	Scopes      string     `json:"scopes" gorm:"not null;size:500"` // space separated
+	ExpiresAt   *time.Time `json:"expires_at" gorm:"index"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at" gorm:"index"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
@@ -20,9 +20,23 @@ func All() ModelList {
 		&UserAuthMethod{},
 		&UserPreference{},
 		&UserLocation{},
+		&RefreshToken{},
+		&UserSession{},
+		&PasswordResetToken{},
+		&RecoveryCode{},
+		&PersonalAccessToken{},
+		&OAuthClient{},
+		&OAuthGrant{},
+		&OAuthRefreshToken{},
 		// Organization models
 		&Organization{},
 		&OrganizationMember{},
+		&OrganizationInvitation{},
+		&ServiceAccount{},
+		&OrganizationSSO{},
+		&OrganizationDomain{},
+		&AuditLog{},
+		&PermissionScheme{},
 
 		// Project and Ticket models
 		&Project{},
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// OrganizationInvitation is an invite sent to an email address, which may
+// not have an account yet. Invitees that already have an account also get an
+// OrganizationMember row with the invited status, which becomes active on
+// acceptance. The emailed token is signed and names the invitation; tokens
+// issued before SentAt are void, so resending replaces the previous link.
+type OrganizationInvitation struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID uint       `json:"organization_id" gorm:"not null;index"`
+	Email          string     `json:"email" gorm:"not null;size:255;index"`
+	Role           string     `json:"role" gorm:"not null;default:'member'"` // owner, admin, member, guest
+	InvitedBy      uint       `json:"invited_by" gorm:"not null;index"`
+	SentAt         time.Time  `json:"sent_at"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null;index"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	AcceptedBy     *uint      `json:"accepted_by"`
+	DeclinedAt     *time.Time `json:"declined_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	Organization Organization `json:"organization" gorm:"foreignKey:OrganizationID"`
+	Inviter      User         `json:"inviter" gorm:"foreignKey:InvitedBy"`
+}
+
+func (OrganizationInvitation) TableName() string {
+	return "organization_invitations"
+}
+
+// IsPending reports whether the invitation can still be answered.
+func (i *OrganizationInvitation) IsPending() bool {
+	return i.AcceptedAt == nil && i.DeclinedAt == nil && i.RevokedAt == nil && time.Now().Before(i.ExpiresAt)
+}
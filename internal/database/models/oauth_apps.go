@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OAuthClient is a third-party application that acts on behalf of users
+// through the OAuth2 authorization code flow. Public clients, e.g. mobile
+// and single page apps, have no secret and rely on PKCE alone. Only the
+// SHA-256 hash of the client secret is stored.
+type OAuthClient struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	OwnerID      uint           `json:"owner_id" gorm:"not null;index"` // user who registered the app
+	Name         string         `json:"name" gorm:"not null;size:100"`
+	Description  string         `json:"description" gorm:"size:500"`
+	HomepageURL  string         `json:"homepage_url" gorm:"size:255"`
+	ClientID     string         `json:"client_id" gorm:"not null;uniqueIndex;size:64"`
+	SecretHash   string         `json:"-" gorm:"size:64"` // empty for public clients
+	Confidential bool           `json:"confidential" gorm:"not null;default:true"`
+	RedirectURIs string         `json:"redirect_uris" gorm:"type:text;not null"` // space separated, matched exactly
+	Scopes       string         `json:"scopes" gorm:"not null;size:500"`         // space separated scopes the app may request
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Owner User `json:"-" gorm:"foreignKey:OwnerID"`
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// OAuthGrant is a user's consent for an app. Revoking it ends every token
+// the app holds for the user; consenting again reuses the row.
+type OAuthGrant struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_oauth_grant_user_client"`
+	OAuthClientID uint       `json:"oauth_client_id" gorm:"not null;uniqueIndex:idx_oauth_grant_user_client;index"`
+	Scopes        string     `json:"scopes" gorm:"not null;size:500"` // space separated
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	RevokedAt     *time.Time `json:"revoked_at" gorm:"index"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Relationships
+	User   User        `json:"-" gorm:"foreignKey:UserID"`
+	Client OAuthClient `json:"client" gorm:"foreignKey:OAuthClientID"`
+}
+
+func (OAuthGrant) TableName() string {
+	return "oauth_grants"
+}
+
+// OAuthRefreshToken is an opaque refresh token issued to an app. Tokens
+// are rotated on every use; presenting a rotated token revokes the grant.
+type OAuthRefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	GrantID   uint       `json:"grant_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	Scopes    string     `json:"scopes" gorm:"not null;size:500"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at" gorm:"index"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relationships
+	Grant OAuthGrant `json:"-" gorm:"foreignKey:GrantID"`
+}
+
+func (OAuthRefreshToken) TableName() string {
+	return "oauth_refresh_tokens"
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PermissionScheme maps project roles to permissions, e.g. to let QA
+// transition tickets without deleting them. Schemes belong to an
+// organization; the one marked IsDefault applies to its projects that have
+// not chosen a scheme.
+type PermissionScheme struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	OrganizationID uint           `json:"organization_id" gorm:"not null;index"`
+	Name           string         `json:"name" gorm:"not null;size:100"`
+	Description    string         `json:"description" gorm:"size:500"`
+	IsDefault      bool           `json:"is_default" gorm:"not null;default:false"`
+	Roles          string         `json:"roles" gorm:"type:json;not null"` // {"qa": ["ticket.view", "ticket.transition"]}
+	CreatedBy      uint           `json:"created_by" gorm:"index"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Organization Organization `json:"-" gorm:"foreignKey:OrganizationID"`
+	Projects     []Project    `json:"projects,omitempty" gorm:"foreignKey:PermissionSchemeID"`
+}
+
+func (PermissionScheme) TableName() string {
+	return "permission_schemes"
+}
@@ -8,26 +8,30 @@ import (
 
 // Project represents a project in the system
 type Project struct {
-	ID             uint           `json:"id" gorm:"primaryKey"`
-	OrganizationID *uint          `json:"organization_id" gorm:"index"` // nullable สำหรับ personal projects
-	Name           string         `json:"name" gorm:"not null;size:255"`
-	Description    string         `json:"description" gorm:"type:text"`
-	Key            string         `json:"key" gorm:"not null;unique;size:10"` // เช่น "PROJ"
-	OwnerID        uint           `json:"owner_id" gorm:"not null;index"`
-	StatusID       uint           `json:"status_id" gorm:"not null;index;default:1"` // FK to project_statuses (1=active)
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	OrganizationID     *uint          `json:"organization_id" gorm:"index"` // nullable สำหรับ personal projects
+	Name               string         `json:"name" gorm:"not null;size:255"`
+	Description        string         `json:"description" gorm:"type:text"`
+	Key                string         `json:"key" gorm:"not null;unique;size:10"` // เช่น "PROJ"
+	OwnerID            uint           `json:"owner_id" gorm:"not null;index"`
+	StatusID           uint           `json:"status_id" gorm:"not null;index;default:1"` // FK to project_statuses (1=active)
+	IsPrivate          bool           `json:"is_private" gorm:"not null;default:false"`  // only visible to project members and org admins
+	PermissionSchemeID *uint          `json:"permission_scheme_id" gorm:"index"`         // nil uses the organization's default scheme
+	Settings           string         `json:"settings" gorm:"type:json;default:null"`    // overrides of the organization's settings
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
-	Status       ProjectStatus   `json:"status" gorm:"foreignKey:StatusID"`
-	Organization *Organization   `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
-	Owner        User            `json:"owner" gorm:"foreignKey:OwnerID"`
-	Members      []ProjectMember `json:"members,omitempty"`
-	Tickets      []Ticket        `json:"tickets,omitempty"`
-	Statuses     []TicketStatus  `json:"statuses,omitempty"`
-	Labels       []Label         `json:"labels,omitempty"`
-	Sprints      []Sprint        `json:"sprints,omitempty"`
+	Status           ProjectStatus     `json:"status" gorm:"foreignKey:StatusID"`
+	Organization     *Organization     `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
+	PermissionScheme *PermissionScheme `json:"permission_scheme,omitempty" gorm:"foreignKey:PermissionSchemeID"`
+	Owner            User              `json:"owner" gorm:"foreignKey:OwnerID"`
+	Members          []ProjectMember   `json:"members,omitempty"`
+	Tickets          []Ticket          `json:"tickets,omitempty"`
+	Statuses         []TicketStatus    `json:"statuses,omitempty"`
+	Labels           []Label           `json:"labels,omitempty"`
+	Sprints          []Sprint          `json:"sprints,omitempty"`
 }
 
 // ProjectMember represents project membership
@@ -35,7 +39,7 @@ type ProjectMember struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	ProjectID uint      `json:"project_id" gorm:"not null;index"`
 	UserID    uint      `json:"user_id" gorm:"not null;index"`
-	Role      string    `json:"role" gorm:"not null;default:'developer';index"` // admin, developer, viewer
+	Role      string    `json:"role" gorm:"not null;default:'member';index"` // owner, admin, member, viewer
 	JoinedAt  time.Time `json:"joined_at" gorm:"default:CURRENT_TIMESTAMP"`
 
 	// Relationships
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ServiceAccount is a non-human member of an organization, e.g. a CI
+// pipeline or chat bot. It acts through its own bot User, so its tickets and
+// comments are attributed like anyone else's, but it is not an
+// OrganizationMember and does not take a seat. Only the SHA-256 hash of the
+// client secret is stored.
+type ServiceAccount struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	OrganizationID uint           `json:"organization_id" gorm:"not null;index"`
+	UserID         uint           `json:"user_id" gorm:"not null;uniqueIndex"` // bot user the account acts as
+	Name           string         `json:"name" gorm:"not null;size:100"`
+	Description    string         `json:"description" gorm:"size:500"`
+	Role           string         `json:"role" gorm:"not null;default:'member'"`                                       // admin, member, guest
+	Scopes         string         `json:"scopes" gorm:"not null;size:500;default:'org:read project:read ticket:read'"` // space separated
+	ClientID       string         `json:"client_id" gorm:"not null;uniqueIndex;size:64"`
+	SecretHash     string         `json:"-" gorm:"not null;size:64"`
+	CreatedBy      uint           `json:"created_by" gorm:"index"`
+	LastUsedAt     *time.Time     `json:"last_used_at"`
+	DisabledAt     *time.Time     `json:"disabled_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Organization Organization `json:"organization" gorm:"foreignKey:OrganizationID"`
+	User         User         `json:"user" gorm:"foreignKey:UserID"`
+	Creator      *User        `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+}
+
+func (ServiceAccount) TableName() string {
+	return "service_accounts"
+}
+
+// AuditLog records security relevant actions. ActorID is the user who acted,
+// which for service accounts is their bot user.
+type AuditLog struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID *uint     `json:"organization_id" gorm:"index"`
+	ActorID        *uint     `json:"actor_id" gorm:"index"`
+	SubjectID      *uint     `json:"subject_id" gorm:"index"`               // user acted as, set while impersonating
+	Action         string    `json:"action" gorm:"not null;size:100;index"` // e.g. service_account.created
+	TargetType     string    `json:"target_type" gorm:"size:50"`
+	TargetID       string    `json:"target_id" gorm:"size:64;index"`
+	IPAddress      string    `json:"ip_address" gorm:"size:45"`
+	UserAgent      string    `json:"user_agent" gorm:"size:500"`
+	Metadata       string    `json:"metadata" gorm:"type:json;default:null"`
+	CreatedAt      time.Time `json:"created_at" gorm:"index"`
+
+	// Relationships
+	Actor   *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+	Subject *User `json:"subject,omitempty" gorm:"foreignKey:SubjectID"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// OrganizationSSO is the SAML identity provider of an enterprise
+// organization. Users signing in through it are provisioned as members with
+// DefaultRole. With ForceSSO, members whose email is in one of the
+// organization's verified domains can only sign in through the identity
+// provider.
+type OrganizationSSO struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID   uint      `json:"organization_id" gorm:"not null;uniqueIndex"`
+	Enabled          bool      `json:"enabled" gorm:"default:true"`
+	IdPEntityID      string    `json:"idp_entity_id" gorm:"size:500"`
+	IdPMetadata      string    `json:"-" gorm:"type:text;not null"` // uploaded metadata XML
+	ForceSSO         bool      `json:"force_sso" gorm:"default:false"`
+	DefaultRole      string    `json:"default_role" gorm:"not null;default:'member'"` // member, guest
+	AttributeMapping string    `json:"attribute_mapping" gorm:"type:json;default:null"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// Relationships
+	Organization Organization `json:"-" gorm:"foreignKey:OrganizationID"`
+}
+
+func (OrganizationSSO) TableName() string {
+	return "organization_sso"
+}
+
+// OrganizationDomain is an email domain claimed by an organization. It is
+// only used for single sign-on once VerifiedAt is set, which happens when
+// the TXT record carrying VerificationToken is found in the domain's DNS.
+// A domain can be verified by one organization at a time.
+type OrganizationDomain struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID    uint       `json:"organization_id" gorm:"not null;uniqueIndex:idx_organization_domain"`
+	Domain            string     `json:"domain" gorm:"not null;size:255;uniqueIndex:idx_organization_domain;uniqueIndex:idx_verified_domain,where:verified_at IS NOT NULL"` // lower case
+	VerificationToken string     `json:"-" gorm:"not null;size:64"`
+	VerifiedAt        *time.Time `json:"verified_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// Relationships
+	Organization Organization `json:"-" gorm:"foreignKey:OrganizationID"`
+}
+
+func (OrganizationDomain) TableName() string {
+	return "organization_domains"
+}
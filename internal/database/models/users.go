@@ -22,6 +22,8 @@ type User struct {
 	TimeZone           string         `json:"time_zone" gorm:"default:'UTC'"`
 	IsEmailVerified    bool           `json:"is_email_verified" gorm:"default:false"`
 	IsPhoneVerified    bool           `json:"is_phone_verified" gorm:"default:false"`
+	IsAdmin            bool           `json:"is_admin" gorm:"default:false"`             // platform administrator
+	IsBot              bool           `json:"is_bot" gorm:"default:false;index"`         // identity of a service account
 	StatusID           uint           `json:"status_id" gorm:"not null;index;default:1"` // FK to user_statuses (1=active)
 	LastLoginAt        *time.Time     `json:"last_login_at" gorm:"index"`
 	CreatedAt          time.Time      `json:"created_at"`
@@ -52,7 +54,7 @@ type User struct {
 type UserAuthMethod struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	UserID       uint           `json:"user_id" gorm:"not null;index"`
-	AuthType     string         `json:"auth_type" gorm:"not null;index"` // password, oauth, sso
+	AuthType     string         `json:"auth_type" gorm:"not null;index"` // password, oauth, sso, totp, magic_link
 	AuthProvider *string        `json:"auth_provider" gorm:"index;default:null"`  // google, github, etc.
 	ProviderID   *string        `json:"provider_id" gorm:"default:null"`          // OAuth ID
 	IsPrimary    bool           `json:"is_primary" gorm:"default:false"`
@@ -61,14 +63,18 @@ type UserAuthMethod struct {
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// For password auth
-	PasswordHash string `json:"-" gorm:"default:null"`
-	PasswordSalt string `json:"-" gorm:"default:null"`
+	PasswordHash string `json:"-" gorm:"default:null"` // argon2id PHC string or legacy bcrypt hash
+	PasswordSalt string `json:"-" gorm:"default:null"` // unused, salts are part of PasswordHash
 
 	// For OAuth
 	AccessToken  *string    `json:"-" gorm:"default:null"`
 	RefreshToken *string    `json:"-" gorm:"default:null"`
 	TokenExpiry  *time.Time `json:"-" gorm:"default:null"`
 
+	// For TOTP
+	Secret      *string    `json:"-" gorm:"default:null"` // encrypted shared secret
+	ConfirmedAt *time.Time `json:"confirmed_at" gorm:"default:null"`
+
 	// Relationships
 	User User `json:"user" gorm:"foreignKey:UserID"`
 }
@@ -89,8 +95,9 @@ type Organization struct {
 
 	// Relationships
 	Status   OrganizationStatus   `json:"status" gorm:"foreignKey:StatusID"`
-	Members  []OrganizationMember `json:"members,omitempty" gorm:"foreignKey:OrganizationID"`
-	Projects []Project            `json:"projects,omitempty" gorm:"foreignKey:OrganizationID"`
+	Members         []OrganizationMember `json:"members,omitempty" gorm:"foreignKey:OrganizationID"`
+	ServiceAccounts []ServiceAccount     `json:"service_accounts,omitempty" gorm:"foreignKey:OrganizationID"`
+	Projects        []Project            `json:"projects,omitempty" gorm:"foreignKey:OrganizationID"`
 }
 
 // OrganizationMember for role-based access
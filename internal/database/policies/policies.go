@@ -0,0 +1,83 @@
+package policies
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Policy is the name of the row-level security policy on every tenant table
+const Policy = "tenant_isolation"
+
+// Functions read the tenant a transaction was scoped to with set_config.
+// They return NULL when it was not scoped, which matches no tenant row.
+var Functions = map[string]string{
+	"app_current_org": `CREATE OR REPLACE FUNCTION app_current_org() RETURNS bigint
+		LANGUAGE sql STABLE AS $$ SELECT NULLIF(current_setting('app.current_org', true), '')::bigint $$;`,
+	"app_current_user": `CREATE OR REPLACE FUNCTION app_current_user() RETURNS bigint
+		LANGUAGE sql STABLE AS $$ SELECT NULLIF(current_setting('app.current_user', true), '')::bigint $$;`,
+}
+
+// Tables maps each tenant table to the rows its policy lets through.
+// Project data follows projects, and ticket data follows tickets, so the
+// subqueries inherit the isolation of the table they read.
+//
+// Organization-level tables have no policy, because they are read before a
+// tenant is known and every such query names its organization itself:
+//   - organizations, organization_members and service_accounts resolve the
+//     :orgSlug of a request and the caller's role in it, list a user's
+//     organizations and authenticate service accounts;
+//   - organization_invitations are looked up by token and listed for the
+//     invitee, who is not a member yet;
+//   - organization_sso and organization_domains are read at sign-in to find
+//     the organization of an email address;
+//   - audit_logs are written for requests outside any organization too,
+//     e.g. impersonation and login events.
+var Tables = map[string]string{
+	"projects":           `organization_id = app_current_org() OR (organization_id IS NULL AND owner_id = app_current_user())`,
+	"permission_schemes": `organization_id = app_current_org()`,
+	"project_members":    `EXISTS (SELECT 1 FROM projects p WHERE p.id = project_members.project_id)`,
+	"epics":              `EXISTS (SELECT 1 FROM projects p WHERE p.id = epics.project_id)`,
+	"sprints":            `EXISTS (SELECT 1 FROM projects p WHERE p.id = sprints.project_id)`,
+	"labels":             `EXISTS (SELECT 1 FROM projects p WHERE p.id = labels.project_id)`,
+	"ticket_statuses":    `EXISTS (SELECT 1 FROM projects p WHERE p.id = ticket_statuses.project_id)`,
+	"tickets":            `EXISTS (SELECT 1 FROM projects p WHERE p.id = tickets.project_id)`,
+	"ticket_comments":    `EXISTS (SELECT 1 FROM tickets t WHERE t.id = ticket_comments.ticket_id)`,
+	"ticket_attachments": `EXISTS (SELECT 1 FROM tickets t WHERE t.id = ticket_attachments.ticket_id)`,
+	"time_logs":          `EXISTS (SELECT 1 FROM tickets t WHERE t.id = time_logs.ticket_id)`,
+}
+
+// Apply creates the functions and the policy of every tenant table. The
+// policies are only enforced with enforce, otherwise row-level security is
+// turned off again on each table. Superusers and roles with BYPASSRLS are
+// never subject to them, so the API must connect as an ordinary role.
+func Apply(db *gorm.DB, enforce bool) error {
+	for name, query := range Functions {
+		if err := db.Exec(query).Error; err != nil {
+			return fmt.Errorf("create function %s: %w", name, err)
+		}
+	}
+
+	for table, using := range Tables {
+		statements := []string{
+			fmt.Sprintf("DROP POLICY IF EXISTS %s ON %s;", Policy, table),
+			fmt.Sprintf("CREATE POLICY %s ON %s USING (%s) WITH CHECK (%s);", Policy, table, using, using),
+		}
+		if enforce {
+			// FORCE applies the policy to the table owner the API connects as
+			statements = append(statements,
+				fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;", table),
+				fmt.Sprintf("ALTER TABLE %s FORCE ROW LEVEL SECURITY;", table))
+		} else {
+			statements = append(statements,
+				fmt.Sprintf("ALTER TABLE %s NO FORCE ROW LEVEL SECURITY;", table),
+				fmt.Sprintf("ALTER TABLE %s DISABLE ROW LEVEL SECURITY;", table))
+		}
+		for _, stmt := range statements {
+			if err := db.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("manage policy on %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
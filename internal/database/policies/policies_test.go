@@ -0,0 +1,271 @@
+package policies_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/dbtest"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/policies"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// tenantRole is the role the tests read as when connected as a superuser,
+// who bypasses row-level security even when it is forced.
+const tenantRole = "tenant_rls_test"
+
+// tenant is an organization with its owner and one project, ticket and
+// comment.
+type tenant struct {
+	org     models.Organization
+	user    models.User
+	project models.Project
+	ticket  models.Ticket
+	comment models.TicketComment
+}
+
+// lookup loads the named row of a lookup table, creating it with attrs if
+// the database was not seeded.
+func lookup(t *testing.T, db *gorm.DB, row any, name string, attrs any) {
+	t.Helper()
+	if err := db.Where("name = ?", name).Attrs(attrs).FirstOrCreate(row).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTenant is created before the policies are enforced.
+func newTenant(t *testing.T, db *gorm.DB, projectStatus, ticketType, priority uint) *tenant {
+	t.Helper()
+	suffix := time.Now().UnixNano()
+	tn := &tenant{
+		org:  models.Organization{Name: "Tenant", Slug: fmt.Sprintf("tenant-%d", suffix), Settings: "{}"},
+		user: models.User{Email: fmt.Sprintf("owner-%d@tenant.example", suffix)},
+	}
+	if err := db.Create(&tn.org).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&tn.user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.OrganizationMember{OrganizationID: tn.org.ID, UserID: tn.user.ID, Role: "owner"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Project keys are at most 10 characters
+	key := fmt.Sprintf("T%09d", suffix%1e9)
+	tn.project = models.Project{OrganizationID: &tn.org.ID, Name: "Project", Key: key, OwnerID: tn.user.ID, StatusID: projectStatus}
+	if err := db.Create(&tn.project).Error; err != nil {
+		t.Fatal(err)
+	}
+	status := models.TicketStatus{ProjectID: tn.project.ID, Name: "To Do", IsDefault: true}
+	if err := db.Create(&status).Error; err != nil {
+		t.Fatal(err)
+	}
+	tn.ticket = models.Ticket{
+		ProjectID: tn.project.ID, Title: "Ticket", TicketKey: key + "-1",
+		TypeID: ticketType, StatusID: status.ID, PriorityID: priority, ReporterID: tn.user.ID,
+	}
+	if err := db.Create(&tn.ticket).Error; err != nil {
+		t.Fatal(err)
+	}
+	tn.comment = models.TicketComment{TicketID: tn.ticket.ID, UserID: tn.user.ID, Content: "Comment"}
+	if err := db.Create(&tn.comment).Error; err != nil {
+		t.Fatal(err)
+	}
+	return tn
+}
+
+// enforce turns the policies on for the rest of the test. Superusers
+// bypass them, so they read as tenantRole instead.
+func enforce(t *testing.T, db *gorm.DB) (asTenant func(tx *gorm.DB) error, superuser bool) {
+	t.Helper()
+	if err := policies.Apply(db, true); err != nil {
+		t.Fatalf("apply policies: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := policies.Apply(db, false); err != nil {
+			t.Errorf("turn policies off: %v", err)
+		}
+	})
+
+	if err := db.Raw("SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&superuser).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !superuser {
+		return func(tx *gorm.DB) error { return nil }, false
+	}
+
+	statements := []string{
+		fmt.Sprintf(`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = '%s') THEN CREATE ROLE %s NOLOGIN; END IF;
+		END $$;`, tenantRole, tenantRole),
+		fmt.Sprintf("GRANT SELECT, INSERT ON ALL TABLES IN SCHEMA public TO %s;", tenantRole),
+		fmt.Sprintf("GRANT USAGE ON ALL SEQUENCES IN SCHEMA public TO %s;", tenantRole),
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("prepare %s: %v", tenantRole, err)
+		}
+	}
+	return func(tx *gorm.DB) error {
+		return tx.Exec("SET LOCAL ROLE " + tenantRole).Error
+	}, true
+}
+
+// tenantConnection returns a handle for code that opens its own
+// transactions. For a superuser it is a single connection switched to
+// tenantRole for good.
+func tenantConnection(t *testing.T, db *gorm.DB, superuser bool) *gorm.DB {
+	t.Helper()
+	if !superuser {
+		return db
+	}
+	conn, err := gorm.Open(postgres.Open(os.Getenv("TEST_DATABASE_URL")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetMaxIdleConns(1)
+	if err := conn.Exec("SET ROLE " + tenantRole).Error; err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// visible returns which of ids the transaction can read from table.
+func visible(t *testing.T, tx *gorm.DB, table string, ids ...uint) []uint {
+	t.Helper()
+	var found []uint
+	if err := tx.Table(table).Where("id IN ?", ids).Order("id").Pluck("id", &found).Error; err != nil {
+		t.Fatalf("read %s: %v", table, err)
+	}
+	return found
+}
+
+func TestTenantIsolation(t *testing.T) {
+	db := dbtest.Open(t)
+
+	var projectStatus models.ProjectStatus
+	lookup(t, db, &projectStatus, "active", models.ProjectStatus{Name: "active", DisplayName: "Active"})
+	var ticketType models.TicketType
+	lookup(t, db, &ticketType, "task", models.TicketType{Name: "task", DisplayName: "Task"})
+	var priority models.Priority
+	lookup(t, db, &priority, "medium", models.Priority{Name: "medium", DisplayName: "Medium", Level: 2})
+
+	a := newTenant(t, db, projectStatus.ID, ticketType.ID, priority.ID)
+	b := newTenant(t, db, projectStatus.ID, ticketType.ID, priority.ID)
+	asTenant, superuser := enforce(t, db)
+
+	tables := []struct {
+		name string
+		a, b uint
+	}{
+		{"projects", a.project.ID, b.project.ID},
+		{"tickets", a.ticket.ID, b.ticket.ID},
+		{"ticket_comments", a.comment.ID, b.comment.ID},
+	}
+
+	t.Run("scoped to a tenant", func(t *testing.T) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := asTenant(tx); err != nil {
+				return err
+			}
+			if err := database.SetTenant(tx, a.org.ID, a.user.ID); err != nil {
+				return err
+			}
+			for _, table := range tables {
+				if got := visible(t, tx, table.name, table.a, table.b); len(got) != 1 || got[0] != table.a {
+					t.Errorf("%s: organization A sees %v, want only its own row %d", table.name, got, table.a)
+				}
+			}
+
+			// Nor can it write into organization B's project
+			err := tx.Transaction(func(nested *gorm.DB) error {
+				return nested.Create(&models.TicketComment{TicketID: b.ticket.ID, UserID: a.user.ID, Content: "Intruder"}).Error
+			})
+			if err == nil || !strings.Contains(err.Error(), "row-level security") {
+				t.Errorf("organization A commenting on a ticket of organization B: err = %v, want a row-level security violation", err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("not scoped", func(t *testing.T) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := asTenant(tx); err != nil {
+				return err
+			}
+			for _, table := range tables {
+				if got := visible(t, tx, table.name, table.a, table.b); len(got) != 0 {
+					t.Errorf("%s: a transaction without a tenant sees %v", table.name, got)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("through TenantScope", func(t *testing.T) {
+		prevDB, prevEnv := database.DB, config.Env
+		database.DB = tenantConnection(t, db, superuser)
+		config.Env = &config.Config{TenantRLS: true}
+		t.Cleanup(func() { database.DB, config.Env = prevDB, prevEnv })
+
+		app := fiber.New()
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals("user", &jwt.Claims{UserID: a.user.ID})
+			return c.Next()
+		})
+		app.Get("/organizations/:orgSlug/probe", middleware.TenantScope(), func(c *fiber.Ctx) error {
+			tx := middleware.DB(c)
+			if tx == database.DB {
+				t.Error("the handler reads outside the tenant transaction")
+			}
+			for _, table := range tables {
+				if got := visible(t, tx, table.name, table.a, table.b); len(got) != 1 || got[0] != table.a {
+					t.Errorf("%s: the handler of organization A sees %v, want only its own row %d", table.name, got, table.a)
+				}
+			}
+
+			err := tx.Transaction(func(nested *gorm.DB) error {
+				return nested.Create(&models.TicketComment{TicketID: b.ticket.ID, UserID: a.user.ID, Content: "Intruder"}).Error
+			})
+			if err == nil || !strings.Contains(err.Error(), "row-level security") {
+				t.Errorf("the handler of organization A commenting on a ticket of organization B: err = %v, want a row-level security violation", err)
+			}
+			if err := tx.Create(&models.TicketComment{TicketID: a.ticket.ID, UserID: a.user.ID, Content: "Reply"}).Error; err != nil {
+				t.Errorf("the handler of organization A commenting on its own ticket: %v", err)
+			}
+			return c.SendStatus(fiber.StatusNoContent)
+		})
+
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/organizations/"+a.org.Slug+"/probe", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusNoContent {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusNoContent)
+		}
+	})
+}
@@ -26,6 +26,7 @@ type UserInfo struct {
 	DisplayName     string     `json:"display_name"`
 	Avatar          string     `json:"avatar"`
 	IsEmailVerified bool       `json:"is_email_verified"`
+	IsBot           bool       `json:"is_bot"`
 	LastLoginAt     *time.Time `json:"last_login_at"`
 }
 
@@ -45,7 +46,8 @@ type RegisterRequest struct {
 
 // ChangePasswordRequest represents password change request
 type ChangePasswordRequest struct {
-	CurrentPassword string `json:"current_password" validate:"required"`
+	CurrentPassword string `json:"current_password" validate:"omitempty"`      // empty when the user has no password yet
+	Code            string `json:"code" validate:"omitempty,min=6,max=20"` // TOTP or recovery code when there is no password yet
 	NewPassword     string `json:"new_password" validate:"required,min=8"`
 	ConfirmPassword string `json:"confirm_password" validate:"required,eqfield=NewPassword"`
 }
@@ -55,6 +57,16 @@ type ForgotPasswordRequest struct {
 	Email string `json:"email" validate:"required,email"`
 }
 
+// MagicLinkRequest asks for a sign-in link by email
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// MagicLinkVerifyRequest signs in with the token from a magic link
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
 // ResetPasswordRequest represents password reset request
 type ResetPasswordRequest struct {
 	Token           string `json:"token" validate:"required"`
@@ -67,6 +79,32 @@ type VerifyEmailRequest struct {
 	Token string `json:"token" validate:"required"`
 }
 
+// TOTPCodeRequest carries a code from the authenticator app or a recovery code
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required,min=6,max=20"`
+}
+
+// TOTPEnrollResponse is returned when TOTP enrollment starts
+type TOTPEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRCode          string `json:"qr_code"` // PNG data URI of ProvisioningURI
+}
+
+// MFAChallengeResponse is returned by login when a second factor is required
+type MFAChallengeResponse struct {
+	MFARequired    bool     `json:"mfa_required"`
+	ChallengeToken string   `json:"challenge_token"`
+	Methods        []string `json:"methods"`
+	ExpiresIn      int      `json:"expires_in"` // seconds
+}
+
+// MFAVerifyRequest completes a login that requires a second factor
+type MFAVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,min=6,max=20"`
+}
+
 // OAuth login request
 type OAuthLoginRequest struct {
 	Provider     string `json:"provider" validate:"required,oneof=google github microsoft"`
@@ -74,19 +112,248 @@ type OAuthLoginRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
-type GoogleCallbackRequest struct {
-    Code  string `json:"code" validate:"required"`
-    State string `json:"state,omitempty"`
-    Error string `json:"error,omitempty"`
-    ErrorDescription string `json:"error_description,omitempty"`
+// GoogleCredentialRequest carries an ID token from Google One Tap / Sign-In
+type GoogleCredentialRequest struct {
+	Credential string `json:"credential" validate:"required"`
+}
+
+// OAuthCallbackRequest is forwarded by the frontend from the provider redirect
+type OAuthCallbackRequest struct {
+	Code             string `json:"code"`
+	State            string `json:"state" validate:"required"`
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthAuthorizeResponse tells the frontend where to send the user
+type OAuthAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
 }
 
-type GoogleTokenResponse struct {
-    AccessToken  string `json:"access_token"`
-    TokenType    string `json:"token_type"`
-    ExpiresIn    int    `json:"expires_in"`
-    RefreshToken string `json:"refresh_token,omitempty"`
-    Scope        string `json:"scope"`
-    IDToken      string `json:"id_token,omitempty"`
+
+// ReauthRequest confirms the user's identity before a sensitive change.
+// Password is checked when the account has one, otherwise a TOTP or
+// recovery code; accounts with neither must have signed in recently.
+type ReauthRequest struct {
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty" validate:"omitempty,min=6,max=20"`
+}
+
+// AuthMethodResponse is a login method as shown to its owner. Credentials
+// and provider tokens are never included.
+type AuthMethodResponse struct {
+	ID           uint      `json:"id"`
+	AuthType     string    `json:"auth_type"`
+	AuthProvider *string   `json:"auth_provider"`
+	IsPrimary    bool      `json:"is_primary"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SessionResponse is an active login as shown to its owner
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceID   string    `json:"device_id,omitempty"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// CreatePATRequest creates a personal access token
+type CreatePATRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=profile:read profile:write org:read org:write project:read project:write ticket:read ticket:write"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"` // defaults to 90
+}
+
+// PATResponse describes a personal access token without its secret
+type PATResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
+// PATCreatedResponse includes the plaintext token, which is shown only once
+type PATCreatedResponse struct {
+	PATResponse
+	Token string `json:"token"`
+}
+
+// OAuthTokenRequest is the form posted to the OAuth2 token endpoint
+type OAuthTokenRequest struct {
+	GrantType    string `json:"grant_type" form:"grant_type" validate:"required"`
+	ClientID     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+	Scope        string `json:"scope" form:"scope"`
+	Code         string `json:"code" form:"code"`                   // authorization_code
+	RedirectURI  string `json:"redirect_uri" form:"redirect_uri"`   // authorization_code
+	CodeVerifier string `json:"code_verifier" form:"code_verifier"` // authorization_code, PKCE
+	RefreshToken string `json:"refresh_token" form:"refresh_token"` // refresh_token
+}
+
+// OAuthTokenResponse follows RFC 6749 section 5.1
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthErrorResponse follows RFC 6749 section 5.2
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// ImpersonateRequest starts acting as a user; the reason is kept in the audit log
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,min=5,max=500"`
+}
+
+// ImpersonationResponse carries a short-lived token that acts as the user
+type ImpersonationResponse struct {
+	AccessToken    string   `json:"access_token"`
+	TokenType      string   `json:"token_type"` // Bearer
+	ExpiresIn      int      `json:"expires_in"` // seconds
+	User           UserInfo `json:"user"`
+	ImpersonatedBy UserInfo `json:"impersonated_by"`
+}
+
+// OAuthAuthorizeRequest holds the authorization request parameters an app
+// sends the user's browser with (RFC 6749 section 4.1.1, RFC 7636)
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `json:"response_type" query:"response_type" validate:"required"`
+	ClientID            string `json:"client_id" query:"client_id" validate:"required"`
+	RedirectURI         string `json:"redirect_uri" query:"redirect_uri"` // optional when the app has exactly one
+	Scope               string `json:"scope" query:"scope"`
+	State               string `json:"state" query:"state"`
+	CodeChallenge       string `json:"code_challenge" query:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method"`
+}
+
+// OAuthConsentRequest is the user's answer on the consent screen
+type OAuthConsentRequest struct {
+	OAuthAuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// OAuthScopeInfo describes a requested scope on the consent screen
+type OAuthScopeInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OAuthAppInfo is the public information about an app
+type OAuthAppInfo struct {
+	ClientID    string `json:"client_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	HomepageURL string `json:"homepage_url"`
+}
+
+// OAuthConsentResponse is what the consent screen shows
+type OAuthConsentResponse struct {
+	App            OAuthAppInfo     `json:"app"`
+	Scopes         []OAuthScopeInfo `json:"scopes"`
+	RedirectURI    string           `json:"redirect_uri"`
+	AlreadyGranted bool             `json:"already_granted"` // the user consented to these scopes before
+}
+
+// OAuthRedirectResponse tells the consent screen where to send the browser
+type OAuthRedirectResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// OAuthTokenActionRequest is the form posted to the introspection
+// (RFC 7662) and revocation (RFC 7009) endpoints
+type OAuthTokenActionRequest struct {
+	Token         string `json:"token" form:"token"`
+	TokenTypeHint string `json:"token_type_hint" form:"token_type_hint"` // access_token, refresh_token
+	ClientID      string `json:"client_id" form:"client_id"`
+	ClientSecret  string `json:"client_secret" form:"client_secret"`
+}
+
+// OAuthIntrospectionResponse follows RFC 7662 section 2.2
+type OAuthIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+// CreateOAuthClientRequest registers a third-party app
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	Description  string   `json:"description" validate:"omitempty,max=500"`
+	HomepageURL  string   `json:"homepage_url" validate:"omitempty,url,max=255"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required,min=1,max=10,dive,url,max=500"`
+	Scopes       []string `json:"scopes" validate:"required,min=1,dive,oneof=profile:read profile:write org:read org:write project:read project:write ticket:read ticket:write"`
+	Confidential *bool    `json:"confidential"` // defaults to true; false for mobile and single page apps
+}
+
+// UpdateOAuthClientRequest changes an app; omitted fields are kept
+type UpdateOAuthClientRequest struct {
+	Name         *string  `json:"name" validate:"omitempty,min=1,max=100"`
+	Description  *string  `json:"description" validate:"omitempty,max=500"`
+	HomepageURL  *string  `json:"homepage_url" validate:"omitempty,url,max=255"`
+	RedirectURIs []string `json:"redirect_uris" validate:"omitempty,min=1,max=10,dive,url,max=500"`
+	Scopes       []string `json:"scopes" validate:"omitempty,min=1,dive,oneof=profile:read profile:write org:read org:write project:read project:write ticket:read ticket:write"`
+}
+
+// OAuthClientResponse represents a registered app for its owner
+type OAuthClientResponse struct {
+	ID           uint      `json:"id"`
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	HomepageURL  string    `json:"homepage_url"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OAuthClientCredentialsResponse includes the client secret, which is shown only once
+type OAuthClientCredentialsResponse struct {
+	OAuthClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// AuthorizedAppResponse is an app the user has granted access to
+type AuthorizedAppResponse struct {
+	ID           uint         `json:"id"`
+	App          OAuthAppInfo `json:"app"`
+	Scopes       []string     `json:"scopes"`
+	AuthorizedAt time.Time    `json:"authorized_at"`
+	LastUsedAt   *time.Time   `json:"last_used_at"`
+}
+
+// SSOExchangeRequest redeems the code the SAML login redirected with
+type SSOExchangeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// SSODiscoverRequest looks up single sign-on for an email address
+type SSODiscoverRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// SSODiscoverResponse tells the login page whether to offer single sign-on
+type SSODiscoverResponse struct {
+	SSOAvailable     bool   `json:"sso_available"`
+	SSORequired      bool   `json:"sso_required"`
+	OrganizationSlug string `json:"organization_slug,omitempty"`
+}
@@ -1,6 +1,9 @@
 package schema
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // CreateOrganization represents the schema for creating a new organization
 type CreateOrganization struct {
@@ -8,15 +11,19 @@ type CreateOrganization struct {
 	Slug        string `json:"slug" validate:"required,min=3,max=100,alphanum"`
 	Description string `json:"description" validate:"omitempty,max=1000"`
 	LogoURL     string `json:"logo_url" validate:"omitempty,url"`
-	PlanType    string `json:"plan_type" validate:"omitempty,oneof=free pro enterprise"`
 }
 
-// UpdateOrganization represents the schema for updating organization data
+// UpdateOrganization represents the schema for updating organization data;
+// omitted fields are kept and an empty description or logo URL clears it
 type UpdateOrganization struct {
-	Name        string `json:"name" validate:"omitempty,min=1,max=255"`
-	Description string `json:"description" validate:"omitempty,max=1000"`
-	LogoURL     string `json:"logo_url" validate:"omitempty,url"`
-	PlanType    string `json:"plan_type" validate:"omitempty,oneof=free pro enterprise"`
+	Name        *string `json:"name" validate:"omitnil,min=1,max=255"`
+	Description *string `json:"description" validate:"omitempty,max=1000"`
+	LogoURL     *string `json:"logo_url" validate:"omitempty,eq=|url"`
+}
+
+// UpdateOrganizationPlan changes the plan of an organization
+type UpdateOrganizationPlan struct {
+	PlanType string `json:"plan_type" validate:"required,oneof=free pro enterprise"`
 }
 
 // OrganizationResponse represents organization data for responses
@@ -46,9 +53,158 @@ type UpdateMemberRole struct {
 type OrganizationMemberResponse struct {
 	ID           uint                 `json:"id"`
 	Role         string               `json:"role"`
+	Status       string               `json:"status"` // active, invited
 	InvitedAt    *time.Time           `json:"invited_at"`
 	JoinedAt     *time.Time           `json:"joined_at"`
 	CreatedAt    time.Time            `json:"created_at"`
 	User         UserInfo             `json:"user"`
 	Organization OrganizationResponse `json:"organization"`
 }
+
+// InvitationTokenRequest carries the token from an invitation email
+type InvitationTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// InvitationResponse represents organization invitation data for responses
+type InvitationResponse struct {
+	ID           uint                  `json:"id"`
+	Email        string                `json:"email"`
+	Role         string                `json:"role"`
+	Status       string                `json:"status"` // pending, expired, accepted, declined, revoked
+	InvitedBy    UserInfo              `json:"invited_by"`
+	SentAt       time.Time             `json:"sent_at"`
+	ExpiresAt    time.Time             `json:"expires_at"`
+	CreatedAt    time.Time             `json:"created_at"`
+	Organization *OrganizationResponse `json:"organization,omitempty"`
+}
+
+// CreateServiceAccount represents the schema for creating a service account
+type CreateServiceAccount struct {
+	Name        string   `json:"name" validate:"required,min=1,max=100"`
+	Description string   `json:"description" validate:"omitempty,max=500"`
+	Role        string   `json:"role" validate:"required,oneof=admin member guest"`
+	Scopes      []string `json:"scopes" validate:"required,min=1,dive,oneof=org:read org:write project:read project:write ticket:read ticket:write"`
+}
+
+// UpdateServiceAccount represents the schema for updating a service account
+type UpdateServiceAccount struct {
+	Name        string   `json:"name" validate:"omitempty,min=1,max=100"`
+	Description string   `json:"description" validate:"omitempty,max=500"`
+	Role        string   `json:"role" validate:"omitempty,oneof=admin member guest"`
+	Scopes      []string `json:"scopes" validate:"omitempty,min=1,dive,oneof=org:read org:write project:read project:write ticket:read ticket:write"`
+	Disabled    *bool    `json:"disabled"`
+}
+
+// ServiceAccountResponse represents service account data for responses
+type ServiceAccountResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Role        string     `json:"role"`
+	Scopes      []string   `json:"scopes"`
+	ClientID    string     `json:"client_id"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	DisabledAt  *time.Time `json:"disabled_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	User        UserInfo   `json:"user"`
+}
+
+// ServiceAccountCredentialsResponse includes the client secret, which is shown only once
+type ServiceAccountCredentialsResponse struct {
+	ServiceAccountResponse
+	ClientSecret string `json:"client_secret"`
+}
+
+// AuditLogResponse represents audit log data for responses
+type AuditLogResponse struct {
+	ID         uint            `json:"id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	ActorID    *uint           `json:"actor_id"`
+	SubjectID  *uint           `json:"subject_id,omitempty"`
+	IPAddress  string          `json:"ip_address"`
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// UpdateOrganizationSSO configures SAML single sign-on for an organization
+type UpdateOrganizationSSO struct {
+	IdPMetadata      string               `json:"idp_metadata" validate:"required"` // metadata XML from the identity provider
+	Enabled          *bool                `json:"enabled"`
+	ForceSSO         bool                 `json:"force_sso"`
+	DefaultRole      string               `json:"default_role" validate:"omitempty,oneof=member guest"`
+	AttributeMapping *SSOAttributeMapping `json:"attribute_mapping"`
+}
+
+// SSOAttributeMapping names the SAML attributes mapped to user fields
+type SSOAttributeMapping struct {
+	Email       string `json:"email,omitempty" validate:"omitempty,max=255"`
+	FirstName   string `json:"first_name,omitempty" validate:"omitempty,max=255"`
+	LastName    string `json:"last_name,omitempty" validate:"omitempty,max=255"`
+	DisplayName string `json:"display_name,omitempty" validate:"omitempty,max=255"`
+}
+
+// OrganizationSSOResponse represents SSO configuration data for responses
+type OrganizationSSOResponse struct {
+	Enabled          bool                `json:"enabled"`
+	IdPEntityID      string              `json:"idp_entity_id"`
+	EmailDomains     []string            `json:"email_domains"` // verified domains
+	ForceSSO         bool                `json:"force_sso"`
+	DefaultRole      string              `json:"default_role"`
+	AttributeMapping SSOAttributeMapping `json:"attribute_mapping"`
+	SPEntityID       string              `json:"sp_entity_id"`
+	ACSURL           string              `json:"acs_url"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+}
+
+// AddSSODomain claims an email domain for single sign-on
+type AddSSODomain struct {
+	Domain string `json:"domain" validate:"required,fqdn,max=255"`
+}
+
+// SSODomainResponse describes a claimed domain and the TXT record that
+// verifies it
+type SSODomainResponse struct {
+	ID          uint       `json:"id"`
+	Domain      string     `json:"domain"`
+	Verified    bool       `json:"verified"`
+	VerifiedAt  *time.Time `json:"verified_at"`
+	RecordName  string     `json:"record_name"`
+	RecordValue string     `json:"record_value"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// PermissionSchemeRequest creates or replaces a permission scheme. Roles
+// maps project roles, built-in or custom such as "qa", to permissions.
+type PermissionSchemeRequest struct {
+	Name        string              `json:"name" validate:"required,max=100"`
+	Description string              `json:"description" validate:"omitempty,max=500"`
+	IsDefault   bool                `json:"is_default"` // applies to projects of the organization without a scheme
+	Roles       map[string][]string `json:"roles" validate:"required,dive,keys,min=1,max=50,endkeys,dive,required"`
+}
+
+// PermissionSchemeResponse represents permission scheme data for responses
+type PermissionSchemeResponse struct {
+	ID          uint                `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	IsDefault   bool                `json:"is_default"`
+	Roles       map[string][]string `json:"roles"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// QuotaUsage shows how much of one plan limit an organization uses
+type QuotaUsage struct {
+	Resource string `json:"resource"`
+	Limit    *int64 `json:"limit"` // null means unlimited
+	Used     int64  `json:"used"`
+}
+
+// OrganizationUsageResponse lists the plan limits of an organization and their usage
+type OrganizationUsageResponse struct {
+	PlanType string       `json:"plan_type"`
+	Quotas   []QuotaUsage `json:"quotas"`
+}
@@ -1,6 +1,10 @@
 package schema
 
-import "time"
+import (
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/settings"
+)
 
 // CreateProject represents the schema for creating a new project
 type CreateProject struct {
@@ -37,12 +41,25 @@ type ProjectResponse struct {
 // AddProjectMember represents the schema for adding a member to project
 type AddProjectMember struct {
 	UserID uint   `json:"user_id" validate:"required"`
-	Role   string `json:"role" validate:"required,oneof=owner admin member viewer"`
+	Role   string `json:"role" validate:"required,max=50"` // owner, admin, member, viewer or a role of the project's permission scheme
 }
 
 // UpdateProjectMemberRole represents the schema for updating project member role
 type UpdateProjectMemberRole struct {
-	Role string `json:"role" validate:"required,oneof=owner admin member viewer"`
+	Role string `json:"role" validate:"required,max=50"` // owner, admin, member, viewer or a role of the project's permission scheme
+}
+
+// AssignPermissionScheme sets the permission scheme of a project
+type AssignPermissionScheme struct {
+	SchemeID *uint `json:"scheme_id"` // null falls back to the organization's default scheme
+}
+
+// ProjectPermissionsResponse tells what a user may do in a project
+type ProjectPermissionsResponse struct {
+	ProjectKey  string   `json:"project_key"`
+	UserID      uint     `json:"user_id"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
 }
 
 // ProjectMemberResponse represents project member data for responses
@@ -82,3 +99,10 @@ type TicketStatusResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// ProjectSettingsResponse shows a project's settings after inheritance and
+// the overrides the project sets itself
+type ProjectSettingsResponse struct {
+	Effective settings.Project          `json:"effective"`
+	Overrides settings.ProjectOverrides `json:"overrides"`
+}
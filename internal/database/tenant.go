@@ -0,0 +1,15 @@
+package database
+
+import (
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// SetTenant scopes the transaction tx to an organization and user, which the
+// row-level security policies read through app_current_org() and
+// app_current_user(). The settings end with the transaction.
+func SetTenant(tx *gorm.DB, orgID, userID uint) error {
+	return tx.Exec("SELECT set_config('app.current_org', ?, true), set_config('app.current_user', ?, true)",
+		strconv.FormatUint(uint64(orgID), 10), strconv.FormatUint(uint64(userID), 10)).Error
+}
@@ -0,0 +1,200 @@
+// Package entitlements defines what each plan allows an organization and
+// measures how much of it is in use.
+package entitlements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/authz"
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Resources limited by plans.
+const (
+	Members           = "members"            // seats, including pending invitations
+	Projects          = "projects"           // projects owned by the organization
+	AttachmentStorage = "attachment_storage" // bytes of ticket attachments
+	AutomationRules   = "automation_rules"   // automation rules of the organization's projects
+	APIRate           = "api_rate"           // requests per minute to organization routes
+)
+
+// Resources lists every limited resource in display order.
+var Resources = []string{Members, Projects, AttachmentStorage, AutomationRules, APIRate}
+
+// Unlimited is the limit of resources a plan does not restrict.
+const Unlimited int64 = -1
+
+// Limits are the entitlements of one plan, keyed by resource.
+type Limits map[string]int64
+
+var plans = map[string]Limits{
+	"free": {
+		Members:           5,
+		Projects:          3,
+		AttachmentStorage: 1 << 30,
+		AutomationRules:   5,
+		APIRate:           60,
+	},
+	"pro": {
+		Members:           100,
+		Projects:          50,
+		AttachmentStorage: 100 << 30,
+		AutomationRules:   100,
+		APIRate:           600,
+	},
+	"enterprise": {
+		Members:           Unlimited,
+		Projects:          Unlimited,
+		AttachmentStorage: 1 << 40,
+		AutomationRules:   Unlimited,
+		APIRate:           6000,
+	},
+}
+
+// For returns the limits of plan. Unknown plans get the free plan.
+func For(plan string) Limits {
+	if limits, ok := plans[plan]; ok {
+		return limits
+	}
+	return plans["free"]
+}
+
+// QuotaError reports that an organization reached a limit of its plan.
+type QuotaError struct {
+	Resource string
+	Plan     string
+	Limit    int64
+	Used     int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("the %s plan allows %d %s, %d in use", e.Plan, e.Limit, e.Resource, e.Used)
+}
+
+// Usage measures how much of resource the organization uses.
+func Usage(ctx context.Context, db *gorm.DB, orgID uint, resource string) (int64, error) {
+	var used int64
+	var err error
+	switch resource {
+	case Members:
+		used, err = seats(db, orgID)
+	case Projects:
+		err = db.Model(&models.Project{}).Where("organization_id = ?", orgID).Count(&used).Error
+	case AttachmentStorage:
+		// Files of deleted tickets and projects are still stored
+		err = db.Raw(`SELECT COALESCE(SUM(a.file_size), 0) FROM ticket_attachments a
+			JOIN tickets t ON t.id = a.ticket_id
+			JOIN projects p ON p.id = t.project_id
+			WHERE p.organization_id = ?`, orgID).Scan(&used).Error
+	case AutomationRules:
+		// There is no automation rule table yet, so none can be in use.
+		// Count them here once rules can be created.
+	case APIRate:
+		value, getErr := cache.Default.Get(ctx, rateKey(orgID, time.Now()))
+		if getErr == nil {
+			used, err = strconv.ParseInt(value, 10, 64)
+		} else if !errors.Is(getErr, cache.ErrNotFound) {
+			err = getErr
+		}
+	default:
+		err = errors.New("unknown resource")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("entitlements: measure %s: %w", resource, err)
+	}
+	return used, nil
+}
+
+// Check returns a *QuotaError if adding more of resource would exceed the
+// organization's plan. Pass adding 0 to only check that the organization
+// is within its limit, e.g. when a reserved seat is taken.
+//
+// Check must run in the transaction that adds the resource. It locks the
+// organization row, so concurrent additions are counted one after the
+// other and cannot both take the last seat.
+func Check(ctx context.Context, tx *gorm.DB, org *models.Organization, resource string, adding int64) error {
+	limit := For(org.PlanType)[resource]
+	if limit == Unlimited {
+		return nil
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Organization{}, org.ID).Error; err != nil {
+		return fmt.Errorf("entitlements: lock organization: %w", err)
+	}
+	used, err := Usage(ctx, tx, org.ID, resource)
+	if err != nil {
+		return err
+	}
+	if used+adding > limit {
+		return &QuotaError{Resource: resource, Plan: org.PlanType, Limit: limit, Used: used}
+	}
+	return nil
+}
+
+// CheckProject returns a *QuotaError if the organization's plan allows no
+// more projects. Call it in the transaction that creates the project.
+func CheckProject(ctx context.Context, tx *gorm.DB, org *models.Organization) error {
+	return Check(ctx, tx, org, Projects, 1)
+}
+
+// CheckAttachment returns a *QuotaError if storing size more bytes of
+// attachments would exceed the organization's plan. Call it in the
+// transaction that records the attachment.
+func CheckAttachment(ctx context.Context, tx *gorm.DB, org *models.Organization, size int64) error {
+	return Check(ctx, tx, org, AttachmentStorage, size)
+}
+
+// CheckAutomationRule returns a *QuotaError if the organization's plan
+// allows no more automation rules. Call it in the transaction that creates
+// the rule.
+func CheckAutomationRule(ctx context.Context, tx *gorm.DB, org *models.Organization) error {
+	return Check(ctx, tx, org, AutomationRules, 1)
+}
+
+// AllowRequest counts a request against the organization's API rate and
+// reports whether it is within the limit, and if not, when the window ends.
+func AllowRequest(ctx context.Context, org *models.Organization) (bool, time.Duration, error) {
+	limit := For(org.PlanType)[APIRate]
+	if limit == Unlimited {
+		return true, 0, nil
+	}
+	now := time.Now()
+	count, err := cache.Default.Incr(ctx, rateKey(org.ID, now), time.Minute)
+	if err != nil {
+		return false, 0, err
+	}
+	if count > limit {
+		return false, now.Truncate(time.Minute).Add(time.Minute).Sub(now), nil
+	}
+	return true, 0, nil
+}
+
+// seats counts active human members plus pending invitations, which
+// reserve a seat until they are answered or expire. Service account bots
+// do not take a seat.
+func seats(db *gorm.DB, orgID uint) (int64, error) {
+	var members int64
+	err := db.Model(&models.OrganizationMember{}).Scopes(authz.ActiveMembers).
+		Joins("JOIN users ON users.id = organization_members.user_id").
+		Where("organization_members.organization_id = ? AND users.is_bot = ?", orgID, false).
+		Count(&members).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var invitations int64
+	err = db.Model(&models.OrganizationInvitation{}).
+		Where("organization_id = ? AND accepted_at IS NULL AND declined_at IS NULL AND revoked_at IS NULL AND expires_at > ?", orgID, time.Now()).
+		Count(&invitations).Error
+	return members + invitations, err
+}
+
+func rateKey(orgID uint, now time.Time) string {
+	return fmt.Sprintf("ratelimit:org:%d:%d", orgID, now.Unix()/60)
+}
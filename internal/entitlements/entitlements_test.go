@@ -0,0 +1,84 @@
+package entitlements
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/database/dbtest"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"gorm.io/gorm"
+)
+
+func TestPlansLimitEveryResource(t *testing.T) {
+	for plan, limits := range plans {
+		for _, resource := range Resources {
+			if _, ok := limits[resource]; !ok {
+				t.Errorf("the %s plan has no %s limit", plan, resource)
+			}
+		}
+	}
+	if got := For("platinum")[Members]; got != plans["free"][Members] {
+		t.Errorf("unknown plan: members = %d, want the free plan's %d", got, plans["free"][Members])
+	}
+}
+
+// addUser adds a user to the organization as an active member.
+func addUser(t *testing.T, db *gorm.DB, org *models.Organization, bot bool) *models.User {
+	t.Helper()
+	user := &models.User{Email: dbtest.Unique("seat") + "@example.com", IsBot: bot}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.OrganizationMember{OrganizationID: org.ID, UserID: user.ID, Role: "member"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func checkSeat(t *testing.T, db *gorm.DB, org *models.Organization) error {
+	t.Helper()
+	return db.Transaction(func(tx *gorm.DB) error {
+		return Check(context.Background(), tx, org, Members, 1)
+	})
+}
+
+func TestCheckSeatLimit(t *testing.T) {
+	db := dbtest.Open(t)
+	org := &models.Organization{Name: "Acme", Slug: dbtest.Unique("acme-"), PlanType: "free", Settings: "{}"}
+	if err := db.Create(org).Error; err != nil {
+		t.Fatal(err)
+	}
+	limit := For("free")[Members]
+
+	owner := addUser(t, db, org, false)
+	for range limit - 3 {
+		addUser(t, db, org, false)
+	}
+	// Bots do not take a seat
+	addUser(t, db, org, true)
+	// A pending invitation reserves one
+	invitation := models.OrganizationInvitation{OrganizationID: org.ID, Email: dbtest.Unique("invitee") + "@example.com", InvitedBy: owner.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := db.Create(&invitation).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSeat(t, db, org); err != nil {
+		t.Fatalf("with one seat left: %v", err)
+	}
+
+	addUser(t, db, org, false)
+	var quota *QuotaError
+	if err := checkSeat(t, db, org); !errors.As(err, &quota) {
+		t.Fatalf("at the limit: err = %v, want a *QuotaError", err)
+	}
+	if quota.Resource != Members || quota.Limit != limit || quota.Used != limit {
+		t.Fatalf("quota = %+v", quota)
+	}
+
+	// Enterprise organizations have no seat limit
+	org.PlanType = "enterprise"
+	if err := checkSeat(t, db, org); err != nil {
+		t.Fatalf("enterprise plan: %v", err)
+	}
+}
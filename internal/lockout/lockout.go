@@ -0,0 +1,131 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+)
+
+// lockoutWindow is how long earlier lockouts count towards the backoff and
+// towards suspension.
+const lockoutWindow = 24 * time.Hour
+
+// Status is the outcome of a failed attempt.
+type Status struct {
+	// RetryAfter is set when the account or IP is now locked.
+	RetryAfter time.Duration
+	// Lockouts is the number of account lockouts within the window.
+	Lockouts int64
+}
+
+// ShouldSuspend reports whether the account hit LOGIN_SUSPEND_AFTER lockouts.
+func (s Status) ShouldSuspend() bool {
+	return config.Env.LoginSuspendAfter > 0 && s.Lockouts >= int64(config.Env.LoginSuspendAfter)
+}
+
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check returns how long the caller has to wait, or zero if the login may
+// be attempted. Accounts are keyed by email whether or not they exist, so
+// the lockout itself does not reveal which emails are registered.
+func Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	for _, key := range []string{"login:lock:acct:" + accountKey(email), "login:lock:ip:" + ip} {
+		value, err := cache.Default.Get(ctx, key)
+		if errors.Is(err, cache.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		until, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if wait := time.Until(time.Unix(until, 0)); wait > 0 {
+			return wait, nil
+		}
+	}
+	return 0, nil
+}
+
+// RecordFailure counts a failed attempt and locks the account or IP once
+// its limit is reached. Each further account lockout within the window
+// doubles the lock duration up to LOGIN_LOCKOUT_MAX.
+func RecordFailure(ctx context.Context, email, ip string) (Status, error) {
+	var status Status
+	window := config.Env.LoginAttemptWindow
+
+	acct := accountKey(email)
+	failures, err := cache.Default.Incr(ctx, "login:fail:acct:"+acct, window)
+	if err != nil {
+		return status, err
+	}
+	if failures >= int64(config.Env.LoginMaxAttempts) {
+		status.Lockouts, err = cache.Default.Incr(ctx, "login:lockouts:acct:"+acct, lockoutWindow)
+		if err != nil {
+			return status, err
+		}
+		status.RetryAfter = backoff(status.Lockouts)
+		if err := lock(ctx, "acct:"+acct, status.RetryAfter); err != nil {
+			return status, err
+		}
+	}
+
+	failures, err = cache.Default.Incr(ctx, "login:fail:ip:"+ip, window)
+	if err != nil {
+		return status, err
+	}
+	if failures >= int64(config.Env.LoginMaxAttemptsIP) {
+		// IPs get a fixed lock; shared NATs should not escalate to hours.
+		if err := lock(ctx, "ip:"+ip, config.Env.LoginLockoutBase); err != nil {
+			return status, err
+		}
+		if status.RetryAfter < config.Env.LoginLockoutBase {
+			status.RetryAfter = config.Env.LoginLockoutBase
+		}
+	}
+	return status, nil
+}
+
+// Reset clears the failure counter of an account after a successful login.
+// Earlier lockouts keep counting towards the backoff.
+func Reset(ctx context.Context, email string) error {
+	return cache.Default.Delete(ctx, "login:fail:acct:"+accountKey(email))
+}
+
+// Unlock removes every lock and counter of an account.
+func Unlock(ctx context.Context, email string) error {
+	acct := accountKey(email)
+	for _, key := range []string{"login:fail:acct:" + acct, "login:lock:acct:" + acct, "login:lockouts:acct:" + acct} {
+		if err := cache.Default.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lock(ctx context.Context, subject string, d time.Duration) error {
+	if err := cache.Default.Delete(ctx, "login:fail:"+subject); err != nil {
+		return err
+	}
+	until := strconv.FormatInt(time.Now().Add(d).Unix(), 10)
+	return cache.Default.Set(ctx, "login:lock:"+subject, until, d)
+}
+
+func backoff(lockouts int64) time.Duration {
+	d := config.Env.LoginLockoutBase
+	for i := int64(1); i < lockouts && d < config.Env.LoginLockoutMax; i++ {
+		d *= 2
+	}
+	if d > config.Env.LoginLockoutMax {
+		d = config.Env.LoginLockoutMax
+	}
+	return d
+}
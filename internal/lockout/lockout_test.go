@@ -0,0 +1,126 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+)
+
+func setup(t *testing.T) {
+	t.Helper()
+	cache.Default = cache.NewMemoryStore()
+	config.Env = &config.Config{
+		LoginMaxAttempts:   3,
+		LoginMaxAttemptsIP: 100,
+		LoginAttemptWindow: time.Minute,
+		LoginLockoutBase:   time.Second,
+		LoginLockoutMax:    4 * time.Second,
+		LoginSuspendAfter:  2,
+	}
+}
+
+// fail records n failed attempts and returns the status of the last one.
+func fail(t *testing.T, n int, email, ip string) Status {
+	t.Helper()
+	var status Status
+	for range n {
+		var err error
+		if status, err = RecordFailure(context.Background(), email, ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return status
+}
+
+func wait(t *testing.T, email, ip string) time.Duration {
+	t.Helper()
+	d, err := Check(context.Background(), email, ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestAccountLockout(t *testing.T) {
+	setup(t)
+
+	if status := fail(t, 2, "jane@example.com", "10.0.0.1"); status.RetryAfter != 0 {
+		t.Fatalf("locked after 2 of 3 attempts: %+v", status)
+	}
+	if d := wait(t, "jane@example.com", "10.0.0.1"); d != 0 {
+		t.Fatalf("wait = %v before the limit", d)
+	}
+
+	status := fail(t, 1, "jane@example.com", "10.0.0.2")
+	if status.RetryAfter != time.Second || status.Lockouts != 1 || status.ShouldSuspend() {
+		t.Fatalf("status after the limit = %+v, want a 1s lock and no suspension", status)
+	}
+	// The account is locked from any IP, whatever the case of the email
+	if d := wait(t, " JANE@example.com", "10.0.0.3"); d <= 0 {
+		t.Fatal("the account is not locked")
+	}
+	if d := wait(t, "john@example.com", "10.0.0.1"); d != 0 {
+		t.Fatalf("another account waits %v", d)
+	}
+
+	// The lock expires, and the next one takes twice as long and suspends
+	time.Sleep(1100 * time.Millisecond)
+	if d := wait(t, "jane@example.com", "10.0.0.1"); d != 0 {
+		t.Fatalf("wait = %v after the lock expired", d)
+	}
+	status = fail(t, 3, "jane@example.com", "10.0.0.1")
+	if status.RetryAfter != 2*time.Second || status.Lockouts != 2 || !status.ShouldSuspend() {
+		t.Fatalf("status after the second lockout = %+v, want a 2s lock and suspension", status)
+	}
+}
+
+func TestResetKeepsLockouts(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+
+	fail(t, 2, "jane@example.com", "10.0.0.1")
+	if err := Reset(ctx, "jane@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if status := fail(t, 2, "jane@example.com", "10.0.0.1"); status.RetryAfter != 0 {
+		t.Fatalf("failures before the successful login still count: %+v", status)
+	}
+
+	if err := Unlock(ctx, "jane@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if status := fail(t, 3, "jane@example.com", "10.0.0.1"); status.Lockouts != 1 {
+		t.Fatalf("lockouts = %d after unlocking, want 1", status.Lockouts)
+	}
+}
+
+func TestIPLockout(t *testing.T) {
+	setup(t)
+	config.Env.LoginMaxAttemptsIP = 3
+
+	fail(t, 1, "a@example.com", "10.0.0.1")
+	fail(t, 1, "b@example.com", "10.0.0.1")
+	status := fail(t, 1, "c@example.com", "10.0.0.1")
+	if status.RetryAfter != config.Env.LoginLockoutBase || status.Lockouts != 0 {
+		t.Fatalf("status = %+v, want an IP lock without an account lockout", status)
+	}
+	if d := wait(t, "d@example.com", "10.0.0.1"); d <= 0 {
+		t.Fatal("the IP is not locked")
+	}
+	if d := wait(t, "d@example.com", "10.0.0.2"); d != 0 {
+		t.Fatalf("another IP waits %v", d)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	config.Env = &config.Config{LoginLockoutBase: time.Minute, LoginLockoutMax: 10 * time.Minute}
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for i, d := range want {
+		if got := backoff(int64(i + 1)); got != d {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, d)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+)
+
+// Message is a single outgoing email.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Default is the mailer used by the application, selected by MAIL_DRIVER.
+var Default Mailer
+
+// Init builds the mailer configured in config.Env.
+func Init() error {
+	switch config.Env.MailDriver {
+	case "smtp":
+		Default = NewSMTPMailer(config.Env.SMTPHost, config.Env.SMTPPort,
+			config.Env.SMTPUsername, config.Env.SMTPPassword, config.Env.MailFrom)
+	case "outbox", "":
+		Default = NewOutboxMailer(config.Env.MailOutboxDir, config.Env.MailFrom)
+	default:
+		return fmt.Errorf("unknown mail driver %q", config.Env.MailDriver)
+	}
+	return nil
+}
+
+// SendAsync delivers the message in the background so request latency does
+// not depend on the mail server. Failures are only logged.
+func SendAsync(msg Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := Default.Send(ctx, msg); err != nil {
+			logf("failed to send %q to %v: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// build renders msg as an RFC 5322 message.
+func build(from string, msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", uuid.NewString(), domainOf(from))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		buf.WriteString(msg.Text)
+		return buf.Bytes()
+	}
+
+	boundary := uuid.NewString()
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&buf, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", boundary, msg.Text)
+	fmt.Fprintf(&buf, "--%s\r\nContent-Type: text/html; charset=utf-8\r\n\r\n%s\r\n", boundary, msg.HTML)
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes()
+}
+
+func domainOf(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return strings.TrimRight(address[i+1:], ">")
+	}
+	return "localhost"
+}
+
+func logf(format string, args ...any) {
+	log.Printf("mailer: "+format, args...)
+}
@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"fmt"
+	"time"
+)
+
+// PasswordResetMessage is sent by the forgot-password flow.
+func PasswordResetMessage(to, link string, ttl time.Duration) Message {
+	return Message{
+		To:      []string{to},
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("We received a request to reset your password.\n\n"+
+			"Open the link below to choose a new one. It expires in %s and can only be used once.\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n", ttl, link),
+	}
+}
+
+// EmailVerificationMessage is sent after registration and on resend.
+func EmailVerificationMessage(to, link string, ttl time.Duration) Message {
+	return Message{
+		To:      []string{to},
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Welcome! Please confirm that this is your email address by opening the link below.\n\n"+
+			"%s\n\nThe link expires in %s.\n", link, ttl),
+	}
+}
+
+// MagicLinkMessage is sent by the passwordless login flow.
+func MagicLinkMessage(to, link string, ttl time.Duration) Message {
+	return Message{
+		To:      []string{to},
+		Subject: "Your sign-in link",
+		Text: fmt.Sprintf("Open the link below to sign in. It expires in %s and can only be used once.\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n", ttl, link),
+	}
+}
+
+// NewDeviceMessage is sent when the account is used from a new device.
+func NewDeviceMessage(to, userAgent, ip string, at time.Time) Message {
+	return Message{
+		To:      []string{to},
+		Subject: "New sign-in to your account",
+		Text: fmt.Sprintf("Your account was just used to sign in from a new device.\n\n"+
+			"Time: %s\nDevice: %s\nIP address: %s\n\n"+
+			"If this was you, there is nothing to do. Otherwise change your password and "+
+			"sign out the device from your active sessions.\n", at.UTC().Format(time.RFC1123), userAgent, ip),
+	}
+}
+
+// OrganizationInviteMessage is sent when someone is invited to an
+// organization, and again on resend.
+func OrganizationInviteMessage(to, orgName, inviter, role, link string, ttl time.Duration) Message {
+	return Message{
+		To:      []string{to},
+		Subject: fmt.Sprintf("You have been invited to join %s", orgName),
+		Text: fmt.Sprintf("%s invited you to join %s as %s.\n\n"+
+			"Open the link below to accept or decline. If you do not have an account yet, sign up with this email address first. "+
+			"The link expires in %s.\n\n%s\n", inviter, orgName, role, ttl, link),
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+// RequireAdmin only lets platform administrators through. It must run
+// after JWTAuthMiddleware.
+func RequireAdmin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := jwt.GetClaimsFromFiberContext(c)
+		if err != nil {
+			return response.Fail(c, "UNAUTHORIZED", "Unauthorized", fiber.StatusUnauthorized)
+		}
+
+		var user models.User
+		if err := database.DB.Select("id", "is_admin").First(&user, claims.UserID).Error; err != nil {
+			return response.Fail(c, "UNAUTHORIZED", "Unauthorized", fiber.StatusUnauthorized)
+		}
+		if !user.IsAdmin {
+			return response.Fail(c, "FORBIDDEN", "Administrator access required", fiber.StatusForbidden)
+		}
+
+		return c.Next()
+	}
+}
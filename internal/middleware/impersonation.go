@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/audit"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+// HeaderImpersonatedBy marks responses to requests made while impersonating,
+// so clients can show who is really signed in.
+const HeaderImpersonatedBy = "X-Impersonated-By"
+
+// auditImpersonation runs the rest of the chain and writes every request
+// made with an impersonation token to the audit log.
+func auditImpersonation(c *fiber.Ctx, claims *jwt.Claims) error {
+	c.Set(HeaderImpersonatedBy, claims.Act.Email)
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		status = fiberErr.Code
+	}
+	entryErr := audit.Record(database.DB, c, audit.Entry{
+		Action:     "impersonation.request",
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(claims.UserID), 10),
+		Metadata: map[string]any{
+			"token_id": claims.ID,
+			"method":   c.Method(),
+			"path":     c.Path(),
+			"status":   status,
+		},
+	})
+	if entryErr != nil {
+		log.Printf("audit: failed to record impersonated request of user %d by %d: %v", claims.UserID, claims.Act.UserID, entryErr)
+	}
+	return err
+}
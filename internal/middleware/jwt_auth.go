@@ -1,14 +1,25 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/session"
 	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
 )
 
-func JWTAuthMiddleware() fiber.Handler {
+// JWTAuthMiddleware accepts access tokens from a login and personal access
+// tokens (prefixed with pat_) and stores the claims in c.Locals("user").
+// Requests made with an impersonation or service account token are
+// audited.
+//
+// Delegated tokens, i.e. personal access tokens and tokens of apps and
+// service accounts, are refused unless a rule names the scope they need
+// for the request. Every rule must be satisfied.
+func JWTAuthMiddleware(rules ...ScopeRule) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -22,13 +33,61 @@ func JWTAuthMiddleware() fiber.Handler {
 
 		tokenString := parts[1]
 
-		claims, err := jwt.ValidateToken(tokenString)
+		var claims *jwt.Claims
+		var err error
+		if session.IsPAT(tokenString) {
+			claims, err = session.AuthenticatePAT(c.Context(), database.DB, tokenString)
+			switch {
+			case errors.Is(err, session.ErrInvalidPAT):
+				return response.Fail(c, "UNAUTHORIZED", "Invalid, expired or revoked access token", fiber.StatusUnauthorized)
+			case errors.Is(err, session.ErrUserDisabled):
+				return response.Fail(c, "ACCOUNT_SUSPENDED", "This account has been suspended", fiber.StatusForbidden)
+			case err != nil:
+				return response.Fail(c, "INTERNAL_ERROR", "Failed to check access token", fiber.StatusInternalServerError)
+			}
+		} else {
+			claims, err = jwt.ValidateToken(tokenString)
+			if err != nil {
+				return response.Fail(c, "UNAUTHORIZED", "Invalid or expired token: "+err.Error(), fiber.StatusUnauthorized)
+			}
+		}
+
+		revoked, err := session.IsRevoked(c.Context(), claims)
 		if err != nil {
-			return response.Fail(c, "UNAUTHORIZED", "Invalid or expired token: "+err.Error(), fiber.StatusUnauthorized)
+			return response.Fail(c, "INTERNAL_ERROR", "Failed to check token revocation", fiber.StatusInternalServerError)
+		}
+		if revoked {
+			return response.Fail(c, "TOKEN_REVOKED", "Token has been revoked", fiber.StatusUnauthorized)
+		}
+
+		if claims.SessionID != "" {
+			alive, err := session.Touch(c.Context(), database.DB, claims.SessionID)
+			if err != nil {
+				return response.Fail(c, "INTERNAL_ERROR", "Failed to check session", fiber.StatusInternalServerError)
+			}
+			if !alive {
+				return response.Fail(c, "SESSION_EXPIRED", "Session has ended, please log in again", fiber.StatusUnauthorized)
+			}
+		}
+
+		if claims.IsDelegated() && len(rules) == 0 {
+			return insufficientScope(c, "")
+		}
+		for _, rule := range rules {
+			if ok, scope := scopeAllows(c, claims, rule); !ok {
+				return insufficientScope(c, scope)
+			}
 		}
 
 		c.Locals("user", claims)
 
+		if claims.IsImpersonated() {
+			return auditImpersonation(c, claims)
+		}
+		if session.IsServiceAccountClient(claims.ClientID) {
+			return auditServiceAccount(c, claims)
+		}
+
 		return c.Next()
 	}
 }
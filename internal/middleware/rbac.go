@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/authz"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/entitlements"
+	"github.com/phonsing-Hub/GoLang/internal/settings"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+// Authz returns the role resolver of the current request. It is created on
+// first use and kept in c.Locals("authz"), so membership lookups are done
+// once per request however many guards and handlers ask. Inside TenantScope
+// it reads through the tenant transaction. It must run after
+// JWTAuthMiddleware.
+func Authz(c *fiber.Ctx) *authz.Resolver {
+	if resolver, ok := c.Locals("authz").(*authz.Resolver); ok {
+		return resolver
+	}
+	claims := c.Locals("user").(*jwt.Claims)
+	resolver := authz.NewResolver(DB(c), claims.UserID)
+	c.Locals("authz", resolver)
+	return resolver
+}
+
+// RequireOrgRole loads the organization named by :orgSlug into
+// c.Locals("organization") and its role for the caller into
+// c.Locals("org_role"), and only lets callers with at least role through.
+// Organizations the caller does not belong to are reported as not found,
+// members without 2FA are turned away if the organization requires it, and
+// requests beyond the API rate of the organization's plan are refused.
+// Delegated tokens need org:read, or org:write to change anything.
+func RequireOrgRole(role string) fiber.Handler {
+	authz.MustOrgRole(role)
+	return func(c *fiber.Ctx) error {
+		if ok, scope := scopeAllows(c, c.Locals("user").(*jwt.Claims), orgScopes); !ok {
+			return insufficientScope(c, scope)
+		}
+
+		_, orgRole, err := loadOrganization(c)
+		if err != nil {
+			return organizationFailure(c, err)
+		}
+		if !authz.OrgRoleAtLeast(orgRole, role) {
+			return response.Fail(c, "FORBIDDEN", "Organization "+role+" access required", fiber.StatusForbidden)
+		}
+		return c.Next()
+	}
+}
+
+// RequireProjectRole loads the project named by :projectKey into
+// c.Locals("project") and the caller's effective role into
+// c.Locals("project_role"), and only lets callers with at least role
+// through. Projects the caller cannot see, such as private projects they
+// are not a member of, are reported as not found. Under an :orgSlug route
+// the project must belong to that organization. Delegated tokens need
+// project:read, or project:write to change anything.
+func RequireProjectRole(role string) fiber.Handler {
+	authz.MustProjectRole(role)
+	return func(c *fiber.Ctx) error {
+		if ok, scope := scopeAllows(c, c.Locals("user").(*jwt.Claims), projectScopes); !ok {
+			return insufficientScope(c, scope)
+		}
+
+		var project models.Project
+		query := DB(c).Where("key = ?", c.Params("projectKey"))
+		if c.Params("orgSlug") != "" {
+			org, _, err := loadOrganization(c)
+			if err != nil {
+				return organizationFailure(c, err)
+			}
+			query = query.Where("organization_id = ?", org.ID)
+		}
+		err := query.First(&project).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.Fail(c, "NOT_FOUND", "Project not found", fiber.StatusNotFound)
+		}
+		if err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to load project", fiber.StatusInternalServerError)
+		}
+
+		projectRole, err := Authz(c).ProjectRole(&project)
+		if err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to load membership", fiber.StatusInternalServerError)
+		}
+		if projectRole == "" {
+			return response.Fail(c, "NOT_FOUND", "Project not found", fiber.StatusNotFound)
+		}
+		if !authz.ProjectRoleAtLeast(projectRole, role) {
+			return response.Fail(c, "FORBIDDEN", "Project "+role+" access required", fiber.StatusForbidden)
+		}
+
+		c.Locals("project", &project)
+		c.Locals("project_role", projectRole)
+		return c.Next()
+	}
+}
+
+// RequirePermission only lets callers through whose project role grants
+// permission under the project's permission scheme. It must run after
+// RequireProjectRole.
+func RequirePermission(permission string) fiber.Handler {
+	if !authz.IsPermission(permission) {
+		panic("authz: unknown permission " + permission)
+	}
+	return func(c *fiber.Ctx) error {
+		project := c.Locals("project").(*models.Project)
+		allowed, err := Authz(c).Can(project, permission)
+		if err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to load permissions", fiber.StatusInternalServerError)
+		}
+		if !allowed {
+			return response.Fail(c, "PERMISSION_DENIED", "Missing project permission "+permission, fiber.StatusForbidden)
+		}
+		return c.Next()
+	}
+}
+
+var (
+	errOrgNotFound       = errors.New("organization not found")
+	errTwoFactorRequired = errors.New("organization requires two-factor authentication")
+)
+
+// rateLimitError is returned when the organization's plan allows no more
+// requests for now.
+type rateLimitError struct {
+	retryAfter time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return "organization rate limit exceeded"
+}
+
+// loadOrganization resolves :orgSlug and the caller's role in it, reusing
+// an organization an earlier guard already loaded.
+func loadOrganization(c *fiber.Ctx) (*models.Organization, string, error) {
+	if org, ok := c.Locals("organization").(*models.Organization); ok {
+		return org, c.Locals("org_role").(string), nil
+	}
+
+	var org models.Organization
+	err := database.DB.Where("slug = ?", strings.ToLower(c.Params("orgSlug"))).First(&org).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", errOrgNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	orgRole, err := Authz(c).OrgRole(org.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if orgRole == "" {
+		// Hide organizations the caller does not belong to
+		return nil, "", errOrgNotFound
+	}
+
+	satisfied, err := twoFactorSatisfied(&org, c.Locals("user").(*jwt.Claims).UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !satisfied {
+		return nil, "", errTwoFactorRequired
+	}
+
+	allowed, retryAfter, err := entitlements.AllowRequest(c.Context(), &org)
+	if err != nil {
+		return nil, "", err
+	}
+	if !allowed {
+		return nil, "", &rateLimitError{retryAfter: retryAfter}
+	}
+
+	c.Locals("organization", &org)
+	c.Locals("org_role", orgRole)
+	return &org, orgRole, nil
+}
+
+// organizationFailure answers a failed loadOrganization.
+func organizationFailure(c *fiber.Ctx, err error) error {
+	var limited *rateLimitError
+	switch {
+	case errors.Is(err, errOrgNotFound):
+		return response.Fail(c, "NOT_FOUND", "Organization not found", fiber.StatusNotFound)
+	case errors.Is(err, errTwoFactorRequired):
+		return response.Fail(c, "TWO_FACTOR_REQUIRED", "This organization requires two-factor authentication, enable it under /profile/2fa", fiber.StatusForbidden)
+	case errors.As(err, &limited):
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(limited.retryAfter.Seconds())+1))
+		return response.Fail(c, "TOO_MANY_REQUESTS", "The organization's plan allows no more requests this minute", fiber.StatusTooManyRequests)
+	default:
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to load organization", fiber.StatusInternalServerError)
+	}
+}
+
+// twoFactorSatisfied reports whether userID meets the organization's
+// require_2fa setting. Service account bots are exempt.
+func twoFactorSatisfied(org *models.Organization, userID uint) (bool, error) {
+	s, err := settings.ParseOrganization(org.Settings)
+	if err != nil || !s.Require2FA {
+		return err == nil, err
+	}
+
+	var user models.User
+	if err := database.DB.Select("id", "is_bot").First(&user, userID).Error; err != nil {
+		return false, err
+	}
+	if user.IsBot {
+		return true, nil
+	}
+
+	var count int64
+	err = database.DB.Model(&models.UserAuthMethod{}).
+		Where("user_id = ? AND auth_type = ? AND confirmed_at IS NOT NULL", userID, "totp").
+		Count(&count).Error
+	return count > 0, err
+}
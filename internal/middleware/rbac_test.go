@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/authz"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+// guarded serves a route behind guard for a caller holding orgRole in an
+// organization an earlier guard already loaded, and returns the status.
+func guarded(t *testing.T, method string, claims *jwt.Claims, orgRole string, guards ...fiber.Handler) int {
+	t.Helper()
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", claims)
+		c.Locals("organization", &models.Organization{ID: 1, Slug: "acme", PlanType: "free"})
+		c.Locals("org_role", orgRole)
+		return c.Next()
+	})
+	handlers := append(guards, func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	app.Add(method, "/organizations/:orgSlug", handlers...)
+
+	resp, err := app.Test(httptest.NewRequest(method, "/organizations/acme", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+var login = &jwt.Claims{UserID: 7, SessionID: "session"}
+
+func TestRequireOrgRole(t *testing.T) {
+	tests := []struct {
+		role, required string
+		want           int
+	}{
+		{authz.OrgOwner, authz.OrgOwner, fiber.StatusNoContent},
+		{authz.OrgAdmin, authz.OrgOwner, fiber.StatusForbidden},
+		{authz.OrgAdmin, authz.OrgAdmin, fiber.StatusNoContent},
+		{authz.OrgOwner, authz.OrgAdmin, fiber.StatusNoContent},
+		{authz.OrgMember, authz.OrgAdmin, fiber.StatusForbidden},
+		{authz.OrgMember, authz.OrgMember, fiber.StatusNoContent},
+		{authz.OrgGuest, authz.OrgMember, fiber.StatusForbidden},
+		{authz.OrgGuest, authz.OrgGuest, fiber.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if got := guarded(t, fiber.MethodGet, login, tt.role, RequireOrgRole(tt.required)); got != tt.want {
+			t.Errorf("%s on a route requiring %s: status = %d, want %d", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestRequireOrgRoleScopes(t *testing.T) {
+	pat := &jwt.Claims{UserID: 7, Scope: "org:read project:read"}
+	if got := guarded(t, fiber.MethodGet, pat, authz.OrgOwner, RequireOrgRole(authz.OrgMember)); got != fiber.StatusNoContent {
+		t.Errorf("read with org:read: status = %d, want %d", got, fiber.StatusNoContent)
+	}
+	if got := guarded(t, fiber.MethodPatch, pat, authz.OrgOwner, RequireOrgRole(authz.OrgAdmin)); got != fiber.StatusForbidden {
+		t.Errorf("write without org:write: status = %d, want %d", got, fiber.StatusForbidden)
+	}
+	if got := guarded(t, fiber.MethodPatch, pat, authz.OrgOwner, RequireSessionToken(), RequireOrgRole(authz.OrgAdmin)); got != fiber.StatusForbidden {
+		t.Errorf("delegated token on a session-only route: status = %d, want %d", got, fiber.StatusForbidden)
+	}
+	if got := guarded(t, fiber.MethodPatch, login, authz.OrgOwner, RequireSessionToken(), RequireOrgRole(authz.OrgAdmin)); got != fiber.StatusNoContent {
+		t.Errorf("login on a session-only route: status = %d, want %d", got, fiber.StatusNoContent)
+	}
+}
+
+func TestRequireOrgRoleUnknownRole(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RequireOrgRole accepted an unknown role")
+		}
+	}()
+	RequireOrgRole("superadmin")
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/audit"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+// auditServiceAccount runs the rest of the chain and writes every request
+// made with a service account token to the audit log of its organization.
+// Tokens of disabled or deleted accounts are refused.
+func auditServiceAccount(c *fiber.Ctx, claims *jwt.Claims) error {
+	var account models.ServiceAccount
+	if err := database.DB.Select("id", "organization_id", "disabled_at").
+		Where("client_id = ?", claims.ClientID).First(&account).Error; err != nil || account.DisabledAt != nil {
+		return response.Fail(c, "UNAUTHORIZED", "Service account is disabled", fiber.StatusUnauthorized)
+	}
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		status = fiberErr.Code
+	}
+	entryErr := audit.Record(database.DB, c, audit.Entry{
+		OrganizationID: &account.OrganizationID,
+		Action:         "service_account.request",
+		TargetType:     "service_account",
+		TargetID:       strconv.FormatUint(uint64(account.ID), 10),
+		Metadata: map[string]any{
+			"token_id": claims.ID,
+			"method":   c.Method(),
+			"path":     c.Path(),
+			"status":   status,
+		},
+	})
+	if entryErr != nil {
+		log.Printf("audit: failed to record request of service account %d: %v", account.ID, entryErr)
+	}
+	return err
+}
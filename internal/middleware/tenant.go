@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+// errRollback discards the tenant transaction of a failed request
+var errRollback = errors.New("rollback tenant transaction")
+
+// DB returns the database handle for the current request: the tenant
+// transaction opened by TenantScope, or database.DB outside of one.
+// Handlers reading tenant tables must use it, since with TENANT_RLS those
+// tables return no rows outside a tenant transaction.
+func DB(c *fiber.Ctx) *gorm.DB {
+	if tx, ok := c.Locals("db").(*gorm.DB); ok {
+		return tx
+	}
+	return database.DB
+}
+
+// TenantScope runs the rest of an :orgSlug request in a transaction scoped
+// to the organization and caller, so Postgres row-level security hides the
+// rows of every other tenant. The transaction is rolled back when the
+// request fails. It does nothing unless TENANT_RLS is enabled, and must run
+// after JWTAuthMiddleware.
+func TenantScope() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !config.Env.TenantRLS {
+			return c.Next()
+		}
+		org, _, err := loadOrganization(c)
+		if err != nil {
+			return organizationFailure(c, err)
+		}
+		claims := c.Locals("user").(*jwt.Claims)
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := database.SetTenant(tx, org.ID, claims.UserID); err != nil {
+				return err
+			}
+			c.Locals("db", tx)
+			// Resolve roles again inside the transaction
+			c.Locals("authz", nil)
+			defer c.Locals("db", nil)
+
+			if err := c.Next(); err != nil {
+				return err
+			}
+			if c.Response().StatusCode() >= fiber.StatusBadRequest {
+				return errRollback
+			}
+			return nil
+		})
+		if errors.Is(err, errRollback) {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+// RequireVerifiedEmail blocks users whose email is not verified yet when
+// REQUIRE_VERIFIED_EMAIL is enabled. It must run after JWTAuthMiddleware.
+func RequireVerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !config.Env.RequireVerified {
+			return c.Next()
+		}
+
+		claims, err := jwt.GetClaimsFromFiberContext(c)
+		if err != nil {
+			return response.Fail(c, "UNAUTHORIZED", "Unauthorized", fiber.StatusUnauthorized)
+		}
+
+		var user models.User
+		if err := database.DB.Select("id", "is_email_verified").First(&user, claims.UserID).Error; err != nil {
+			return response.Fail(c, "UNAUTHORIZED", "Unauthorized", fiber.StatusUnauthorized)
+		}
+		if !user.IsEmailVerified {
+			return response.Fail(c, "EMAIL_NOT_VERIFIED", "Please verify your email address first", fiber.StatusForbidden)
+		}
+
+		return c.Next()
+	}
+}
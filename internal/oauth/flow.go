@@ -0,0 +1,82 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	"golang.org/x/oauth2"
+)
+
+// flowTTL is how long a user has to finish the login at the provider.
+const flowTTL = 10 * time.Minute
+
+// flowState is kept server side between Begin and Complete, so the PKCE
+// verifier and the nonce never reach the browser.
+type flowState struct {
+	Provider string `json:"provider"`
+	UserID   uint   `json:"user_id,omitempty"` // set when linking to a signed-in user
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// Begin starts an authorization code flow and returns the URL to send the
+// user to together with the state the callback has to present. userID is
+// zero for logins and the signed-in user when linking an account.
+func Begin(ctx context.Context, p Provider, userID uint) (authURL, state string, err error) {
+	state, err = auth.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	fs := flowState{Provider: p.Name(), UserID: userID, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}
+	authURL, err = p.AuthCodeURL(ctx, state, fs.Nonce, fs.Verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	data, err := json.Marshal(fs)
+	if err != nil {
+		return "", "", err
+	}
+	if err := cache.Default.Set(ctx, "oauth:state:"+state, string(data), flowTTL); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// Complete checks the state, which can be used once, and exchanges the code.
+// userID must match the one given to Begin, so a login flow cannot be
+// completed as a link and vice versa.
+func Complete(ctx context.Context, p Provider, state, code string, userID uint) (*Identity, error) {
+	key := "oauth:state:" + state
+	data, err := cache.Default.Get(ctx, key)
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, ErrInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fresh, err := cache.Default.SetNX(ctx, key+":used", "1", flowTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrInvalidState
+	}
+	_ = cache.Default.Delete(ctx, key)
+
+	var fs flowState
+	if err := json.Unmarshal([]byte(data), &fs); err != nil || fs.Provider != p.Name() || fs.UserID != userID {
+		return nil, ErrInvalidState
+	}
+	return p.Exchange(ctx, code, fs.Verifier, fs.Nonce)
+}
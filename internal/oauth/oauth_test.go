@@ -0,0 +1,354 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const testClientID = "test-client"
+
+// idp is a local stand-in for an OpenID Connect provider. It serves
+// discovery, JWKS and a token endpoint that enforces PKCE, plus the GitHub
+// user API.
+type idp struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]grant
+
+	// claims returns the ID token claims for a grant
+	claims func(g grant) jwt.MapClaims
+	// githubUser and githubEmails are served under /user and /user/emails
+	githubUser   map[string]any
+	githubEmails []map[string]any
+}
+
+// grant is what the authorization endpoint remembered about a code
+type grant struct {
+	challenge string
+	nonce     string
+}
+
+func newIDP(t *testing.T) *idp {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &idp{t: t, key: key, codes: map[string]grant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kid": "test",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, p.githubUser)
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, p.githubEmails)
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	p.claims = func(g grant) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            p.server.URL,
+			"aud":            testClientID,
+			"sub":            "user-1",
+			"nonce":          g.nonce,
+			"email":          "jane@example.com",
+			"email_verified": true,
+			"name":           "Jane Doe",
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Minute).Unix(),
+		}
+	}
+	return p
+}
+
+// authorize plays the user approving the login: it records the PKCE
+// challenge and nonce of authURL and returns a code for them.
+func (p *idp) authorize(authURL string) string {
+	p.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		p.t.Fatalf("auth URL lacks a S256 PKCE challenge: %s", authURL)
+	}
+
+	code := "code-" + q.Get("state")
+	p.mu.Lock()
+	p.codes[code] = grant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	p.mu.Unlock()
+	return code
+}
+
+func (p *idp) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.mu.Lock()
+	g, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, p.claims(g))
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]string{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (p *idp) oidcProvider(name string, extraIssuers ...string) *OIDCProvider {
+	return NewOIDCProvider(OIDCConfig{
+		Name:         name,
+		Issuer:       p.server.URL,
+		ExtraIssuers: extraIssuers,
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	})
+}
+
+func (p *idp) githubProvider() *GitHubProvider {
+	gh := NewGitHubProvider(testClientID, "secret", "http://localhost/callback")
+	gh.conf.Endpoint = oauth2.Endpoint{AuthURL: p.server.URL + "/authorize", TokenURL: p.server.URL + "/token"}
+	gh.apiURL = p.server.URL
+	return gh
+}
+
+// login runs Begin, the user's approval and Complete
+func login(t *testing.T, p *idp, provider Provider) (*Identity, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, state, err := Begin(ctx, provider, 0)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	return Complete(ctx, provider, state, p.authorize(authURL), 0)
+}
+
+func TestGenericOIDCClaimMapping(t *testing.T) {
+	p := newIDP(t)
+	p.claims = func(g grant) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":                p.server.URL,
+			"aud":                testClientID,
+			"sub":                "kc-42",
+			"nonce":              g.nonce,
+			"email":              "sam@corp.example",
+			"email_verified":     "true", // some providers send a string
+			"preferred_username": "sam",
+			"given_name":         "Sam",
+			"family_name":        "Smith",
+			"iat":                time.Now().Unix(),
+			"exp":                time.Now().Add(time.Minute).Unix(),
+		}
+	}
+
+	identity, err := login(t, p, p.oidcProvider("keycloak"))
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	want := Identity{
+		Provider:      "keycloak",
+		Subject:       "kc-42",
+		Email:         "sam@corp.example",
+		EmailVerified: true,
+		Name:          "sam",
+		GivenName:     "Sam",
+		FamilyName:    "Smith",
+	}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestGoogleClaimMapping(t *testing.T) {
+	p := newIDP(t)
+	p.claims = func(g grant) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            "accounts.google.com", // Google also issues without the scheme
+			"aud":            testClientID,
+			"sub":            "1170000000",
+			"nonce":          g.nonce,
+			"email":          "jane@gmail.com",
+			"email_verified": true,
+			"name":           "Jane Doe",
+			"given_name":     "Jane",
+			"family_name":    "Doe",
+			"picture":        "https://example.com/jane.png",
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Minute).Unix(),
+		}
+	}
+
+	identity, err := login(t, p, p.oidcProvider("google", "accounts.google.com"))
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	want := Identity{
+		Provider:      "google",
+		Subject:       "1170000000",
+		Email:         "jane@gmail.com",
+		EmailVerified: true,
+		Name:          "Jane Doe",
+		GivenName:     "Jane",
+		FamilyName:    "Doe",
+		Picture:       "https://example.com/jane.png",
+	}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestGitHubClaimMapping(t *testing.T) {
+	p := newIDP(t)
+	p.githubUser = map[string]any{"id": 583231, "login": "octocat", "name": "", "avatar_url": "https://example.com/octocat.png"}
+	p.githubEmails = []map[string]any{
+		{"email": "public@example.com", "primary": false, "verified": true},
+		{"email": "octocat@example.com", "primary": true, "verified": false},
+	}
+
+	identity, err := login(t, p, p.githubProvider())
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	want := Identity{
+		Provider:      "github",
+		Subject:       "583231",
+		Email:         "octocat@example.com",
+		EmailVerified: false,
+		Name:          "octocat",
+		Picture:       "https://example.com/octocat.png",
+	}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestStateMismatch(t *testing.T) {
+	p := newIDP(t)
+	provider := p.oidcProvider("keycloak")
+	ctx := context.Background()
+
+	authURL, state, err := Begin(ctx, provider, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := p.authorize(authURL)
+
+	if _, err := Complete(ctx, provider, "forged-state", code, 0); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("unknown state: err = %v, want ErrInvalidState", err)
+	}
+	// A login flow cannot be completed as a link to another account
+	if _, err := Complete(ctx, provider, state, code, 7); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("link with login state: err = %v, want ErrInvalidState", err)
+	}
+	// The state was spent by the attempt above
+	if _, err := Complete(ctx, provider, state, code, 0); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("reused state: err = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestNonceMismatch(t *testing.T) {
+	p := newIDP(t)
+	defaults := p.claims
+	p.claims = func(g grant) jwt.MapClaims {
+		claims := defaults(g)
+		claims["nonce"] = "replayed-nonce"
+		return claims
+	}
+
+	if _, err := login(t, p, p.oidcProvider("keycloak")); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestPKCEVerifierRequired(t *testing.T) {
+	p := newIDP(t)
+	ctx := context.Background()
+
+	for _, provider := range []Provider{p.oidcProvider("keycloak"), p.githubProvider()} {
+		verifier := oauth2.GenerateVerifier()
+		authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := p.authorize(authURL)
+
+		if _, err := provider.Exchange(ctx, code, oauth2.GenerateVerifier(), "nonce"); err == nil {
+			t.Fatalf("%s: exchange with the wrong verifier succeeded", provider.Name())
+		}
+	}
+}
+
+func TestIDTokenFromOtherIssuerRejected(t *testing.T) {
+	p := newIDP(t)
+	defaults := p.claims
+	p.claims = func(g grant) jwt.MapClaims {
+		claims := defaults(g)
+		claims["iss"] = "https://evil.example"
+		return claims
+	}
+
+	if _, err := login(t, p, p.oidcProvider("keycloak")); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+	}
+}
@@ -0,0 +1,122 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/config"
+)
+
+var (
+	ErrUnknownProvider = errors.New("oauth: unknown provider")
+	ErrInvalidState    = errors.New("oauth: invalid or expired state")
+	ErrInvalidIDToken  = errors.New("oauth: invalid id token")
+)
+
+// Identity is the provider account mapped onto the fields the API needs.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+	Picture       string
+}
+
+// Provider is an external identity provider using the authorization code
+// flow with PKCE.
+type Provider interface {
+	Name() string
+	// AuthCodeURL returns the URL the user is sent to. Providers without
+	// OpenID Connect ignore the nonce.
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	// Exchange redeems the code and returns the verified identity.
+	Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error)
+}
+
+// IDTokenVerifier is implemented by providers that can check an ID token
+// obtained by the client directly, e.g. Google One Tap credentials.
+type IDTokenVerifier interface {
+	VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Identity, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+)
+
+// httpClient is used for discovery, JWKS, token and user info requests.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Register adds or replaces a provider.
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[p.Name()] = p
+}
+
+// Get returns the provider registered under name.
+func Get(name string) (Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// Init registers the providers configured in config.Env. Google is always
+// registered; the others only when a client id is set.
+func Init() error {
+	env := config.Env
+
+	Register(NewOIDCProvider(OIDCConfig{
+		Name:         "google",
+		Issuer:       "https://accounts.google.com",
+		ExtraIssuers: []string{"accounts.google.com"},
+		ClientID:     env.GoogleClientID,
+		ClientSecret: env.GoogleClientSecret,
+		RedirectURL:  env.GoogleRedirectURI,
+	}))
+
+	if env.GitHubClientID != "" {
+		Register(NewGitHubProvider(env.GitHubClientID, env.GitHubClientSecret, env.GitHubRedirectURI))
+	}
+
+	if env.MicrosoftClientID != "" {
+		Register(NewOIDCProvider(OIDCConfig{
+			Name:         "microsoft",
+			Issuer:       fmt.Sprintf("https://login.microsoftonline.com/%s/v2.0", env.MicrosoftTenant),
+			ClientID:     env.MicrosoftClientID,
+			ClientSecret: env.MicrosoftClientSecret,
+			RedirectURL:  env.MicrosoftRedirectURI,
+		}))
+	}
+
+	if env.OIDCClientID != "" {
+		if env.OIDCIssuer == "" {
+			return fmt.Errorf("OIDC_ISSUER is required for provider %q", env.OIDCProviderName)
+		}
+		var scopes []string
+		if env.OIDCScopes != "" {
+			scopes = strings.Fields(strings.ReplaceAll(env.OIDCScopes, ",", " "))
+		}
+		Register(NewOIDCProvider(OIDCConfig{
+			Name:         env.OIDCProviderName,
+			Issuer:       env.OIDCIssuer,
+			ClientID:     env.OIDCClientID,
+			ClientSecret: env.OIDCClientSecret,
+			RedirectURL:  env.OIDCRedirectURI,
+			Scopes:       scopes,
+		}))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/lockout"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"gorm.io/gorm"
+)
+
+func SetupAdminRoutes(router *fiber.App) {
+	adminGroup := router.Group("/admin")
+	adminGroup.Use(middleware.JWTAuthMiddleware(), middleware.RequireSessionToken(), middleware.RequireAdmin())
+	adminGroup.Post("/users/:id/unlock", unlockUserHandler)
+	adminGroup.Post("/users/:id/impersonate", impersonateUserHandler)
+	adminGroup.Put("/organizations/:id/plan", updateOrganizationPlanHandler)
+}
+
+// unlockUserHandler lifts a login lockout
+// @Summary Unlock User
+// @Description Clear failed login counters and lockouts of a user. Users suspended by repeated lockouts are reactivated.
+// @Tags ADMIN
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /admin/users/{id}/unlock [post]
+func unlockUserHandler(c *fiber.Ctx) error {
+	db := database.DB
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+	}
+
+	var user models.User
+	if err := db.First(&user, id).Error; err != nil {
+		return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
+	}
+
+	if err := lockout.Unlock(c.Context(), user.Email); err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to clear lockout", fiber.StatusInternalServerError)
+	}
+
+	suspended, err := isSuspended(db, &user)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to resolve user status", fiber.StatusInternalServerError)
+	}
+	if suspended {
+		activeID, err := userStatusID(db, "active")
+		if err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to resolve user status", fiber.StatusInternalServerError)
+		}
+		if err := db.Model(&user).Update("status_id", activeID).Error; err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to reactivate user", fiber.StatusInternalServerError)
+		}
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "User unlocked",
+		"user":    toUserInfo(&user),
+	}, fiber.StatusOK)
+}
+
+// updateOrganizationPlanHandler changes the plan of an organization
+// @Summary Update Organization Plan
+// @Description Move an organization to another plan, e.g. after a purchase. Organizations cannot change their own plan.
+// @Tags ADMIN
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param request body schema.UpdateOrganizationPlan true "Update Organization Plan Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /admin/organizations/{id}/plan [put]
+func updateOrganizationPlanHandler(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+	}
+
+	var req schema.UpdateOrganizationPlan
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	var org models.Organization
+	err = database.DB.First(&org, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.Fail(c, "NOT_FOUND", "Organization not found", fiber.StatusNotFound)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load organization", fiber.StatusInternalServerError)
+	}
+
+	previous := org.PlanType
+	if req.PlanType != previous {
+		if err := database.DB.Model(&org).Update("plan_type", req.PlanType).Error; err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to update plan", fiber.StatusInternalServerError)
+		}
+		auditOrganization(c, &org, "organization.plan_changed", map[string]any{
+			"from": previous,
+			"to":   req.PlanType,
+		})
+	}
+
+	return response.OK(c, toOrganizationResponse(&org), fiber.StatusOK)
+}
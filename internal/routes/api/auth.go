@@ -1,26 +1,19 @@
 package api
 
 import (
-	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"net/http"
-	"net/url"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/phonsing-Hub/GoLang/internal/config"
 	"github.com/phonsing-Hub/GoLang/internal/database"
 	"github.com/phonsing-Hub/GoLang/internal/database/models"
 	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/lockout"
 	"github.com/phonsing-Hub/GoLang/internal/middleware"
 	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
 	"github.com/phonsing-Hub/GoLang/internal/utils/response"
 	"github.com/phonsing-Hub/GoLang/pkg/auth"
 	"github.com/phonsing-Hub/GoLang/pkg/jwt"
-	"google.golang.org/api/idtoken"
-	"gorm.io/gorm"
 )
 
 
@@ -28,33 +21,30 @@ func SetupAuthRoutes(router *fiber.App) {
 	authGroup := router.Group("/auth")
 	authGroup.Post("/register", registerUser)
 	authGroup.Post("/login", loginUser)
-	// Google OAuth routes
+	authGroup.Post("/2fa/verify", verifyMFA)
+	authGroup.Post("/refresh", refreshToken)
+	authGroup.Post("/logout", middleware.JWTAuthMiddleware(), middleware.RequireSessionToken(), logout)
+	authGroup.Post("/logout-all", middleware.JWTAuthMiddleware(), middleware.RequireSessionToken(), logoutAll)
+	authGroup.Post("/impersonation/end", middleware.JWTAuthMiddleware(), endImpersonation)
+	authGroup.Post("/forgot-password", forgotPassword)
+	authGroup.Post("/reset-password", resetPassword)
+	authGroup.Post("/magic-link", requestMagicLink)
+	authGroup.Post("/magic-link/verify", verifyMagicLink)
+	authGroup.Post("/verify-email", verifyEmail)
+	authGroup.Post("/resend-verification", middleware.JWTAuthMiddleware(), resendVerification)
+	// OAuth / OpenID Connect routes
+	authGroup.Get("/oauth/:provider/authorize", oauthAuthorize)
+	authGroup.Post("/oauth/:provider/callback", oauthCallback)
 	authGroup.Post("/google", googleLogin)
 	authGroup.Post("/google/callback", googleCallback)
-
-	authGroup.Get("/userinfo", middleware.JWTAuthMiddleware(), getUserInfo)
-}
-
-func exchangeCodeForToken(code string) (*schema.GoogleTokenResponse, error) {
-	values := url.Values{}
-	values.Set("code", code)
-	values.Set("client_id", config.Env.GoogleClientID)
-	values.Set("client_secret", config.Env.GoogleClientSecret)
-	values.Set("redirect_uri", config.Env.GoogleRedirectURI)
-	values.Set("grant_type", config.Env.GoogleGrantType)
-
-	resp, err := http.PostForm(config.Env.GoogleTokenURL, values)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var tokenRes schema.GoogleTokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tokenRes); err != nil {
-		return nil, err
-	}
-	return &tokenRes, nil
-
+	// SAML single sign-on for enterprise organizations
+	authGroup.Post("/sso/discover", ssoDiscover)
+	authGroup.Get("/sso/saml/:orgSlug/login", ssoLogin)
+	authGroup.Get("/sso/saml/:orgSlug/metadata", ssoMetadata)
+	authGroup.Post("/sso/saml/:orgSlug/acs", ssoACS)
+	authGroup.Post("/sso/exchange", ssoExchange)
+
+	authGroup.Get("/userinfo", middleware.JWTAuthMiddleware(middleware.ScopeRule{Read: middleware.ScopeProfileRead}), getUserInfo)
 }
 
 // getUserInfo retrieves the authenticated user's information
@@ -76,29 +66,13 @@ func getUserInfo(c *fiber.Ctx) error {
 		return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
 	}
 
-	token, err := jwt.GenerateToken(user.ID, user.Email)
-	if err != nil {
-		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate token", fiber.StatusInternalServerError)
-	}
-
 	now := time.Now()
 	if err := database.DB.Model(&user).Update("last_login_at", &now).Error; err != nil {
 		return response.Fail(c, "DB_ERROR", "Failed to update last login", fiber.StatusInternalServerError)
 	}
 
-	userInfo := schema.UserInfo{
-		ID:              user.ID,
-		Email:           user.Email,
-		FirstName:       user.FirstName,
-		LastName:        user.LastName,
-		DisplayName:     user.DisplayName,
-		Avatar:          user.Avatar,
-		IsEmailVerified: user.IsEmailVerified,
-		LastLoginAt:     user.LastLoginAt,
-	}
 	return response.OK(c, fiber.Map{
-		"user":  userInfo,
-		"token": token,
+		"user": toUserInfo(&user),
 	}, fiber.StatusOK)
 }
 
@@ -131,12 +105,22 @@ func registerUser(c *fiber.Ctx) error {
 		return response.Fail(c, "EMAIL_EXISTS", "Email already registered", fiber.StatusBadRequest)
 	}
 
+	if err := auth.ValidatePassword(req.Password, req.Email); err != nil {
+		return response.Fail(c, "WEAK_PASSWORD", err.Error(), fiber.StatusBadRequest)
+	}
+
 	// Hash password
 	hashedPassword, err := auth.HashPassword(req.Password)
 	if err != nil {
 		return response.Fail(c, "INTERNAL_ERROR", "Failed to hash password", fiber.StatusInternalServerError)
 	}
 
+	// New accounts stay pending until the email address is confirmed
+	pendingStatusID, err := userStatusID(database.DB, "pending_verification")
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to resolve user status", fiber.StatusInternalServerError)
+	}
+
 	// Begin transaction
 	tx := database.DB.Begin()
 	defer func() {
@@ -152,6 +136,7 @@ func registerUser(c *fiber.Ctx) error {
 		FirstName:   req.FirstName,
 		LastName:    req.LastName,
 		DisplayName: req.DisplayName,
+		StatusID:    pendingStatusID,
 		LastLoginAt: &now,
 	}
 	if err := tx.Create(&user).Error; err != nil {
@@ -177,32 +162,16 @@ func registerUser(c *fiber.Ctx) error {
 		return response.Fail(c, "DATABASE_ERROR", "Failed to commit transaction", fiber.StatusInternalServerError)
 	}
 
-	// Generate JWT token
-	token, err := jwt.GenerateToken(user.ID, user.Email)
-	if err != nil {
-		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate token", fiber.StatusInternalServerError)
-	}
-
-	userInfo := schema.UserInfo{
-		ID:              user.ID,
-		Email:           user.Email,
-		FirstName:       user.FirstName,
-		LastName:        user.LastName,
-		DisplayName:     user.DisplayName,
-		Avatar:          user.Avatar,
-		IsEmailVerified: user.IsEmailVerified,
-		LastLoginAt:     user.LastLoginAt,
+	if err := sendVerificationEmail(&user); err != nil {
+		log.Printf("register: failed to send verification email to user %d: %v", user.ID, err)
 	}
 
-	return response.OK(c, fiber.Map{
-		"user":  userInfo,
-		"token": token,
-	}, fiber.StatusCreated)
+	return respondWithTokenPair(c, &user, fiber.StatusCreated)
 }
 
 // loginUser handles user login
 // @Summary User Login
-// @Description Login user with email and password
+// @Description Login user with email and password. Repeated failures lock the account and client IP for an increasing time.
 // @Tags AUTH
 // @Accept json
 // @Produce json
@@ -210,6 +179,8 @@ func registerUser(c *fiber.Ctx) error {
 // @Success 200 {object} response.SWSuccessResponse
 // @Failure 400 {object} response.SWErrorResponse
 // @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 429 {object} response.SWErrorResponse
 // @Failure 500 {object} response.SWErrorResponse
 // @Router /auth/login [post]
 func loginUser(c *fiber.Ctx) error {
@@ -224,310 +195,50 @@ func loginUser(c *fiber.Ctx) error {
 		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
 	}
 
-	// Find user by email or username
-	var user models.User
-	if err := database.DB.Preload("AuthMethods").Where("email = ? ", req.Email).First(&user).Error; err != nil {
-		return response.Fail(c, "USER_NOT_FOUND", "Invalid email or password", fiber.StatusNotFound)
-	}
-
-	// Verify password
-	if !auth.CheckPasswordHash(req.Password, user.AuthMethods[0].PasswordHash) {
-		return response.Fail(c, "INVALID_CREDENTIALS", "Invalid email or password", fiber.StatusUnauthorized)
-	}
-
-	// Generate JWT token
-	token, err := jwt.GenerateToken(user.ID, user.Email)
+	wait, err := lockout.Check(c.Context(), req.Email, c.IP())
 	if err != nil {
-		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate token", fiber.StatusInternalServerError)
-	}
-
-	now := time.Now()
-
-	if err := database.DB.Model(&user).Update("last_login_at", &now).Error; err != nil {
-		return response.Fail(c, "DB_ERROR", "Failed to update last login", fiber.StatusInternalServerError)
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to check login attempts", fiber.StatusInternalServerError)
 	}
-	userInfo := schema.UserInfo{
-		ID:              user.ID,
-		Email:           user.Email,
-		FirstName:       user.FirstName,
-		LastName:        user.LastName,
-		DisplayName:     user.DisplayName,
-		Avatar:          user.Avatar,
-		IsEmailVerified: user.IsEmailVerified,
-		LastLoginAt:     user.LastLoginAt,
+	if wait > 0 {
+		return tooManyAttempts(c, wait)
 	}
 
-	return response.OK(c, fiber.Map{
-		"user":  userInfo,
-		"token": token,
-	}, fiber.StatusOK)
-}
-
-// googleLogin redirects user to Google OAuth
-// @Summary Google OAuth Login
-// @Description Redirect to Google OAuth login
-// @Tags AUTH
-// @Accept json
-// @Produce json
-// @Success 200 {object} response.SWSuccessResponse
-// @Router /auth/google [post]
-func googleLogin(c *fiber.Ctx) error {
-	authProvider := "google"
-	authType := "oauth"
-	now := time.Now()
-	type Req struct {
-		Credential string `json:"credential"`
-	}
-	var req Req
-	if err := c.BodyParser(&req); err != nil {
-		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	if org, err := ssoEnforcedFor(database.DB, req.Email); err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to look up single sign-on", fiber.StatusInternalServerError)
+	} else if org != nil {
+		return ssoRequired(c)
 	}
 
-	payload, err := idtoken.Validate(context.Background(), req.Credential, config.Env.GoogleClientID)
-	if err != nil {
-		return response.Fail(c, "INVALID_TOKEN", "Invalid token", fiber.StatusUnauthorized)
-	}
-
-	googleID := payload.Claims["sub"].(string)
-	email := payload.Claims["email"].(string)
-	name := payload.Claims["name"].(string)
-	FirstName := payload.Claims["given_name"].(string)
-	LastName := payload.Claims["family_name"].(string)
-	picture := payload.Claims["picture"].(string)
-
-	// Check if user already exists
-	var existingAuth models.UserAuthMethod
-	result := database.DB.Where("auth_provider = ? AND provider_id = ?", authProvider, googleID).First(&existingAuth)
-
-	if result.Error == nil {
-		var user models.User
-		if err := database.DB.Where("id = ?", existingAuth.UserID).First(&user).Error; err != nil {
-			return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
-		}
-		if err := database.DB.Model(&user).Update("last_login_at", &now).Error; err != nil {
-			return response.Fail(c, "DB_ERROR", "Failed to update last login", fiber.StatusInternalServerError)
-		}
-		token, err := jwt.GenerateToken(user.ID, user.Email)
-		if err != nil {
-			return response.Fail(c, "INTERNAL_ERROR", "Failed to generate token", fiber.StatusInternalServerError)
-		}
-		userInfo := schema.UserInfo{
-			ID:              user.ID,
-			Email:           user.Email,
-			FirstName:       user.FirstName,
-			LastName:        user.LastName,
-			DisplayName:     user.DisplayName,
-			Avatar:          user.Avatar,
-			IsEmailVerified: user.IsEmailVerified,
-			LastLoginAt:     user.LastLoginAt,
-		}
-		return response.OK(c, fiber.Map{
-			"user":  userInfo,
-			"token": token,
-		}, fiber.StatusOK)
+	// Unknown emails, accounts without a password and wrong passwords all
+	// get the same response after one hash comparison
+	var user models.User
+	if err := database.DB.Preload("AuthMethods").Where("email = ? ", req.Email).First(&user).Error; err != nil {
+		auth.CheckPasswordDummy(req.Password)
+		return loginFailed(c, nil, req.Email)
 	}
 
-	newUser := models.User{
-		Email:           email,
-		DisplayName:     name,
-		Avatar:          picture,
-		FirstName:       FirstName,
-		LastName:        LastName,
-		LastLoginAt:     &now,
-		IsEmailVerified: true,
-	}
-	if err := database.DB.Create(&newUser).Error; err != nil {
-		return response.Fail(c, "DATABASE_ERROR", "Failed to create user", fiber.StatusInternalServerError)
-	}
-	userAuth := models.UserAuthMethod{
-		UserID:       newUser.ID,
-		AuthType:     authType,
-		AuthProvider: &authProvider,
-		ProviderID:   &googleID,
-		IsPrimary:    true,
+	method := findAuthMethod(user.AuthMethods, "password")
+	if method == nil {
+		auth.CheckPasswordDummy(req.Password)
+		return loginFailed(c, &user, req.Email)
 	}
-	if err := database.DB.Create(&userAuth).Error; err != nil {
-		return response.Fail(c, "DATABASE_ERROR", "Failed to create user auth method", fiber.StatusInternalServerError)
+	ok, needsRehash := auth.VerifyPassword(req.Password, method.PasswordHash)
+	if !ok {
+		return loginFailed(c, &user, req.Email)
 	}
-	token, err := jwt.GenerateToken(newUser.ID, newUser.Email)
-	if err != nil {
-		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate token", fiber.StatusInternalServerError)
+	if needsRehash {
+		upgradePasswordHash(method, req.Password)
 	}
 
-	userInfo := schema.UserInfo{
-		ID:              newUser.ID,
-		Email:           newUser.Email,
-		FirstName:       newUser.FirstName,
-		LastName:        newUser.LastName,
-		DisplayName:     newUser.DisplayName,
-		Avatar:          newUser.Avatar,
-		IsEmailVerified: newUser.IsEmailVerified,
-		LastLoginAt:     newUser.LastLoginAt,
+	if err := lockout.Reset(c.Context(), req.Email); err != nil {
+		log.Printf("login: failed to reset attempts for user %d: %v", user.ID, err)
 	}
 
-	return response.OK(c, fiber.Map{
-		"user":  userInfo,
-		"token": token,
-	}, fiber.StatusCreated)
-}
-
-// googleCallback handles Google OAuth callback
-// @Summary Google OAuth Callback
-// @Description Handle Google OAuth callback and exchange code for tokens
-// @Tags AUTH
-// @Accept json
-// @Produce json
-// @Param request body object{code=string} true "Authorization Code Request" example({"code": "4/0AX4XfWh..."})
-// @Success 200 {object} response.SWSuccessResponse
-// @Failure 400 {object} response.SWErrorResponse
-// @Failure 401 {object} response.SWErrorResponse
-// @Failure 500 {object} response.SWErrorResponse
-// @Router /auth/google/callback [post]
-func googleCallback(c *fiber.Ctx) error {
-	authProvider := "google"
-	authType := "oauth"
 	now := time.Now()
-	type Req struct {
-		Code string `json:"code"`
-	}
-	var req Req
-	if err := c.BodyParser(&req); err != nil {
-		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
-	}
-
-	// Step 1: แลก code เป็น token
-	tokenResp, err := exchangeCodeForToken(req.Code)
-	if err != nil {
-		return response.Fail(c, "TOKEN_EXCHANGE_ERROR", "Failed to exchange code", fiber.StatusUnauthorized)
-	}
-
-	// Step 2: Decode id_token (JWT)
-	fmt.Println("ID Token:", tokenResp.IDToken)
-	payload, err := idtoken.Validate(context.Background(), tokenResp.IDToken, config.Env.GoogleClientID)
-	if err != nil {
-		return response.Fail(c, "INVALID_TOKEN", "Invalid ID Token", fiber.StatusUnauthorized)
-	}
-
-	googleID := payload.Claims["sub"].(string)
-	email := payload.Claims["email"].(string)
-	name := payload.Claims["name"].(string)
-	FirstName := payload.Claims["given_name"].(string)
-	LastName := payload.Claims["family_name"].(string)
-	picture := payload.Claims["picture"].(string)
-
-	// Check if user already exists
-	var existingAuth models.UserAuthMethod
-	result := database.DB.Where("auth_provider = ? AND provider_id = ?", authProvider, googleID).First(&existingAuth)
-
-	if result.Error == nil {
-		var user models.User
-		if err := database.DB.Where("id = ?", existingAuth.UserID).First(&user).Error; err != nil {
-			return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
-		}
-		if err := database.DB.Model(&user).Update("last_login_at", &now).Error; err != nil {
-			return response.Fail(c, "DB_ERROR", "Failed to update last login", fiber.StatusInternalServerError)
-		}
-		token, err := jwt.GenerateToken(user.ID, user.Email)
-		if err != nil {
-			return response.Fail(c, "INTERNAL_ERROR", "Failed to generate token", fiber.StatusInternalServerError)
-		}
-		userInfo := schema.UserInfo{
-			ID:              user.ID,
-			Email:           user.Email,
-			FirstName:       user.FirstName,
-			LastName:        user.LastName,
-			DisplayName:     user.DisplayName,
-			Avatar:          user.Avatar,
-			IsEmailVerified: user.IsEmailVerified,
-			LastLoginAt:     user.LastLoginAt,
-		}
-		return response.OK(c, fiber.Map{
-			"user":  userInfo,
-			"token": token,
-		}, fiber.StatusOK)
-	}
-
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		var existingUser models.User
-		if err := database.DB.Where("email = ?", email).First(&existingUser).Error; err == nil {
-			if emailVerified, ok := payload.Claims["email_verified"].(bool); ok && emailVerified {
-
-				userAuth := models.UserAuthMethod{
-					UserID:       existingUser.ID,
-					AuthType:     authType,
-					AuthProvider: &authProvider,
-					ProviderID:   &googleID,
-					IsPrimary:    false,
-				}
 
-				if err := database.DB.Create(&userAuth).Error; err != nil {
-					return response.Fail(c, "DB_ERROR", "Failed to link Google account", fiber.StatusInternalServerError)
-				}
-
-				_ = database.DB.Model(&existingUser).Updates(map[string]interface{}{
-					"last_login_at":     now,
-					"is_email_verified": true,
-				})
-				
-				token, _ := jwt.GenerateToken(existingUser.ID, existingUser.Email)
-				userInfo := schema.UserInfo{
-					ID:              existingUser.ID,
-					Email:           existingUser.Email,
-					FirstName:       existingUser.FirstName,
-					LastName:        existingUser.LastName,
-					DisplayName:     existingUser.DisplayName,
-					Avatar:          existingUser.Avatar,
-					IsEmailVerified: existingUser.IsEmailVerified,
-					LastLoginAt:     existingUser.LastLoginAt,
-				}
-				return response.OK(c, fiber.Map{
-					"user":  userInfo,
-					"token": token,
-				}, fiber.StatusOK)
-			}
-		}
-	}
-
-	newUser := models.User{
-		Email:           email,
-		DisplayName:     name,
-		Avatar:          picture,
-		FirstName:       FirstName,
-		LastName:        LastName,
-		LastLoginAt:     &now,
-		IsEmailVerified: true,
-	}
-	if err := database.DB.Create(&newUser).Error; err != nil {
-		return response.Fail(c, "DATABASE_ERROR", "Failed to create user", fiber.StatusInternalServerError)
-	}
-	userAuth := models.UserAuthMethod{
-		UserID:       newUser.ID,
-		AuthType:     authType,
-		AuthProvider: &authProvider,
-		ProviderID:   &googleID,
-		IsPrimary:    true,
-	}
-	if err := database.DB.Create(&userAuth).Error; err != nil {
-		return response.Fail(c, "DATABASE_ERROR", "Failed to create user auth method", fiber.StatusInternalServerError)
-	}
-	token, err := jwt.GenerateToken(newUser.ID, newUser.Email)
-	if err != nil {
-		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate token", fiber.StatusInternalServerError)
-	}
-
-	userInfo := schema.UserInfo{
-		ID:              newUser.ID,
-		Email:           newUser.Email,
-		FirstName:       newUser.FirstName,
-		LastName:        newUser.LastName,
-		DisplayName:     newUser.DisplayName,
-		Avatar:          newUser.Avatar,
-		IsEmailVerified: newUser.IsEmailVerified,
-		LastLoginAt:     newUser.LastLoginAt,
+	if err := database.DB.Model(&user).Update("last_login_at", &now).Error; err != nil {
+		return response.Fail(c, "DB_ERROR", "Failed to update last login", fiber.StatusInternalServerError)
 	}
 
-	return response.OK(c, fiber.Map{
-		"user":  userInfo,
-		"token": token,
-	}, fiber.StatusCreated)
+	return completeLogin(c, &user, fiber.StatusOK)
 }
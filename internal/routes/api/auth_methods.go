@@ -0,0 +1,352 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/oauth"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errReauthFailed   = errors.New("re-authentication failed")
+	errReauthRequired = errors.New("recent login required")
+)
+
+// reauthenticate confirms that the caller is the account owner: by
+// password, by TOTP or recovery code, or for accounts that have neither by
+// a login within config.Env.ReauthMaxAge.
+func reauthenticate(c *fiber.Ctx, db *gorm.DB, claims *jwt.Claims, req schema.ReauthRequest) error {
+	var password models.UserAuthMethod
+	err := db.Where("user_id = ? AND auth_type = ?", claims.UserID, "password").First(&password).Error
+	if err == nil {
+		if !auth.CheckPasswordHash(req.Password, password.PasswordHash) {
+			return errReauthFailed
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	totpMethod, err := loadTOTPMethod(db, claims.UserID, true)
+	if err == nil {
+		if err := verifySecondFactor(c.Context(), db, totpMethod, req.Code); err != nil {
+			if errors.Is(err, errInvalidSecondFactor) {
+				return errReauthFailed
+			}
+			return err
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if claims.SessionID == "" {
+		return errReauthRequired
+	}
+	authTime, err := session.AuthTime(db, claims.SessionID)
+	if err != nil || time.Since(authTime) > config.Env.ReauthMaxAge {
+		return errReauthRequired
+	}
+	return nil
+}
+
+func reauthFailure(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errReauthFailed):
+		return response.Fail(c, "INVALID_CREDENTIALS", "Re-authentication failed", fiber.StatusUnauthorized)
+	case errors.Is(err, errReauthRequired):
+		return response.Fail(c, "REAUTH_REQUIRED", "Please sign in again to continue", fiber.StatusUnauthorized)
+	default:
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to verify identity", fiber.StatusInternalServerError)
+	}
+}
+
+func toAuthMethodResponse(method *models.UserAuthMethod) schema.AuthMethodResponse {
+	return schema.AuthMethodResponse{
+		ID:           method.ID,
+		AuthType:     method.AuthType,
+		AuthProvider: method.AuthProvider,
+		IsPrimary:    method.IsPrimary,
+		CreatedAt:    method.CreatedAt,
+	}
+}
+
+// loginMethods returns the methods the user can sign in with. TOTP is a
+// second factor and is managed under /profile/2fa.
+func loginMethods(db *gorm.DB, userID uint) ([]models.UserAuthMethod, error) {
+	var methods []models.UserAuthMethod
+	err := db.Where("user_id = ? AND auth_type <> ?", userID, "totp").Order("created_at").Find(&methods).Error
+	return methods, err
+}
+
+// listAuthMethodsHandler lists the user's login methods
+// @Summary List Auth Methods
+// @Description List the login methods linked to the authenticated user
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/auth-methods [get]
+func listAuthMethodsHandler(c *fiber.Ctx) error {
+	user := c.Locals("user").(*jwt.Claims)
+
+	methods, err := loginMethods(database.DB, user.UserID)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load auth methods", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.AuthMethodResponse, 0, len(methods))
+	for i := range methods {
+		result = append(result, toAuthMethodResponse(&methods[i]))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// linkAuthMethodHandler starts linking an OAuth provider
+// @Summary Link Auth Method
+// @Description Re-authenticate and start the OAuth flow that links a provider account. Post the resulting code and state to /profile/auth-methods/{provider}/callback.
+// @Tags PROFILE
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "Provider name, e.g. google, github, microsoft"
+// @Param request body schema.ReauthRequest true "Re-authentication"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 502 {object} response.SWErrorResponse
+// @Router /profile/auth-methods/{provider}/link [post]
+func linkAuthMethodHandler(c *fiber.Ctx) error {
+	db := database.DB
+	user := c.Locals("user").(*jwt.Claims)
+
+	provider, err := oauth.Get(c.Params("provider"))
+	if err != nil {
+		return response.Fail(c, "UNKNOWN_PROVIDER", "Unknown OAuth provider", fiber.StatusNotFound)
+	}
+
+	var req schema.ReauthRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	if err := reauthenticate(c, db, user, req); err != nil {
+		return reauthFailure(c, err)
+	}
+
+	authURL, state, err := oauth.Begin(c.Context(), provider, user.UserID)
+	if err != nil {
+		return response.Fail(c, "PROVIDER_ERROR", "Failed to start login with provider", fiber.StatusBadGateway)
+	}
+
+	return response.OK(c, schema.OAuthAuthorizeResponse{
+		AuthorizationURL: authURL,
+		State:            state,
+	}, fiber.StatusOK)
+}
+
+// linkAuthMethodCallbackHandler finishes linking an OAuth provider
+// @Summary Link Auth Method Callback
+// @Description Complete the link flow started by /profile/auth-methods/{provider}/link
+// @Tags PROFILE
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "Provider name, e.g. google, github, microsoft"
+// @Param request body schema.OAuthCallbackRequest true "OAuth Callback Request"
+// @Success 201 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/auth-methods/{provider}/callback [post]
+func linkAuthMethodCallbackHandler(c *fiber.Ctx) error {
+	db := database.DB
+	user := c.Locals("user").(*jwt.Claims)
+
+	provider, err := oauth.Get(c.Params("provider"))
+	if err != nil {
+		return response.Fail(c, "UNKNOWN_PROVIDER", "Unknown OAuth provider", fiber.StatusNotFound)
+	}
+
+	var req schema.OAuthCallbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	if req.Error != "" || req.Code == "" {
+		return response.Fail(c, "OAUTH_DENIED", "Login was cancelled or denied by the provider", fiber.StatusBadRequest)
+	}
+
+	identity, err := oauth.Complete(c.Context(), provider, req.State, req.Code, user.UserID)
+	if err != nil {
+		return oauthFailure(c, provider.Name(), err)
+	}
+
+	var existing models.UserAuthMethod
+	err = db.Where("auth_type = ? AND auth_provider = ? AND provider_id = ?", "oauth", identity.Provider, identity.Subject).
+		First(&existing).Error
+	switch {
+	case err == nil && existing.UserID == user.UserID:
+		return response.Fail(c, "ALREADY_LINKED", "This account is already linked", fiber.StatusConflict)
+	case err == nil:
+		return response.Fail(c, "IDENTITY_IN_USE", "This account is linked to another user", fiber.StatusConflict)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load auth methods", fiber.StatusInternalServerError)
+	}
+
+	method := newOAuthMethod(user.UserID, identity, false)
+	if err := db.Create(method).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to link account", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, toAuthMethodResponse(method), fiber.StatusCreated)
+}
+
+// setPrimaryAuthMethodHandler marks a login method as primary
+// @Summary Set Primary Auth Method
+// @Description Make the given login method the primary one
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Auth method ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/auth-methods/{id}/primary [put]
+func setPrimaryAuthMethodHandler(c *fiber.Ctx) error {
+	db := database.DB
+	user := c.Locals("user").(*jwt.Claims)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+	}
+
+	var method models.UserAuthMethod
+	if err := db.Where("id = ? AND user_id = ? AND auth_type <> ?", id, user.UserID, "totp").First(&method).Error; err != nil {
+		return response.Fail(c, "NOT_FOUND", "Auth method not found", fiber.StatusNotFound)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserAuthMethod{}).
+			Where("user_id = ? AND id <> ?", user.UserID, method.ID).
+			Update("is_primary", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&method).Update("is_primary", true).Error
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to update auth method", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, toAuthMethodResponse(&method), fiber.StatusOK)
+}
+
+// unlinkAuthMethodHandler removes a login method
+// @Summary Unlink Auth Method
+// @Description Remove a login method. The last remaining login method cannot be removed.
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Auth method ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/auth-methods/{id} [delete]
+func unlinkAuthMethodHandler(c *fiber.Ctx) error {
+	db := database.DB
+	user := c.Locals("user").(*jwt.Claims)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+	}
+
+	errLastMethod := errors.New("last login method")
+	errNotFound := errors.New("auth method not found")
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Serialize unlinks per user so two requests cannot each see a
+		// second method and remove the last one between them
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, user.UserID).Error; err != nil {
+			return err
+		}
+
+		methods, err := loginMethods(tx, user.UserID)
+		if err != nil {
+			return err
+		}
+
+		var target *models.UserAuthMethod
+		for i := range methods {
+			if uint64(methods[i].ID) == id {
+				target = &methods[i]
+			}
+		}
+		if target == nil {
+			return errNotFound
+		}
+		if len(methods) <= 1 {
+			return errLastMethod
+		}
+
+		// Remove the row for good so no stale credentials are left behind
+		if err := tx.Unscoped().Delete(target).Error; err != nil {
+			return err
+		}
+		if !target.IsPrimary {
+			return nil
+		}
+		for i := range methods {
+			if methods[i].ID != target.ID {
+				return tx.Model(&methods[i]).Update("is_primary", true).Error
+			}
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errNotFound):
+		return response.Fail(c, "NOT_FOUND", "Auth method not found", fiber.StatusNotFound)
+	case errors.Is(err, errLastMethod):
+		return response.Fail(c, "LAST_AUTH_METHOD", "Cannot remove the last login method", fiber.StatusConflict)
+	case err != nil:
+		return response.Fail(c, "DATABASE_ERROR", "Failed to remove auth method", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Auth method removed",
+	}, fiber.StatusOK)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/audit"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+// impersonateUserHandler lets an administrator act as a user
+// @Summary Impersonate User
+// @Description Issue a short-lived token that acts as the user on behalf of the calling administrator. The token carries an act claim naming the administrator, cannot be refreshed or used to manage credentials, and every request made with it is audited. Administrators and service accounts cannot be impersonated.
+// @Tags ADMIN
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body schema.ImpersonateRequest true "Impersonate Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /admin/users/{id}/impersonate [post]
+func impersonateUserHandler(c *fiber.Ctx) error {
+	db := database.DB
+	claims := c.Locals("user").(*jwt.Claims)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+	}
+
+	var req schema.ImpersonateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	if uint(id) == claims.UserID {
+		return response.Fail(c, "CANNOT_IMPERSONATE", "You cannot impersonate yourself", fiber.StatusBadRequest)
+	}
+
+	var admin, user models.User
+	if err := db.First(&admin, claims.UserID).Error; err != nil {
+		return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
+	}
+	if err := db.First(&user, id).Error; err != nil {
+		return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
+	}
+	// Acting as another administrator would hand out their privileges.
+	if user.IsAdmin {
+		return response.Fail(c, "CANNOT_IMPERSONATE", "Administrators cannot be impersonated", fiber.StatusForbidden)
+	}
+	if user.IsBot {
+		return response.Fail(c, "CANNOT_IMPERSONATE", "Service accounts cannot be impersonated", fiber.StatusBadRequest)
+	}
+
+	ttl := config.Env.ImpersonationTTL
+	actor := jwt.Actor{Subject: claims.Subject, UserID: admin.ID, Email: admin.Email}
+	token, tokenID, err := jwt.GenerateImpersonationToken(user.ID, user.Email, actor, ttl)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to issue token", fiber.StatusInternalServerError)
+	}
+
+	// Without an audit entry the impersonation must not happen at all.
+	err = audit.Record(db, c, audit.Entry{
+		Action:     "impersonation.started",
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+		SubjectID:  &user.ID,
+		Metadata: map[string]any{
+			"token_id":   tokenID,
+			"reason":     req.Reason,
+			"expires_at": time.Now().Add(ttl),
+		},
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to record impersonation", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, schema.ImpersonationResponse{
+		AccessToken:    token,
+		TokenType:      "Bearer",
+		ExpiresIn:      int(ttl.Seconds()),
+		User:           toUserInfo(&user),
+		ImpersonatedBy: toUserInfo(&admin),
+	}, fiber.StatusOK)
+}
+
+// endImpersonation revokes the impersonation token used for the request
+// @Summary End Impersonation
+// @Description Revoke the impersonation token used for this request before it expires
+// @Tags AUTH
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/impersonation/end [post]
+func endImpersonation(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	if !claims.IsImpersonated() {
+		return response.Fail(c, "NOT_IMPERSONATING", "This token is not an impersonation token", fiber.StatusBadRequest)
+	}
+
+	if err := session.RevokeAccessToken(c.Context(), claims); err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to revoke token", fiber.StatusInternalServerError)
+	}
+
+	err := audit.Record(database.DB, c, audit.Entry{
+		Action:     "impersonation.ended",
+		TargetType: "user",
+		TargetID:   strconv.FormatUint(uint64(claims.UserID), 10),
+		Metadata:   map[string]any{"token_id": claims.ID},
+	})
+	if err != nil {
+		log.Printf("audit: failed to record end of impersonation of user %d: %v", claims.UserID, err)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Impersonation ended",
+	}, fiber.StatusOK)
+}
+
+// listImpersonationsHandler shows when administrators acted as the user
+// @Summary List Impersonations
+// @Description List the latest impersonations of the authenticated user and the requests made during them
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/impersonations [get]
+func listImpersonationsHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var logs []models.AuditLog
+	err := database.DB.Where("subject_id = ? AND action LIKE ?", claims.UserID, "impersonation.%").
+		Order("created_at DESC").Limit(100).Find(&logs).Error
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load impersonations", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.AuditLogResponse, 0, len(logs))
+	for _, entry := range logs {
+		result = append(result, toAuditLogResponse(&entry))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
@@ -0,0 +1,637 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/authz"
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/entitlements"
+	"github.com/phonsing-Hub/GoLang/internal/mailer"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+const purposeOrgInvite = "org_invite"
+
+var (
+	errAlreadyMember    = errors.New("already a member")
+	errInvitePending    = errors.New("invitation already pending")
+	errInvitationClosed = errors.New("invitation is no longer pending")
+	errInvalidInvite    = errors.New("invalid invitation token")
+)
+
+// memberStatusID resolves a member_statuses row by name, e.g. "invited".
+func memberStatusID(db *gorm.DB, name string) (uint, error) {
+	var status models.MemberStatus
+	if err := db.Where("name = ?", name).First(&status).Error; err != nil {
+		return 0, fmt.Errorf("member status %q: %w", name, err)
+	}
+	return status.ID, nil
+}
+
+func invitationStatus(inv *models.OrganizationInvitation) string {
+	switch {
+	case inv.AcceptedAt != nil:
+		return "accepted"
+	case inv.DeclinedAt != nil:
+		return "declined"
+	case inv.RevokedAt != nil:
+		return "revoked"
+	case !time.Now().Before(inv.ExpiresAt):
+		return "expired"
+	}
+	return "pending"
+}
+
+func toInvitationResponse(inv *models.OrganizationInvitation) schema.InvitationResponse {
+	result := schema.InvitationResponse{
+		ID:        inv.ID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		Status:    invitationStatus(inv),
+		InvitedBy: toUserInfo(&inv.Inviter),
+		SentAt:    inv.SentAt,
+		ExpiresAt: inv.ExpiresAt,
+		CreatedAt: inv.CreatedAt,
+	}
+	if inv.Organization.ID != 0 {
+		org := toOrganizationResponse(&inv.Organization)
+		result.Organization = &org
+	}
+	return result
+}
+
+// unansweredInvitations limits a query to invitations that were neither
+// accepted, declined nor revoked. Expired ones are included.
+func unansweredInvitations(db *gorm.DB) *gorm.DB {
+	return db.Where("accepted_at IS NULL AND declined_at IS NULL AND revoked_at IS NULL")
+}
+
+// sendInvitation mails a signed link naming the invitation. inv.Organization
+// and inv.Inviter must be loaded.
+func sendInvitation(inv *models.OrganizationInvitation) error {
+	token, err := jwt.GeneratePurposeToken(purposeOrgInvite, strconv.FormatUint(uint64(inv.ID), 10), inv.Email, time.Until(inv.ExpiresAt))
+	if err != nil {
+		return err
+	}
+	inviter := inv.Inviter.DisplayName
+	if inviter == "" {
+		inviter = inv.Inviter.Email
+	}
+	link := fmt.Sprintf("%s/invitations?token=%s", config.Env.AppBaseURL, url.QueryEscape(token))
+	mailer.SendAsync(mailer.OrganizationInviteMessage(inv.Email, inv.Organization.Name, inviter, inv.Role, link, config.Env.InviteTTL))
+	return nil
+}
+
+// removeInvitedMember deletes the pending membership an invitation created
+// for an existing account.
+func removeInvitedMember(tx *gorm.DB, inv *models.OrganizationInvitation) error {
+	invitedID, err := memberStatusID(tx, authz.MemberInvited)
+	if err != nil {
+		return err
+	}
+	return tx.Where("organization_id = ? AND status_id = ? AND user_id IN (?)", inv.OrganizationID, invitedID,
+		tx.Session(&gorm.Session{NewDB: true}).Model(&models.User{}).Select("id").Where("LOWER(email) = ?", inv.Email)).
+		Delete(&models.OrganizationMember{}).Error
+}
+
+// loadInvitationToken validates an invitation token and loads the pending
+// invitation it names, with its organization.
+func loadInvitationToken(token string) (*models.OrganizationInvitation, error) {
+	claims, err := jwt.ValidatePurposeToken(purposeOrgInvite, token)
+	if err != nil {
+		return nil, errInvalidInvite
+	}
+
+	// Resending voids the links sent before
+	var inv models.OrganizationInvitation
+	err = database.DB.Preload("Organization").Where("id = ?", claims.Subject).First(&inv).Error
+	if err != nil || inv.Email != claims.Data || claims.IssuedAt == nil || claims.IssuedAt.Unix() < inv.SentAt.Unix() {
+		return nil, errInvalidInvite
+	}
+	if !inv.IsPending() || inv.Organization.ID == 0 {
+		return nil, errInvitationClosed
+	}
+	return &inv, nil
+}
+
+// invitationTokenFailure answers a failed loadInvitationToken.
+func invitationTokenFailure(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errInvitationClosed) {
+		return response.Fail(c, "INVITATION_CLOSED", "Invitation is no longer pending", fiber.StatusBadRequest)
+	}
+	return response.Fail(c, "INVALID_TOKEN", "Invitation token is invalid or expired", fiber.StatusBadRequest)
+}
+
+// loadInvitation finds the :id invitation of the current organization,
+// with its organization and inviter.
+func loadInvitation(c *fiber.Ctx) (*models.OrganizationInvitation, error) {
+	org := c.Locals("organization").(*models.Organization)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return nil, errInvalidID
+	}
+
+	var inv models.OrganizationInvitation
+	if err := middleware.DB(c).Preload("Inviter").Where("id = ? AND organization_id = ?", id, org.ID).First(&inv).Error; err != nil {
+		return nil, err
+	}
+	inv.Organization = *org
+	return &inv, nil
+}
+
+// listInvitationsHandler lists the organization's open invitations
+// @Summary List Invitations
+// @Description List invitations of the organization that were not answered or revoked yet, including expired ones that can be resent. Requires the owner or admin role.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/invitations [get]
+func listInvitationsHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	var invitations []models.OrganizationInvitation
+	if err := middleware.DB(c).Preload("Inviter").Scopes(unansweredInvitations).
+		Where("organization_id = ?", org.ID).Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load invitations", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.InvitationResponse, 0, len(invitations))
+	for i := range invitations {
+		result = append(result, toInvitationResponse(&invitations[i]))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// createInvitationHandler invites someone to the organization
+// @Summary Invite Member
+// @Description Email an invitation link to an address, with or without an account. Existing users show up as invited members until they accept. Only owners can invite owners.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param request body schema.InviteMember true "Invite Member Request"
+// @Success 201 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 402 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/invitations [post]
+func createInvitationHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	org := c.Locals("organization").(*models.Organization)
+
+	var req schema.InviteMember
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	if req.Role == authz.OrgOwner && c.Locals("org_role").(string) != authz.OrgOwner {
+		return response.Fail(c, "FORBIDDEN", "Only owners can invite owners", fiber.StatusForbidden)
+	}
+
+	orgSettings, err := loadOrganizationSettings(org)
+	if err != nil {
+		return settingsFailure(c, err)
+	}
+	if !orgSettings.EmailAllowed(req.Email) {
+		return response.Fail(c, "EMAIL_DOMAIN_NOT_ALLOWED", "This organization only accepts members from its allowed email domains", fiber.StatusBadRequest)
+	}
+
+	now := time.Now()
+	inv := models.OrganizationInvitation{
+		OrganizationID: org.ID,
+		Email:          strings.ToLower(strings.TrimSpace(req.Email)),
+		Role:           req.Role,
+		InvitedBy:      claims.UserID,
+		SentAt:         now,
+		ExpiresAt:      now.Add(config.Env.InviteTTL),
+	}
+
+	err = middleware.DB(c).Transaction(func(tx *gorm.DB) error {
+		if err := entitlements.Check(c.Context(), tx, org, entitlements.Members, 1); err != nil {
+			return err
+		}
+
+		var pending int64
+		if err := tx.Model(&models.OrganizationInvitation{}).Scopes(unansweredInvitations).
+			Where("organization_id = ? AND email = ? AND expires_at > ?", org.ID, inv.Email, now).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return errInvitePending
+		}
+
+		var user models.User
+		err := tx.Where("LOWER(email) = ?", inv.Email).First(&user).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if user.ID != 0 {
+			invitedID, err := memberStatusID(tx, authz.MemberInvited)
+			if err != nil {
+				return err
+			}
+			var member models.OrganizationMember
+			err = tx.Where("organization_id = ? AND user_id = ?", org.ID, user.ID).First(&member).Error
+			switch {
+			case err == nil && member.StatusID != invitedID:
+				return errAlreadyMember
+			case err == nil:
+				// Left over from an invitation that expired
+				if err := tx.Model(&member).Updates(map[string]any{
+					"role":       inv.Role,
+					"invited_at": now,
+					"invited_by": claims.UserID,
+				}).Error; err != nil {
+					return err
+				}
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				if err := tx.Create(&models.OrganizationMember{
+					OrganizationID: org.ID,
+					UserID:         user.ID,
+					Role:           inv.Role,
+					StatusID:       invitedID,
+					InvitedAt:      &now,
+					InvitedBy:      &claims.UserID,
+				}).Error; err != nil {
+					return err
+				}
+			default:
+				return err
+			}
+		}
+
+		return tx.Create(&inv).Error
+	})
+	var quota *entitlements.QuotaError
+	if errors.As(err, &quota) {
+		return quotaFail(c, quota)
+	}
+	if errors.Is(err, errInvitePending) {
+		return response.Fail(c, "INVITATION_PENDING", "This email already has a pending invitation, resend it instead", fiber.StatusConflict)
+	}
+	if errors.Is(err, errAlreadyMember) {
+		return response.Fail(c, "ALREADY_MEMBER", "This user is already a member", fiber.StatusConflict)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to create invitation", fiber.StatusInternalServerError)
+	}
+
+	if err := middleware.DB(c).First(&inv.Inviter, claims.UserID).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load inviter", fiber.StatusInternalServerError)
+	}
+	inv.Organization = *org
+	if err := sendInvitation(&inv); err != nil {
+		log.Printf("invite: invitation %d: %v", inv.ID, err)
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to send invitation", fiber.StatusInternalServerError)
+	}
+
+	auditOrganization(c, org, "member.invited", map[string]any{
+		"invitation_id": inv.ID,
+		"email":         inv.Email,
+		"role":          inv.Role,
+	})
+
+	return response.OK(c, toInvitationResponse(&inv), fiber.StatusCreated)
+}
+
+// resendInvitationHandler sends an invitation again
+// @Summary Resend Invitation
+// @Description Email a new link for an open invitation and restart its expiry. Links sent before stop working. Limited to one email per cooldown period.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 429 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/invitations/{id}/resend [post]
+func resendInvitationHandler(c *fiber.Ctx) error {
+	inv, err := loadInvitation(c)
+	if err != nil {
+		return loadFailure(c, err, "Invitation")
+	}
+	if inv.AcceptedAt != nil || inv.DeclinedAt != nil || inv.RevokedAt != nil {
+		return response.Fail(c, "INVITATION_CLOSED", "Invitation is no longer pending", fiber.StatusBadRequest)
+	}
+
+	key := fmt.Sprintf("invite:resend:%d", inv.ID)
+	allowed, err := cache.Default.SetNX(c.Context(), key, "1", config.Env.InviteResendDelay)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to send invitation", fiber.StatusInternalServerError)
+	}
+	if !allowed {
+		return response.Fail(c, "TOO_MANY_REQUESTS", "Please wait before resending this invitation", fiber.StatusTooManyRequests)
+	}
+
+	now := time.Now()
+	if err := middleware.DB(c).Model(inv).Updates(map[string]any{
+		"sent_at":    now,
+		"expires_at": now.Add(config.Env.InviteTTL),
+	}).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to update invitation", fiber.StatusInternalServerError)
+	}
+
+	if err := sendInvitation(inv); err != nil {
+		log.Printf("invite: invitation %d: %v", inv.ID, err)
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to send invitation", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, toInvitationResponse(inv), fiber.StatusOK)
+}
+
+// revokeInvitationHandler withdraws an invitation
+// @Summary Revoke Invitation
+// @Description Withdraw an open invitation. Its link stops working and the invited member is removed.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/invitations/{id} [delete]
+func revokeInvitationHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	inv, err := loadInvitation(c)
+	if err != nil {
+		return loadFailure(c, err, "Invitation")
+	}
+
+	err = middleware.DB(c).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.OrganizationInvitation{}).Scopes(unansweredInvitations).
+			Where("id = ?", inv.ID).Update("revoked_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errInvitationClosed
+		}
+		return removeInvitedMember(tx, inv)
+	})
+	if errors.Is(err, errInvitationClosed) {
+		return response.Fail(c, "INVITATION_CLOSED", "Invitation is no longer pending", fiber.StatusBadRequest)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to revoke invitation", fiber.StatusInternalServerError)
+	}
+
+	auditOrganization(c, org, "member.invitation_revoked", map[string]any{
+		"invitation_id": inv.ID,
+		"email":         inv.Email,
+	})
+
+	return response.OK(c, fiber.Map{
+		"message": "Invitation revoked",
+	}, fiber.StatusOK)
+}
+
+// listMyInvitationsHandler lists the caller's pending invitations
+// @Summary List My Invitations
+// @Description List pending invitations sent to the caller's email address. The address must be verified.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /invitations [get]
+func listMyInvitationsHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var user models.User
+	if err := database.DB.First(&user, claims.UserID).Error; err != nil {
+		return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
+	}
+	// Anyone can register with an address they do not own
+	if !user.IsEmailVerified {
+		return response.Fail(c, "EMAIL_NOT_VERIFIED", "Please verify your email address first", fiber.StatusForbidden)
+	}
+
+	var invitations []models.OrganizationInvitation
+	if err := database.DB.Preload("Organization").Preload("Inviter").Scopes(unansweredInvitations).
+		Joins("JOIN organizations ON organizations.id = organization_invitations.organization_id AND organizations.deleted_at IS NULL").
+		Where("organization_invitations.email = ? AND organization_invitations.expires_at > ?", strings.ToLower(user.Email), time.Now()).
+		Order("organization_invitations.created_at DESC").Find(&invitations).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load invitations", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.InvitationResponse, 0, len(invitations))
+	for i := range invitations {
+		result = append(result, toInvitationResponse(&invitations[i]))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// acceptInvitationHandler joins an organization
+// @Summary Accept Invitation
+// @Description Accept an invitation with the token from the invitation email. The caller's account must use the invited email address; new users sign up with it first.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body schema.InvitationTokenRequest true "Invitation Token"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 402 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /invitations/accept [post]
+func acceptInvitationHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var req schema.InvitationTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	inv, err := loadInvitationToken(req.Token)
+	if err != nil {
+		return invitationTokenFailure(c, err)
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, claims.UserID).Error; err != nil {
+		return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
+	}
+	if !strings.EqualFold(user.Email, inv.Email) {
+		return response.Fail(c, "INVITATION_EMAIL_MISMATCH", "This invitation was sent to a different email address", fiber.StatusForbidden)
+	}
+	// The allowed domains may have changed since the invitation was sent
+	orgSettings, err := loadOrganizationSettings(&inv.Organization)
+	if err != nil {
+		return settingsFailure(c, err)
+	}
+	if !orgSettings.EmailAllowed(inv.Email) {
+		return response.Fail(c, "EMAIL_DOMAIN_NOT_ALLOWED", "This organization only accepts members from its allowed email domains", fiber.StatusBadRequest)
+	}
+	now := time.Now()
+	var member models.OrganizationMember
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// The invitation already holds a seat, but the plan may have shrunk
+		if err := entitlements.Check(c.Context(), tx, &inv.Organization, entitlements.Members, 0); err != nil {
+			return err
+		}
+
+		res := tx.Model(&models.OrganizationInvitation{}).Scopes(unansweredInvitations).
+			Where("id = ?", inv.ID).Updates(map[string]any{
+			"accepted_at": now,
+			"accepted_by": user.ID,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errInvitationClosed
+		}
+
+		activeID, err := memberStatusID(tx, authz.MemberActive)
+		if err != nil {
+			return err
+		}
+		err = tx.Where("organization_id = ? AND user_id = ?", inv.OrganizationID, user.ID).First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			member = models.OrganizationMember{
+				OrganizationID: inv.OrganizationID,
+				UserID:         user.ID,
+				Role:           inv.Role,
+				StatusID:       activeID,
+				InvitedAt:      &inv.CreatedAt,
+				InvitedBy:      &inv.InvitedBy,
+				JoinedAt:       &now,
+			}
+			return tx.Create(&member).Error
+		}
+		if err != nil || member.StatusID == activeID {
+			return err
+		}
+		// Keep the role, an admin may have changed it while invited
+		return tx.Model(&member).Updates(map[string]any{
+			"status_id": activeID,
+			"joined_at": now,
+		}).Error
+	})
+	var quota *entitlements.QuotaError
+	if errors.As(err, &quota) {
+		return quotaFail(c, quota)
+	}
+	if errors.Is(err, errInvitationClosed) {
+		return response.Fail(c, "INVITATION_CLOSED", "Invitation is no longer pending", fiber.StatusBadRequest)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to accept invitation", fiber.StatusInternalServerError)
+	}
+
+	auditOrganization(c, &inv.Organization, "member.joined", map[string]any{
+		"invitation_id": inv.ID,
+		"role":          member.Role,
+	})
+
+	return response.OK(c, fiber.Map{
+		"message":      "Invitation accepted",
+		"role":         member.Role,
+		"organization": toOrganizationResponse(&inv.Organization),
+	}, fiber.StatusOK)
+}
+
+// declineInvitationHandler turns an invitation down
+// @Summary Decline Invitation
+// @Description Decline an invitation with the token from the invitation email. No account is needed.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Param request body schema.InvitationTokenRequest true "Invitation Token"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /invitations/decline [post]
+func declineInvitationHandler(c *fiber.Ctx) error {
+	var req schema.InvitationTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	inv, err := loadInvitationToken(req.Token)
+	if err != nil {
+		return invitationTokenFailure(c, err)
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.OrganizationInvitation{}).Scopes(unansweredInvitations).
+			Where("id = ?", inv.ID).Update("declined_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errInvitationClosed
+		}
+		return removeInvitedMember(tx, inv)
+	})
+	if errors.Is(err, errInvitationClosed) {
+		return response.Fail(c, "INVITATION_CLOSED", "Invitation is no longer pending", fiber.StatusBadRequest)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to decline invitation", fiber.StatusInternalServerError)
+	}
+
+	auditOrganization(c, &inv.Organization, "member.invitation_declined", map[string]any{
+		"invitation_id": inv.ID,
+		"email":         inv.Email,
+	})
+
+	return response.OK(c, fiber.Map{
+		"message": "Invitation declined",
+	}, fiber.StatusOK)
+}
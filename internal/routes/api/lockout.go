@@ -0,0 +1,57 @@
+package api
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/lockout"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"gorm.io/gorm"
+)
+
+// loginFailed records a failed password login and answers with the same
+// response whatever the reason was. user is nil when the email is unknown.
+func loginFailed(c *fiber.Ctx, user *models.User, email string) error {
+	status, err := lockout.RecordFailure(c.Context(), email, c.IP())
+	if err != nil {
+		log.Printf("login: failed to record attempt: %v", err)
+	}
+	if user != nil && status.ShouldSuspend() {
+		if err := suspendUser(c, database.DB, user); err != nil {
+			log.Printf("login: failed to suspend user %d: %v", user.ID, err)
+		}
+	}
+	return response.Fail(c, "INVALID_CREDENTIALS", "Invalid email or password", fiber.StatusUnauthorized)
+}
+
+func tooManyAttempts(c *fiber.Ctx, wait time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return response.Fail(c, "TOO_MANY_ATTEMPTS", "Too many failed login attempts, please try again later", fiber.StatusTooManyRequests)
+}
+
+// suspendUser moves the user to the suspended status and ends all sessions.
+func suspendUser(c *fiber.Ctx, db *gorm.DB, user *models.User) error {
+	suspendedID, err := userStatusID(db, "suspended")
+	if err != nil {
+		return err
+	}
+	if err := db.Model(user).Update("status_id", suspendedID).Error; err != nil {
+		return err
+	}
+	return session.RevokeAllSessions(c.Context(), db, user.ID)
+}
+
+// isSuspended reports whether the user may not sign in at all.
+func isSuspended(db *gorm.DB, user *models.User) (bool, error) {
+	suspendedID, err := userStatusID(db, "suspended")
+	if err != nil {
+		return false, err
+	}
+	return user.StatusID == suspendedID, nil
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/authz"
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/mailer"
+	"github.com/phonsing-Hub/GoLang/internal/settings"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+const purposeMagicLink = "magic_link"
+
+// requestMagicLink emails a passwordless sign-in link
+// @Summary Request Magic Link
+// @Description Email a single-use sign-in link. Only available to members of an organization that enables magic links. The response is the same whether or not a link was sent.
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param request body schema.MagicLinkRequest true "Magic Link Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Router /auth/magic-link [post]
+func requestMagicLink(c *fiber.Ctx) error {
+	var req schema.MagicLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	// Same body for every outcome, see forgotPassword
+	accepted := fiber.Map{
+		"message": "If magic links are enabled for this account, a sign-in link has been sent",
+	}
+
+	var user models.User
+	if err := database.DB.Where("email = ? AND is_bot = ?", req.Email, false).First(&user).Error; err == nil {
+		go sendMagicLink(user)
+	}
+
+	return response.OK(c, accepted, fiber.StatusOK)
+}
+
+func sendMagicLink(user models.User) {
+	allowed, err := magicLinkAllowed(database.DB, user.ID)
+	if err != nil {
+		log.Printf("magic-link: user %d: %v", user.ID, err)
+		return
+	}
+	if !allowed {
+		return
+	}
+
+	// At most one email per resend delay, so the endpoint cannot be used to
+	// flood an inbox.
+	sendable, err := cache.Default.SetNX(context.Background(), fmt.Sprintf("magiclink:send:%d", user.ID), "1", config.Env.EmailResendDelay)
+	if err != nil || !sendable {
+		return
+	}
+
+	token, err := jwt.GeneratePurposeToken(purposeMagicLink, strconv.FormatUint(uint64(user.ID), 10), user.Email, config.Env.MagicLinkTTL)
+	if err != nil {
+		log.Printf("magic-link: failed to generate token for user %d: %v", user.ID, err)
+		return
+	}
+	link := fmt.Sprintf("%s/magic-link?token=%s", config.Env.AppBaseURL, url.QueryEscape(token))
+	mailer.SendAsync(mailer.MagicLinkMessage(user.Email, link, config.Env.MagicLinkTTL))
+}
+
+// magicLinkAllowed reports whether one of the user's organizations enables
+// magic links with "magic_link_enabled": true in its settings.
+func magicLinkAllowed(db *gorm.DB, userID uint) (bool, error) {
+	var documents []*string
+	err := db.Model(&models.Organization{}).
+		Joins("JOIN organization_members ON organization_members.organization_id = organizations.id").
+		Scopes(authz.ActiveMembers).
+		Where("organization_members.user_id = ?", userID).
+		Pluck("organizations.settings", &documents).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to load organization settings: %w", err)
+	}
+
+	for _, raw := range documents {
+		if raw == nil {
+			continue
+		}
+		if s, err := settings.ParseOrganization(*raw); err == nil && s.MagicLinkEnabled {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// verifyMagicLink signs in with a magic link
+// @Summary Verify Magic Link
+// @Description Sign in with the token from a magic link. The token can be used once and also confirms the email address. Users with 2FA get a challenge token instead of a token pair.
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param X-Device-ID header string false "Device identifier the session is bound to"
+// @Param request body schema.MagicLinkVerifyRequest true "Magic Link Verify Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/magic-link/verify [post]
+func verifyMagicLink(c *fiber.Ctx) error {
+	var req schema.MagicLinkVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	claims, err := jwt.ValidatePurposeToken(purposeMagicLink, req.Token)
+	if err != nil {
+		return response.Fail(c, "INVALID_TOKEN", "Sign-in link is invalid or expired", fiber.StatusBadRequest)
+	}
+
+	db := database.DB
+	var user models.User
+	if err := db.Where("id = ?", claims.Subject).First(&user).Error; err != nil || user.Email != claims.Data {
+		return response.Fail(c, "INVALID_TOKEN", "Sign-in link is invalid or expired", fiber.StatusBadRequest)
+	}
+
+	if org, err := ssoEnforcedFor(db, user.Email); err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to look up single sign-on", fiber.StatusInternalServerError)
+	} else if org != nil {
+		return ssoRequired(c)
+	}
+
+	// The organization may have turned magic links off since the link was sent
+	allowed, err := magicLinkAllowed(db, user.ID)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load organization settings", fiber.StatusInternalServerError)
+	}
+	if !allowed {
+		return response.Fail(c, "MAGIC_LINK_DISABLED", "Magic links are not enabled for this account", fiber.StatusForbidden)
+	}
+
+	// Checked again by completeLogin, but a suspended user must not get the
+	// email verified or the method recorded either
+	suspended, err := isSuspended(db, &user)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to resolve user status", fiber.StatusInternalServerError)
+	}
+	if suspended {
+		return response.Fail(c, "ACCOUNT_SUSPENDED", "This account has been suspended", fiber.StatusForbidden)
+	}
+
+	first, err := cache.Default.SetNX(c.Context(), "magiclink:used:"+claims.ID, "1", time.Until(claims.ExpiresAt.Time))
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to verify sign-in link", fiber.StatusInternalServerError)
+	}
+	if !first {
+		return response.Fail(c, "INVALID_TOKEN", "Sign-in link is invalid or expired", fiber.StatusBadRequest)
+	}
+
+	if !user.IsEmailVerified {
+		if err := markEmailVerified(db, &user); err != nil {
+			return response.Fail(c, "DB_ERROR", "Failed to verify email", fiber.StatusInternalServerError)
+		}
+	}
+
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var method models.UserAuthMethod
+		err := tx.Where("user_id = ? AND auth_type = ?", user.ID, "magic_link").
+			Attrs(models.UserAuthMethod{UserID: user.ID, AuthType: "magic_link"}).
+			FirstOrCreate(&method).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&method).Update("updated_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("last_login_at", &now).Error
+	})
+	if err != nil {
+		return response.Fail(c, "DB_ERROR", "Failed to update last login", fiber.StatusInternalServerError)
+	}
+
+	return completeLogin(c, &user, fiber.StatusOK)
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/authz"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errMemberNotFound = errors.New("member not found")
+	errOwnerRequired  = errors.New("owner role required")
+	errLastOwner      = errors.New("organization would have no owner")
+)
+
+func toOrganizationMemberResponse(member *models.OrganizationMember) schema.OrganizationMemberResponse {
+	return schema.OrganizationMemberResponse{
+		ID:           member.ID,
+		Role:         member.Role,
+		Status:       member.Status.Name,
+		InvitedAt:    member.InvitedAt,
+		JoinedAt:     member.JoinedAt,
+		CreatedAt:    member.CreatedAt,
+		User:         toUserInfo(&member.User),
+		Organization: toOrganizationResponse(&member.Organization),
+	}
+}
+
+// listMembersHandler lists the organization's members
+// @Summary List Members
+// @Description List the members of the organization, including invited users who have not accepted yet
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/members [get]
+func listMembersHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	var members []models.OrganizationMember
+	if err := middleware.DB(c).Preload("User").Preload("Status").Where("organization_id = ?", org.ID).
+		Order("created_at").Find(&members).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load members", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.OrganizationMemberResponse, 0, len(members))
+	for i := range members {
+		members[i].Organization = *org
+		result = append(result, toOrganizationMemberResponse(&members[i]))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// updateMemberRoleHandler changes a member's role
+// @Summary Update Member Role
+// @Description Change the role of a member. Only owners can grant or take away the owner role, and the last owner cannot be demoted.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param userId path int true "User ID"
+// @Param request body schema.UpdateMemberRole true "Update Member Role Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/members/{userId} [patch]
+func updateMemberRoleHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+	callerRole := c.Locals("org_role").(string)
+
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+	}
+
+	var req schema.UpdateMemberRole
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	var member models.OrganizationMember
+	err = middleware.DB(c).Transaction(func(tx *gorm.DB) error {
+		// Serialize role changes per organization so two owners cannot
+		// demote each other at the same time
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Organization{}, org.ID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("organization_id = ? AND user_id = ?", org.ID, userID).First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errMemberNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if (member.Role == authz.OrgOwner || req.Role == authz.OrgOwner) && callerRole != authz.OrgOwner {
+			return errOwnerRequired
+		}
+
+		if member.Role == authz.OrgOwner && req.Role != authz.OrgOwner {
+			var owners int64
+			if err := tx.Model(&models.OrganizationMember{}).Scopes(authz.ActiveMembers).
+				Where("organization_members.organization_id = ? AND organization_members.role = ? AND organization_members.user_id <> ?", org.ID, authz.OrgOwner, userID).
+				Count(&owners).Error; err != nil {
+				return err
+			}
+			if owners == 0 {
+				return errLastOwner
+			}
+		}
+
+		return tx.Model(&member).Update("role", req.Role).Error
+	})
+	switch {
+	case errors.Is(err, errMemberNotFound):
+		return response.Fail(c, "NOT_FOUND", "Member not found", fiber.StatusNotFound)
+	case errors.Is(err, errOwnerRequired):
+		return response.Fail(c, "FORBIDDEN", "Only owners can grant or remove the owner role", fiber.StatusForbidden)
+	case errors.Is(err, errLastOwner):
+		return response.Fail(c, "LAST_OWNER", "The organization must keep at least one owner", fiber.StatusConflict)
+	case err != nil:
+		return response.Fail(c, "DATABASE_ERROR", "Failed to update member role", fiber.StatusInternalServerError)
+	}
+
+	auditOrganization(c, org, "member.role_changed", map[string]any{
+		"user_id": member.UserID,
+		"role":    req.Role,
+	})
+
+	if err := middleware.DB(c).Preload("User").Preload("Status").First(&member, member.ID).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load member", fiber.StatusInternalServerError)
+	}
+	member.Organization = *org
+	return response.OK(c, toOrganizationMemberResponse(&member), fiber.StatusOK)
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/oauth"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"gorm.io/gorm"
+)
+
+// oauthAuthorize starts an OAuth / OpenID Connect login
+// @Summary OAuth Authorize
+// @Description Start the authorization code flow with PKCE. The frontend redirects the user to authorization_url and posts the returned code and state to the callback.
+// @Tags AUTH
+// @Produce json
+// @Param provider path string true "Provider name, e.g. google, github, microsoft"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 502 {object} response.SWErrorResponse
+// @Router /auth/oauth/{provider}/authorize [get]
+func oauthAuthorize(c *fiber.Ctx) error {
+	provider, err := oauth.Get(c.Params("provider"))
+	if err != nil {
+		return response.Fail(c, "UNKNOWN_PROVIDER", "Unknown OAuth provider", fiber.StatusNotFound)
+	}
+
+	authURL, state, err := oauth.Begin(c.Context(), provider, 0)
+	if err != nil {
+		log.Printf("oauth: %s authorize: %v", provider.Name(), err)
+		return response.Fail(c, "PROVIDER_ERROR", "Failed to start login with provider", fiber.StatusBadGateway)
+	}
+
+	return response.OK(c, schema.OAuthAuthorizeResponse{
+		AuthorizationURL: authURL,
+		State:            state,
+	}, fiber.StatusOK)
+}
+
+// oauthCallback completes an OAuth / OpenID Connect login
+// @Summary OAuth Callback
+// @Description Exchange the authorization code for a session. The state must come from the authorize endpoint and can be used once.
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider name, e.g. google, github, microsoft"
+// @Param X-Device-ID header string false "Device identifier the session is bound to"
+// @Param request body schema.OAuthCallbackRequest true "OAuth Callback Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/oauth/{provider}/callback [post]
+func oauthCallback(c *fiber.Ctx) error {
+	return handleOAuthCallback(c, c.Params("provider"))
+}
+
+// googleLogin signs in with a Google ID token
+// @Summary Google Sign-In
+// @Description Sign in with an ID token (credential) obtained by Google Sign-In / One Tap
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param X-Device-ID header string false "Device identifier the session is bound to"
+// @Param request body schema.GoogleCredentialRequest true "Google Credential"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/google [post]
+func googleLogin(c *fiber.Ctx) error {
+	var req schema.GoogleCredentialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	provider, err := oauth.Get("google")
+	if err != nil {
+		return response.Fail(c, "UNKNOWN_PROVIDER", "Unknown OAuth provider", fiber.StatusNotFound)
+	}
+	verifier, ok := provider.(oauth.IDTokenVerifier)
+	if !ok {
+		return response.Fail(c, "UNKNOWN_PROVIDER", "Provider does not accept ID tokens", fiber.StatusBadRequest)
+	}
+
+	identity, err := verifier.VerifyIDToken(c.Context(), req.Credential, "")
+	if err != nil {
+		return oauthFailure(c, provider.Name(), err)
+	}
+	return signInWithIdentity(c, identity)
+}
+
+// googleCallback handles Google OAuth callback
+// @Summary Google OAuth Callback
+// @Description Same as /auth/oauth/google/callback
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param X-Device-ID header string false "Device identifier the session is bound to"
+// @Param request body schema.OAuthCallbackRequest true "OAuth Callback Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/google/callback [post]
+func googleCallback(c *fiber.Ctx) error {
+	return handleOAuthCallback(c, "google")
+}
+
+func handleOAuthCallback(c *fiber.Ctx, providerName string) error {
+	provider, err := oauth.Get(providerName)
+	if err != nil {
+		return response.Fail(c, "UNKNOWN_PROVIDER", "Unknown OAuth provider", fiber.StatusNotFound)
+	}
+
+	var req schema.OAuthCallbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	if req.Error != "" || req.Code == "" {
+		return response.Fail(c, "OAUTH_DENIED", "Login was cancelled or denied by the provider", fiber.StatusBadRequest)
+	}
+
+	identity, err := oauth.Complete(c.Context(), provider, req.State, req.Code, 0)
+	if err != nil {
+		return oauthFailure(c, provider.Name(), err)
+	}
+	return signInWithIdentity(c, identity)
+}
+
+func oauthFailure(c *fiber.Ctx, provider string, err error) error {
+	switch {
+	case errors.Is(err, oauth.ErrInvalidState):
+		return response.Fail(c, "INVALID_STATE", "Login session is invalid or expired, please try again", fiber.StatusBadRequest)
+	case errors.Is(err, oauth.ErrInvalidIDToken):
+		return response.Fail(c, "INVALID_TOKEN", "Invalid ID token", fiber.StatusUnauthorized)
+	default:
+		log.Printf("oauth: %s: %v", provider, err)
+		return response.Fail(c, "TOKEN_EXCHANGE_ERROR", "Failed to sign in with provider", fiber.StatusUnauthorized)
+	}
+}
+
+// signInWithIdentity logs in the user linked to the provider account. An
+// unknown account is linked to an existing user only when the provider
+// vouches for the email address; otherwise a new user is created.
+func signInWithIdentity(c *fiber.Ctx, identity *oauth.Identity) error {
+	db := database.DB
+	now := time.Now()
+
+	var existingAuth models.UserAuthMethod
+	err := db.Where("auth_type = ? AND auth_provider = ? AND provider_id = ?", "oauth", identity.Provider, identity.Subject).
+		First(&existingAuth).Error
+	if err == nil {
+		var user models.User
+		if err := db.Where("id = ?", existingAuth.UserID).First(&user).Error; err != nil {
+			return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
+		}
+		if org, err := ssoEnforcedFor(db, user.Email); err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to look up single sign-on", fiber.StatusInternalServerError)
+		} else if org != nil {
+			return ssoRequired(c)
+		}
+		if err := db.Model(&user).Update("last_login_at", &now).Error; err != nil {
+			return response.Fail(c, "DB_ERROR", "Failed to update last login", fiber.StatusInternalServerError)
+		}
+		return completeLogin(c, &user, fiber.StatusOK)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load auth methods", fiber.StatusInternalServerError)
+	}
+
+	if identity.Email == "" {
+		return response.Fail(c, "EMAIL_REQUIRED", "The provider did not share an email address", fiber.StatusBadRequest)
+	}
+
+	var existingUser models.User
+	if err := db.Where("email = ?", identity.Email).First(&existingUser).Error; err == nil {
+		if org, err := ssoEnforcedFor(db, existingUser.Email); err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to look up single sign-on", fiber.StatusInternalServerError)
+		} else if org != nil {
+			return ssoRequired(c)
+		}
+		if !identity.EmailVerified {
+			return response.Fail(c, "EMAIL_EXISTS", "An account with this email already exists, sign in to link this provider", fiber.StatusConflict)
+		}
+
+		if err := db.Create(newOAuthMethod(existingUser.ID, identity, false)).Error; err != nil {
+			return response.Fail(c, "DB_ERROR", "Failed to link account", fiber.StatusInternalServerError)
+		}
+		if !existingUser.IsEmailVerified {
+			if err := markEmailVerified(db, &existingUser); err != nil {
+				return response.Fail(c, "DB_ERROR", "Failed to verify email", fiber.StatusInternalServerError)
+			}
+		}
+		if err := db.Model(&existingUser).Update("last_login_at", &now).Error; err != nil {
+			return response.Fail(c, "DB_ERROR", "Failed to update last login", fiber.StatusInternalServerError)
+		}
+		return completeLogin(c, &existingUser, fiber.StatusOK)
+	}
+
+	newUser := models.User{
+		Email:           identity.Email,
+		DisplayName:     identity.Name,
+		Avatar:          identity.Picture,
+		FirstName:       identity.GivenName,
+		LastName:        identity.FamilyName,
+		LastLoginAt:     &now,
+		IsEmailVerified: identity.EmailVerified,
+	}
+	if !identity.EmailVerified {
+		pendingStatusID, err := userStatusID(db, "pending_verification")
+		if err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to resolve user status", fiber.StatusInternalServerError)
+		}
+		newUser.StatusID = pendingStatusID
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		return tx.Create(newOAuthMethod(newUser.ID, identity, true)).Error
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to create user", fiber.StatusInternalServerError)
+	}
+
+	if !newUser.IsEmailVerified {
+		if err := sendVerificationEmail(&newUser); err != nil {
+			log.Printf("oauth: failed to send verification email to user %d: %v", newUser.ID, err)
+		}
+	}
+
+	return respondWithTokenPair(c, &newUser, fiber.StatusCreated)
+}
+
+func newOAuthMethod(userID uint, identity *oauth.Identity, primary bool) *models.UserAuthMethod {
+	provider, subject := identity.Provider, identity.Subject
+	return &models.UserAuthMethod{
+		UserID:       userID,
+		AuthType:     "oauth",
+		AuthProvider: &provider,
+		ProviderID:   &subject,
+		IsPrimary:    primary,
+	}
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+var (
+	errUnknownApp           = errors.New("unknown app")
+	errUnregisteredRedirect = errors.New("redirect URI is not registered")
+)
+
+// redirectError is an error in an authorization request that is reported to
+// the app through its redirect URI.
+type redirectError struct {
+	redirectURI, state string
+	code, description  string
+}
+
+func (e *redirectError) Error() string {
+	return e.code + ": " + e.description
+}
+
+// authorizeRequest is an authorization request that passed validation.
+type authorizeRequest struct {
+	client      *models.OAuthClient
+	redirectURI string // where to send the browser, resolved from the app
+	scopes      []string
+}
+
+// oauthAuthorizeHandler validates an authorization request for the consent screen
+// @Summary OAuth2 Authorize
+// @Description Validate the authorization request an app sent the user with and describe the app and the requested scopes for the consent screen. Only the code response type with an S256 PKCE challenge is supported. Errors after the redirect URI has been checked carry a redirect_to URL that reports the error to the app.
+// @Tags AUTH
+// @Produce json
+// @Security BearerAuth
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "App client id"
+// @Param redirect_uri query string false "Registered redirect URI, optional when the app has exactly one"
+// @Param scope query string false "Space separated scopes, defaults to all scopes of the app"
+// @Param state query string false "Opaque value returned to the app"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Router /oauth/authorize [get]
+func oauthAuthorizeHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var req schema.OAuthAuthorizeRequest
+	if err := c.QueryParser(&req); err != nil {
+		return response.Fail(c, "QUERY_PARSE_ERROR", "Failed to parse query parameters", fiber.StatusBadRequest)
+	}
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+	authz, err := parseAuthorizeRequest(&req)
+	if err != nil {
+		return authorizeFailure(c, err)
+	}
+
+	var grant models.OAuthGrant
+	err = database.DB.Where("user_id = ? AND oauth_client_id = ? AND revoked_at IS NULL", claims.UserID, authz.client.ID).First(&grant).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load authorization", fiber.StatusInternalServerError)
+	}
+	granted := strings.Fields(grant.Scopes)
+
+	scopes := make([]schema.OAuthScopeInfo, 0, len(authz.scopes))
+	alreadyGranted := err == nil
+	for _, scope := range authz.scopes {
+		scopes = append(scopes, schema.OAuthScopeInfo{Name: scope, Description: middleware.ScopeDescriptions[scope]})
+		if !slices.Contains(granted, scope) {
+			alreadyGranted = false
+		}
+	}
+
+	return response.OK(c, schema.OAuthConsentResponse{
+		App:            toOAuthAppInfo(authz.client),
+		Scopes:         scopes,
+		RedirectURI:    authz.redirectURI,
+		AlreadyGranted: alreadyGranted,
+	}, fiber.StatusOK)
+}
+
+// oauthConsentHandler records the user's answer on the consent screen
+// @Summary OAuth2 Consent
+// @Description Approve or deny an authorization request. The response names the URL to send the browser to: the app's redirect URI with an authorization code, or with error=access_denied when the user declined.
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body schema.OAuthConsentRequest true "Consent Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /oauth/authorize [post]
+func oauthConsentHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var req schema.OAuthConsentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+	if err := helper.VLD.Struct(req.OAuthAuthorizeRequest); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+	authz, err := parseAuthorizeRequest(&req.OAuthAuthorizeRequest)
+	if err != nil {
+		return authorizeFailure(c, err)
+	}
+
+	if !req.Approve {
+		return response.OK(c, schema.OAuthRedirectResponse{
+			RedirectTo: authorizeRedirect(authz.redirectURI, url.Values{
+				"error": {"access_denied"},
+				"state": {req.State},
+			}),
+		}, fiber.StatusOK)
+	}
+
+	grant, err := saveOAuthGrant(database.DB, claims.UserID, authz.client.ID, authz.scopes)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to store authorization", fiber.StatusInternalServerError)
+	}
+
+	code, err := session.IssueAuthorizationCode(c.Context(), session.AuthorizationCode{
+		ClientID:      authz.client.ClientID,
+		GrantID:       grant.ID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         strings.Join(authz.scopes, " "),
+		CodeChallenge: req.CodeChallenge,
+	})
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to issue authorization code", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, schema.OAuthRedirectResponse{
+		RedirectTo: authorizeRedirect(authz.redirectURI, url.Values{
+			"code":  {code},
+			"state": {req.State},
+		}),
+	}, fiber.StatusOK)
+}
+
+// parseAuthorizeRequest checks the app and redirect URI first, as errors
+// about them must not be sent to the redirect URI, then the rest of the
+// request. Errors after that are a *redirectError.
+func parseAuthorizeRequest(req *schema.OAuthAuthorizeRequest) (*authorizeRequest, error) {
+	var client models.OAuthClient
+	if !session.IsAppClient(req.ClientID) || database.DB.Where("client_id = ?", req.ClientID).First(&client).Error != nil {
+		return nil, errUnknownApp
+	}
+
+	registered := strings.Fields(client.RedirectURIs)
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(registered) == 1 {
+		redirectURI = registered[0]
+	}
+	if !slices.Contains(registered, redirectURI) {
+		return nil, errUnregisteredRedirect
+	}
+
+	if req.ResponseType != "code" {
+		return nil, &redirectError{redirectURI, req.State, "unsupported_response_type", "Only the code response type is supported"}
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return nil, &redirectError{redirectURI, req.State, "invalid_request", "A PKCE code challenge with method S256 is required"}
+	}
+
+	allowed := strings.Fields(client.Scopes)
+	scopes := allowed
+	if req.Scope != "" {
+		scopes = nil
+		for _, scope := range strings.Fields(req.Scope) {
+			if !slices.Contains(allowed, scope) {
+				return nil, &redirectError{redirectURI, req.State, "invalid_scope", "Scope " + scope + " is not available to this app"}
+			}
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	return &authorizeRequest{client: &client, redirectURI: redirectURI, scopes: scopes}, nil
+}
+
+// authorizeFailure answers a failed parseAuthorizeRequest.
+func authorizeFailure(c *fiber.Ctx, err error) error {
+	var redirect *redirectError
+	switch {
+	case errors.As(err, &redirect):
+		return authorizeError(c, redirect.redirectURI, redirect.state, redirect.code, redirect.description)
+	case errors.Is(err, errUnknownApp):
+		return response.Fail(c, "INVALID_CLIENT", "Unknown app", fiber.StatusBadRequest)
+	case errors.Is(err, errUnregisteredRedirect):
+		return response.Fail(c, "INVALID_REDIRECT_URI", "Redirect URI is not registered for this app", fiber.StatusBadRequest)
+	default:
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to check authorization request", fiber.StatusInternalServerError)
+	}
+}
+
+// authorizeError reports an invalid authorization request. The redirect_to
+// URL passes the error on to the app as RFC 6749 section 4.1.2.1 describes.
+func authorizeError(c *fiber.Ctx, redirectURI, state, code, description string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(response.ErrorResponse{
+		Success: false,
+		Data: schema.OAuthRedirectResponse{
+			RedirectTo: authorizeRedirect(redirectURI, url.Values{
+				"error":             {code},
+				"error_description": {description},
+				"state":             {state},
+			}),
+		},
+		Error: &response.ErrorDetail{
+			Code:    strings.ToUpper(code),
+			Message: description,
+		},
+	})
+}
+
+// authorizeRedirect appends params to the redirect URI, keeping its own
+// query. An empty state is left out.
+func authorizeRedirect(redirectURI string, params url.Values) string {
+	if params.Get("state") == "" {
+		params.Del("state")
+	}
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// saveOAuthGrant records the user's consent. Scopes granted earlier are
+// kept, unless the grant had been revoked in between.
+func saveOAuthGrant(db *gorm.DB, userID, clientID uint, scopes []string) (*models.OAuthGrant, error) {
+	var grant models.OAuthGrant
+	err := db.Where("user_id = ? AND oauth_client_id = ?", userID, clientID).First(&grant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		grant = models.OAuthGrant{UserID: userID, OAuthClientID: clientID, Scopes: strings.Join(scopes, " ")}
+		return &grant, db.Create(&grant).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	merged := scopes
+	if grant.RevokedAt == nil {
+		merged = strings.Fields(grant.Scopes)
+		for _, scope := range scopes {
+			if !slices.Contains(merged, scope) {
+				merged = append(merged, scope)
+			}
+		}
+	}
+	err = db.Model(&grant).Updates(map[string]interface{}{
+		"scopes":     strings.Join(merged, " "),
+		"revoked_at": nil,
+	}).Error
+	return &grant, err
+}
@@ -0,0 +1,379 @@
+package api
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+func toOAuthClientResponse(client *models.OAuthClient) schema.OAuthClientResponse {
+	return schema.OAuthClientResponse{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		Description:  client.Description,
+		HomepageURL:  client.HomepageURL,
+		RedirectURIs: strings.Fields(client.RedirectURIs),
+		Scopes:       strings.Fields(client.Scopes),
+		Confidential: client.Confidential,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+func toOAuthAppInfo(client *models.OAuthClient) schema.OAuthAppInfo {
+	return schema.OAuthAppInfo{
+		ClientID:    client.ClientID,
+		Name:        client.Name,
+		Description: client.Description,
+		HomepageURL: client.HomepageURL,
+	}
+}
+
+// validRedirectURI accepts https URLs, http on the loopback interface for
+// native apps during development, and private-use schemes such as
+// com.example.app:/callback (RFC 8252). Fragments are never allowed.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Fragment != "" || strings.ContainsAny(raw, " \t\n") {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	default:
+		return strings.Contains(u.Scheme, ".")
+	}
+}
+
+func checkRedirectURIs(c *fiber.Ctx, uris []string) error {
+	for _, uri := range uris {
+		if !validRedirectURI(uri) {
+			return response.Fail(c, "INVALID_REDIRECT_URI", "Redirect URI must use https, a loopback http address or a private-use scheme: "+uri, fiber.StatusBadRequest)
+		}
+	}
+	return nil
+}
+
+// loadOAuthClient finds the :id app of the current user.
+func loadOAuthClient(c *fiber.Ctx) (*models.OAuthClient, error) {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return nil, errInvalidID
+	}
+
+	var client models.OAuthClient
+	if err := database.DB.Where("id = ? AND owner_id = ?", id, claims.UserID).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// listOAuthClientsHandler lists the apps registered by the user
+// @Summary List OAuth Apps
+// @Description List the third-party apps registered by the authenticated user
+// @Tags AUTH
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /oauth/clients [get]
+func listOAuthClientsHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var clients []models.OAuthClient
+	if err := database.DB.Where("owner_id = ?", claims.UserID).Order("created_at DESC").Find(&clients).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load apps", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.OAuthClientResponse, 0, len(clients))
+	for i := range clients {
+		result = append(result, toOAuthClientResponse(&clients[i]))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// createOAuthClientHandler registers a third-party app
+// @Summary Register OAuth App
+// @Description Register a third-party app that acts on behalf of users through the authorization code flow with PKCE. Confidential apps get a client secret, which is returned only once; public apps such as mobile and single page apps get none.
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body schema.CreateOAuthClientRequest true "Register App Request"
+// @Success 201 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /oauth/clients [post]
+func createOAuthClientHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var req schema.CreateOAuthClientRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+	if err := checkRedirectURIs(c, req.RedirectURIs); err != nil {
+		return err
+	}
+
+	confidential := req.Confidential == nil || *req.Confidential
+	clientID, secret, secretHash, err := session.NewAppCredentials(confidential)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate credentials", fiber.StatusInternalServerError)
+	}
+
+	client := models.OAuthClient{
+		OwnerID:      claims.UserID,
+		Name:         req.Name,
+		Description:  req.Description,
+		HomepageURL:  req.HomepageURL,
+		ClientID:     clientID,
+		SecretHash:   secretHash,
+		Confidential: confidential,
+		RedirectURIs: strings.Join(req.RedirectURIs, " "),
+		Scopes:       strings.Join(req.Scopes, " "),
+	}
+	if err := database.DB.Create(&client).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to register app", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, schema.OAuthClientCredentialsResponse{
+		OAuthClientResponse: toOAuthClientResponse(&client),
+		ClientSecret:        secret,
+	}, fiber.StatusCreated)
+}
+
+// updateOAuthClientHandler changes a registered app
+// @Summary Update OAuth App
+// @Description Change the name, description, homepage, redirect URIs or scopes of an app
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "App ID"
+// @Param request body schema.UpdateOAuthClientRequest true "Update App Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /oauth/clients/{id} [patch]
+func updateOAuthClientHandler(c *fiber.Ctx) error {
+	client, err := loadOAuthClient(c)
+	if err != nil {
+		return loadFailure(c, err, "App")
+	}
+
+	var req schema.UpdateOAuthClientRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.HomepageURL != nil {
+		updates["homepage_url"] = *req.HomepageURL
+	}
+	if req.RedirectURIs != nil {
+		if err := checkRedirectURIs(c, req.RedirectURIs); err != nil {
+			return err
+		}
+		updates["redirect_uris"] = strings.Join(req.RedirectURIs, " ")
+	}
+	if req.Scopes != nil {
+		updates["scopes"] = strings.Join(req.Scopes, " ")
+	}
+
+	if len(updates) > 0 {
+		if err := database.DB.Model(client).Updates(updates).Error; err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to update app", fiber.StatusInternalServerError)
+		}
+	}
+
+	return response.OK(c, toOAuthClientResponse(client), fiber.StatusOK)
+}
+
+// rotateOAuthClientSecretHandler replaces the secret of a confidential app
+// @Summary Rotate OAuth App Secret
+// @Description Generate a new client secret. The old secret stops working immediately; the new one is returned only once.
+// @Tags AUTH
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "App ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /oauth/clients/{id}/rotate-secret [post]
+func rotateOAuthClientSecretHandler(c *fiber.Ctx) error {
+	client, err := loadOAuthClient(c)
+	if err != nil {
+		return loadFailure(c, err, "App")
+	}
+	if !client.Confidential {
+		return response.Fail(c, "PUBLIC_CLIENT", "Public apps have no client secret", fiber.StatusBadRequest)
+	}
+
+	secret, err := session.NewAppSecret()
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate secret", fiber.StatusInternalServerError)
+	}
+	if err := database.DB.Model(client).Update("secret_hash", auth.HashToken(secret)).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to rotate secret", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, schema.OAuthClientCredentialsResponse{
+		OAuthClientResponse: toOAuthClientResponse(client),
+		ClientSecret:        secret,
+	}, fiber.StatusOK)
+}
+
+// deleteOAuthClientHandler removes a registered app
+// @Summary Delete OAuth App
+// @Description Delete an app and revoke every authorization users gave it
+// @Tags AUTH
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "App ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /oauth/clients/{id} [delete]
+func deleteOAuthClientHandler(c *fiber.Ctx) error {
+	client, err := loadOAuthClient(c)
+	if err != nil {
+		return loadFailure(c, err, "App")
+	}
+
+	var grants []models.OAuthGrant
+	if err := database.DB.Where("oauth_client_id = ? AND revoked_at IS NULL", client.ID).Find(&grants).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to delete app", fiber.StatusInternalServerError)
+	}
+	for i := range grants {
+		grants[i].Client = *client
+		if err := session.RevokeGrant(c.Context(), database.DB, &grants[i]); err != nil {
+			return response.Fail(c, "INTERNAL_ERROR", "Failed to revoke authorizations", fiber.StatusInternalServerError)
+		}
+	}
+
+	if err := database.DB.Delete(client).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to delete app", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "App deleted",
+	}, fiber.StatusOK)
+}
+
+// listAuthorizedAppsHandler lists the apps the user has granted access to
+// @Summary List Authorized Apps
+// @Description List the third-party apps the authenticated user has authorized, with the scopes granted to them
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/apps [get]
+func listAuthorizedAppsHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var grants []models.OAuthGrant
+	err := database.DB.Joins("Client").
+		Where("oauth_grants.user_id = ? AND oauth_grants.revoked_at IS NULL", claims.UserID).
+		Order("oauth_grants.created_at DESC").Find(&grants).Error
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load authorized apps", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.AuthorizedAppResponse, 0, len(grants))
+	for i := range grants {
+		result = append(result, schema.AuthorizedAppResponse{
+			ID:           grants[i].ID,
+			App:          toOAuthAppInfo(&grants[i].Client),
+			Scopes:       strings.Fields(grants[i].Scopes),
+			AuthorizedAt: grants[i].CreatedAt,
+			LastUsedAt:   grants[i].LastUsedAt,
+		})
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// revokeAuthorizedAppHandler withdraws an app's access to the account
+// @Summary Revoke Authorized App
+// @Description Revoke the access of an authorized app. Its refresh tokens stop working at once and its access tokens are rejected.
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Authorization ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/apps/{id} [delete]
+func revokeAuthorizedAppHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+	}
+
+	var grant models.OAuthGrant
+	err = database.DB.Preload("Client", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, claims.UserID).First(&grant).Error
+	if err != nil {
+		return response.Fail(c, "APP_NOT_FOUND", "Authorized app not found", fiber.StatusNotFound)
+	}
+
+	if err := session.RevokeGrant(c.Context(), database.DB, &grant); err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to revoke app", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "App access revoked",
+	}, fiber.StatusOK)
+}
@@ -0,0 +1,364 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+// SetupOAuthServerRoutes registers the OAuth2 authorization server. The
+// token, introspection and revocation endpoints answer in the RFC format
+// instead of the usual envelope so that standard OAuth2 client libraries
+// can use them; the consent and app registration endpoints are called by
+// our own web app.
+func SetupOAuthServerRoutes(router *fiber.App) {
+	oauthGroup := router.Group("/oauth")
+	oauthGroup.Post("/token", oauthTokenHandler)
+	oauthGroup.Post("/introspect", oauthIntrospectHandler)
+	oauthGroup.Post("/revoke", oauthRevokeHandler)
+
+	// Consent screen of the authorization code flow
+	consent := oauthGroup.Group("/authorize", middleware.JWTAuthMiddleware(), middleware.RequireSessionToken())
+	consent.Get("", oauthAuthorizeHandler)
+	consent.Post("", oauthConsentHandler)
+
+	// Registration of third-party apps
+	clients := oauthGroup.Group("/clients", middleware.JWTAuthMiddleware(), middleware.RequireSessionToken())
+	clients.Get("", listOAuthClientsHandler)
+	clients.Post("", createOAuthClientHandler)
+	clients.Patch("/:id", updateOAuthClientHandler)
+	clients.Post("/:id/rotate-secret", rotateOAuthClientSecretHandler)
+	clients.Delete("/:id", deleteOAuthClientHandler)
+}
+
+func oauthError(c *fiber.Ctx, status int, code, description string) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).JSON(schema.OAuthErrorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
+
+// oauthTokenHandler issues access tokens to API clients
+// @Summary OAuth2 Token
+// @Description Issue an access token. Service accounts use grant_type=client_credentials and may narrow their scopes with scope; third-party apps redeem an authorization code with its PKCE verifier (grant_type=authorization_code) and renew with grant_type=refresh_token. Credentials are accepted with HTTP Basic auth or as form fields; public apps only send client_id.
+// @Tags AUTH
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param request formData schema.OAuthTokenRequest true "Token Request"
+// @Success 200 {object} schema.OAuthTokenResponse
+// @Failure 400 {object} schema.OAuthErrorResponse
+// @Failure 401 {object} schema.OAuthErrorResponse
+// @Router /oauth/token [post]
+func oauthTokenHandler(c *fiber.Ctx) error {
+	var req schema.OAuthTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "Failed to parse request body")
+	}
+
+	switch req.GrantType {
+	case "client_credentials":
+		return clientCredentialsGrant(c, &req)
+	case "authorization_code":
+		return authorizationCodeGrant(c, &req)
+	case "refresh_token":
+		return refreshTokenGrant(c, &req)
+	case "":
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "grant_type is required")
+	default:
+		return oauthError(c, fiber.StatusBadRequest, "unsupported_grant_type", "")
+	}
+}
+
+func clientCredentialsGrant(c *fiber.Ctx, req *schema.OAuthTokenRequest) error {
+	clientID, secret := req.ClientID, req.ClientSecret
+	if id, pass, ok := basicAuth(c); ok {
+		clientID, secret = id, pass
+	}
+	if clientID == "" || secret == "" {
+		return oauthError(c, fiber.StatusUnauthorized, "invalid_client", "Client authentication is required")
+	}
+
+	account, err := session.AuthenticateClient(c.Context(), database.DB, clientID, secret)
+	if errors.Is(err, session.ErrInvalidClient) {
+		return oauthError(c, fiber.StatusUnauthorized, "invalid_client", "")
+	}
+	if err != nil {
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "")
+	}
+
+	// The token carries the account's scopes, or the subset that was asked for
+	allowed := strings.Fields(account.Scopes)
+	scopes := allowed
+	if req.Scope != "" {
+		scopes = nil
+		for _, scope := range strings.Fields(req.Scope) {
+			if !slices.Contains(allowed, scope) {
+				return oauthError(c, fiber.StatusBadRequest, "invalid_scope", "Scope "+scope+" is not granted to this service account")
+			}
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	scope := strings.Join(scopes, " ")
+
+	token, err := jwt.GenerateClientToken(account.UserID, account.User.Email, account.ClientID, scope, config.Env.JWTAccessTTL)
+	if err != nil {
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "")
+	}
+
+	// The request is not authenticated with a JWT, so name the bot as actor
+	c.Locals("user", &jwt.Claims{UserID: account.UserID, ClientID: account.ClientID})
+	auditServiceAccount(c, account, "service_account.token_issued", map[string]any{"scope": scope})
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(schema.OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(config.Env.JWTAccessTTL.Seconds()),
+		Scope:       scope,
+	})
+}
+
+func authorizationCodeGrant(c *fiber.Ctx, req *schema.OAuthTokenRequest) error {
+	client, err := authenticateApp(c, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return clientFailure(c, err)
+	}
+	if req.Code == "" || req.CodeVerifier == "" {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "code and code_verifier are required")
+	}
+
+	code, err := session.RedeemAuthorizationCode(c.Context(), req.Code, client.ClientID, req.RedirectURI, req.CodeVerifier)
+	if errors.Is(err, session.ErrInvalidGrant) {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_grant", "")
+	}
+	if err != nil {
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "")
+	}
+
+	var grant models.OAuthGrant
+	if err := database.DB.Preload("User").Preload("Client").First(&grant, code.GrantID).Error; err != nil || grant.RevokedAt != nil {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_grant", "")
+	}
+
+	pair, err := session.IssueAppTokens(database.DB, &grant, code.Scope)
+	if errors.Is(err, session.ErrUserDisabled) {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_grant", "")
+	}
+	if err != nil {
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "")
+	}
+	return oauthTokenResponse(c, pair, code.Scope)
+}
+
+func refreshTokenGrant(c *fiber.Ctx, req *schema.OAuthTokenRequest) error {
+	client, err := authenticateApp(c, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return clientFailure(c, err)
+	}
+	if req.RefreshToken == "" {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "refresh_token is required")
+	}
+
+	pair, previous, err := session.RotateAppToken(c.Context(), database.DB, client, req.RefreshToken)
+	if errors.Is(err, session.ErrInvalidGrant) || errors.Is(err, session.ErrUserDisabled) {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_grant", "")
+	}
+	if err != nil {
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "")
+	}
+	return oauthTokenResponse(c, pair, previous.Scopes)
+}
+
+func oauthTokenResponse(c *fiber.Ctx, pair *session.TokenPair, scope string) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(schema.OAuthTokenResponse{
+		AccessToken:  pair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    pair.ExpiresIn,
+		RefreshToken: pair.RefreshToken,
+		Scope:        scope,
+	})
+}
+
+var errClientAuthRequired = errors.New("client authentication required")
+
+// authenticateApp identifies the third-party app making a request to the
+// token, introspection or revocation endpoint.
+func authenticateApp(c *fiber.Ctx, clientID, secret string) (*models.OAuthClient, error) {
+	if id, pass, ok := basicAuth(c); ok {
+		clientID, secret = id, pass
+	}
+	if !session.IsAppClient(clientID) {
+		return nil, errClientAuthRequired
+	}
+	return session.AuthenticateApp(database.DB, clientID, secret)
+}
+
+// clientFailure answers a failed authenticateApp.
+func clientFailure(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errClientAuthRequired):
+		return oauthError(c, fiber.StatusUnauthorized, "invalid_client", "Client authentication is required")
+	case errors.Is(err, session.ErrInvalidClient):
+		return oauthError(c, fiber.StatusUnauthorized, "invalid_client", "")
+	default:
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "")
+	}
+}
+
+// oauthIntrospectHandler reports whether a token is active
+// @Summary OAuth2 Token Introspection
+// @Description Report whether an access or refresh token issued to the calling app is active (RFC 7662). Only confidential apps may introspect, and tokens of other apps are reported as inactive.
+// @Tags AUTH
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param request formData schema.OAuthTokenActionRequest true "Introspection Request"
+// @Success 200 {object} schema.OAuthIntrospectionResponse
+// @Failure 400 {object} schema.OAuthErrorResponse
+// @Failure 401 {object} schema.OAuthErrorResponse
+// @Router /oauth/introspect [post]
+func oauthIntrospectHandler(c *fiber.Ctx) error {
+	var req schema.OAuthTokenActionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "Failed to parse request body")
+	}
+
+	client, err := authenticateApp(c, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return clientFailure(c, err)
+	}
+	if !client.Confidential {
+		return oauthError(c, fiber.StatusUnauthorized, "invalid_client", "Public apps cannot introspect tokens")
+	}
+	if req.Token == "" {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "token is required")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	inactive := schema.OAuthIntrospectionResponse{Active: false}
+
+	if strings.HasPrefix(req.Token, session.AppRefreshTokenPrefix) {
+		record, err := session.FindAppRefreshToken(database.DB, req.Token)
+		if errors.Is(err, session.ErrInvalidGrant) || (err == nil && record.Grant.OAuthClientID != client.ID) {
+			return c.JSON(inactive)
+		}
+		if err != nil {
+			return oauthError(c, fiber.StatusInternalServerError, "server_error", "")
+		}
+		return c.JSON(schema.OAuthIntrospectionResponse{
+			Active:    true,
+			Scope:     record.Scopes,
+			ClientID:  client.ClientID,
+			Username:  record.Grant.User.Email,
+			TokenType: "refresh_token",
+			Exp:       record.ExpiresAt.Unix(),
+			Iat:       record.CreatedAt.Unix(),
+			Sub:       strconv.FormatUint(uint64(record.Grant.UserID), 10),
+			Iss:       config.Env.JWTIssuer,
+		})
+	}
+
+	claims, err := jwt.ValidateToken(req.Token)
+	if err != nil || claims.ClientID != client.ClientID {
+		return c.JSON(inactive)
+	}
+	revoked, err := session.IsRevoked(c.Context(), claims)
+	if err != nil {
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "")
+	}
+	if revoked {
+		return c.JSON(inactive)
+	}
+	return c.JSON(schema.OAuthIntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Username:  claims.Email,
+		TokenType: "Bearer",
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Sub:       claims.Subject,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	})
+}
+
+// oauthRevokeHandler revokes a token issued to the calling app
+// @Summary OAuth2 Token Revocation
+// @Description Revoke an access or refresh token issued to the calling app (RFC 7009). The response is the same for unknown tokens.
+// @Tags AUTH
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param request formData schema.OAuthTokenActionRequest true "Revocation Request"
+// @Success 200
+// @Failure 400 {object} schema.OAuthErrorResponse
+// @Failure 401 {object} schema.OAuthErrorResponse
+// @Router /oauth/revoke [post]
+func oauthRevokeHandler(c *fiber.Ctx) error {
+	var req schema.OAuthTokenActionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "Failed to parse request body")
+	}
+
+	client, err := authenticateApp(c, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return clientFailure(c, err)
+	}
+	if req.Token == "" {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "token is required")
+	}
+
+	if strings.HasPrefix(req.Token, session.AppRefreshTokenPrefix) {
+		if err := session.RevokeAppRefreshToken(database.DB, client, req.Token); err != nil {
+			return oauthError(c, fiber.StatusInternalServerError, "server_error", "")
+		}
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	claims, err := jwt.ValidateToken(req.Token)
+	if err == nil && claims.ClientID == client.ClientID {
+		if err := session.RevokeAccessToken(c.Context(), claims); err != nil {
+			return oauthError(c, fiber.StatusInternalServerError, "server_error", "")
+		}
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// basicAuth reads client credentials from an HTTP Basic Authorization header
+func basicAuth(c *fiber.Ctx) (string, string, bool) {
+	header := c.Get(fiber.HeaderAuthorization)
+	if !strings.HasPrefix(header, "Basic ") {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+	if err != nil {
+		return "", "", false
+	}
+	id, secret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", false
+	}
+	// RFC 6749 section 2.3.1: both parts are form-urlencoded
+	if id, err = url.QueryUnescape(id); err != nil {
+		return "", "", false
+	}
+	if secret, err = url.QueryUnescape(secret); err != nil {
+		return "", "", false
+	}
+	return id, secret, true
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/settings"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+// loadOrganizationSettings reads the settings of org, migrating older
+// documents.
+func loadOrganizationSettings(org *models.Organization) (*settings.Organization, error) {
+	s, err := settings.ParseOrganization(org.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("organization %d: %w", org.ID, err)
+	}
+	return &s, nil
+}
+
+// settingsFailure answers a failed loadOrganizationSettings.
+func settingsFailure(c *fiber.Ctx, err error) error {
+	log.Printf("settings: %v", err)
+	return response.Fail(c, "INTERNAL_ERROR", "Failed to read organization settings", fiber.StatusInternalServerError)
+}
+
+// projectSettings returns the effective settings and the overrides of a
+// project. Personal projects inherit the defaults.
+func projectSettings(db *gorm.DB, project *models.Project) (schema.ProjectSettingsResponse, error) {
+	org := settings.Defaults()
+	if project.OrganizationID != nil {
+		var raw *string
+		if err := db.Model(&models.Organization{}).Where("id = ?", *project.OrganizationID).
+			Pluck("settings", &raw).Error; err != nil {
+			return schema.ProjectSettingsResponse{}, err
+		}
+		if raw != nil {
+			var err error
+			if org, err = settings.ParseOrganization(*raw); err != nil {
+				return schema.ProjectSettingsResponse{}, err
+			}
+		}
+	}
+
+	overrides, err := settings.ParseProject(project.Settings)
+	if err != nil {
+		return schema.ProjectSettingsResponse{}, err
+	}
+	return schema.ProjectSettingsResponse{
+		Effective: settings.Effective(org, overrides),
+		Overrides: overrides,
+	}, nil
+}
+
+// getOrganizationSettingsHandler returns the organization's settings
+// @Summary Get Organization Settings
+// @Description Get the settings of the organization. Settings it never changed have their default value.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/settings [get]
+func getOrganizationSettingsHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	current, err := loadOrganizationSettings(org)
+	if err != nil {
+		return settingsFailure(c, err)
+	}
+	return response.OK(c, current, fiber.StatusOK)
+}
+
+// updateOrganizationSettingsHandler changes the organization's settings
+// @Summary Update Organization Settings
+// @Description Change settings with a JSON merge patch: only the keys sent are changed and null restores a default. Unknown keys are rejected. Requiring 2FA needs 2FA on the caller's own account first.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param request body object true "Settings to change"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/settings [patch]
+func updateOrganizationSettingsHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	org := c.Locals("organization").(*models.Organization)
+
+	current, err := loadOrganizationSettings(org)
+	if err != nil {
+		return settingsFailure(c, err)
+	}
+
+	updated, err := settings.PatchOrganization(*current, c.Body())
+	if err != nil {
+		return response.Fail(c, "INVALID_SETTINGS", err.Error(), fiber.StatusBadRequest)
+	}
+	if err := helper.VLD.Struct(updated); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+	if err := updated.Check(); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	// Do not let admins lock themselves out of the organization
+	if updated.Require2FA && !current.Require2FA {
+		if _, err := loadTOTPMethod(middleware.DB(c), claims.UserID, true); err != nil {
+			return response.Fail(c, "TWO_FACTOR_REQUIRED", "Enable two-factor authentication on your account before requiring it", fiber.StatusBadRequest)
+		}
+	}
+
+	raw, err := settings.Encode(updated)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to encode settings", fiber.StatusInternalServerError)
+	}
+	if err := middleware.DB(c).Model(org).Update("settings", raw).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to update settings", fiber.StatusInternalServerError)
+	}
+
+	var patch map[string]any
+	_ = json.Unmarshal(c.Body(), &patch)
+	auditOrganization(c, org, "organization.settings_updated", map[string]any{
+		"patch": patch,
+	})
+
+	return response.OK(c, updated, fiber.StatusOK)
+}
+
+// getProjectSettingsHandler returns the project's settings
+// @Summary Get Project Settings
+// @Description Get the effective settings of the project, inherited from its organization, and the overrides the project sets itself
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param projectKey path string true "Project key"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/projects/{projectKey}/settings [get]
+func getProjectSettingsHandler(c *fiber.Ctx) error {
+	project := c.Locals("project").(*models.Project)
+
+	result, err := projectSettings(middleware.DB(c), project)
+	if err != nil {
+		log.Printf("settings: project %d: %v", project.ID, err)
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to read project settings", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// updateProjectSettingsHandler changes the project's overrides
+// @Summary Update Project Settings
+// @Description Override organization settings for the project with a JSON merge patch. Setting a key to null inherits it from the organization again. Unknown keys are rejected. Requires the project.manage permission, which owners and admins always hold and permission schemes can grant to other roles.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param projectKey path string true "Project key"
+// @Param request body settings.ProjectOverrides true "Overrides to change"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/projects/{projectKey}/settings [patch]
+func updateProjectSettingsHandler(c *fiber.Ctx) error {
+	project := c.Locals("project").(*models.Project)
+
+	current, err := settings.ParseProject(project.Settings)
+	if err != nil {
+		log.Printf("settings: project %d: %v", project.ID, err)
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to read project settings", fiber.StatusInternalServerError)
+	}
+
+	updated, err := settings.PatchProject(current, c.Body())
+	if err != nil {
+		return response.Fail(c, "INVALID_SETTINGS", err.Error(), fiber.StatusBadRequest)
+	}
+	if err := helper.VLD.Struct(updated); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+	if err := updated.Check(); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	raw, err := settings.Encode(updated)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to encode settings", fiber.StatusInternalServerError)
+	}
+	if err := middleware.DB(c).Model(project).Update("settings", raw).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to update settings", fiber.StatusInternalServerError)
+	}
+
+	result, err := projectSettings(middleware.DB(c), project)
+	if err != nil {
+		log.Printf("settings: project %d: %v", project.ID, err)
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to read project settings", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
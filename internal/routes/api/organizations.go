@@ -0,0 +1,313 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/audit"
+	"github.com/phonsing-Hub/GoLang/internal/authz"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/settings"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+var errSlugTaken = errors.New("organization slug is taken")
+
+func SetupOrganizationRoutes(router *fiber.App) {
+	orgs := router.Group("/organizations")
+	orgs.Use(middleware.JWTAuthMiddleware(middleware.OrgAccess))
+
+	orgs.Get("", listOrganizationsHandler)
+	orgs.Post("", middleware.RequireSessionToken(), createOrganizationHandler)
+
+	// Invitees answer through the emailed token; declining needs no account
+	invites := router.Group("/invitations")
+	invites.Post("/decline", declineInvitationHandler)
+	invites.Get("", middleware.JWTAuthMiddleware(), listMyInvitationsHandler)
+	invites.Post("/accept", middleware.JWTAuthMiddleware(), middleware.RequireSessionToken(), acceptInvitationHandler)
+
+	orgGroup := orgs.Group("/:orgSlug")
+	orgGroup.Use(middleware.TenantScope())
+	orgGroup.Get("", middleware.RequireOrgRole(authz.OrgGuest), getOrganizationHandler)
+	orgGroup.Patch("", middleware.RequireOrgRole(authz.OrgAdmin), updateOrganizationHandler)
+	orgGroup.Delete("", middleware.RequireSessionToken(), middleware.RequireOrgRole(authz.OrgOwner), deleteOrganizationHandler)
+
+	orgGroup.Get("/members", middleware.RequireOrgRole(authz.OrgMember), listMembersHandler)
+	orgGroup.Patch("/members/:userId", middleware.RequireSessionToken(), middleware.RequireOrgRole(authz.OrgAdmin), updateMemberRoleHandler)
+
+	orgGroup.Get("/usage", middleware.RequireOrgRole(authz.OrgAdmin), organizationUsageHandler)
+	orgGroup.Get("/settings", middleware.RequireOrgRole(authz.OrgMember), getOrganizationSettingsHandler)
+	orgGroup.Patch("/settings", middleware.RequireSessionToken(), middleware.RequireOrgRole(authz.OrgAdmin), updateOrganizationSettingsHandler)
+
+	invitations := orgGroup.Group("/invitations", middleware.RequireSessionToken(), middleware.RequireOrgRole(authz.OrgAdmin))
+	invitations.Get("", listInvitationsHandler)
+	invitations.Post("", createInvitationHandler)
+	invitations.Post("/:id/resend", resendInvitationHandler)
+	invitations.Delete("/:id", revokeInvitationHandler)
+
+	serviceAccounts := orgGroup.Group("/service-accounts", middleware.RequireSessionToken(), middleware.RequireOrgRole(authz.OrgAdmin))
+	serviceAccounts.Get("", listServiceAccountsHandler)
+	serviceAccounts.Post("", createServiceAccountHandler)
+	serviceAccounts.Patch("/:id", updateServiceAccountHandler)
+	serviceAccounts.Post("/:id/rotate-secret", rotateServiceAccountSecretHandler)
+	serviceAccounts.Delete("/:id", deleteServiceAccountHandler)
+	serviceAccounts.Get("/:id/audit-logs", serviceAccountAuditLogsHandler)
+
+	ssoGroup := orgGroup.Group("/sso", middleware.RequireSessionToken(), middleware.RequireOrgRole(authz.OrgAdmin))
+	ssoGroup.Get("", getOrganizationSSOHandler)
+	ssoGroup.Put("", updateOrganizationSSOHandler)
+	ssoGroup.Delete("", deleteOrganizationSSOHandler)
+	ssoGroup.Get("/domains", listSSODomainsHandler)
+	ssoGroup.Post("/domains", addSSODomainHandler)
+	ssoGroup.Post("/domains/:id/verify", verifySSODomainHandler)
+	ssoGroup.Delete("/domains/:id", deleteSSODomainHandler)
+
+	schemes := orgGroup.Group("/permission-schemes", middleware.RequireSessionToken(), middleware.RequireOrgRole(authz.OrgAdmin))
+	schemes.Get("", listPermissionSchemesHandler)
+	schemes.Post("", createPermissionSchemeHandler)
+	schemes.Get("/:id", getPermissionSchemeHandler)
+	schemes.Put("/:id", updatePermissionSchemeHandler)
+	schemes.Delete("/:id", deletePermissionSchemeHandler)
+
+	projectGroup := orgGroup.Group("/projects/:projectKey")
+	projectGroup.Get("/permissions", middleware.RequireProjectRole(authz.ProjectViewer), projectPermissionsHandler)
+	projectGroup.Post("/members", middleware.RequireSessionToken(), middleware.RequireProjectRole(authz.ProjectAdmin), addProjectMemberHandler)
+	projectGroup.Patch("/members/:userId", middleware.RequireSessionToken(), middleware.RequireProjectRole(authz.ProjectAdmin), updateProjectMemberRoleHandler)
+	projectGroup.Put("/permission-scheme", middleware.RequireSessionToken(), middleware.RequireProjectRole(authz.ProjectAdmin), assignPermissionSchemeHandler)
+	projectGroup.Get("/settings", middleware.RequireProjectRole(authz.ProjectViewer), getProjectSettingsHandler)
+	projectGroup.Patch("/settings", middleware.RequireSessionToken(), middleware.RequireProjectRole(authz.ProjectViewer), middleware.RequirePermission(authz.PermProjectManage), updateProjectSettingsHandler)
+}
+
+func toOrganizationResponse(org *models.Organization) schema.OrganizationResponse {
+	return schema.OrganizationResponse{
+		ID:          org.ID,
+		Name:        org.Name,
+		Slug:        org.Slug,
+		Description: org.Description,
+		LogoURL:     org.LogoURL,
+		PlanType:    org.PlanType,
+		CreatedAt:   org.CreatedAt,
+		UpdatedAt:   org.UpdatedAt,
+	}
+}
+
+func auditOrganization(c *fiber.Ctx, org *models.Organization, action string, metadata map[string]any) {
+	err := audit.Record(middleware.DB(c), c, audit.Entry{
+		OrganizationID: &org.ID,
+		Action:         action,
+		TargetType:     "organization",
+		TargetID:       strconv.FormatUint(uint64(org.ID), 10),
+		Metadata:       metadata,
+	})
+	if err != nil {
+		log.Printf("audit: failed to record %s for organization %d: %v", action, org.ID, err)
+	}
+}
+
+// listOrganizationsHandler lists the caller's organizations
+// @Summary List Organizations
+// @Description List the organizations the caller belongs to
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations [get]
+func listOrganizationsHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var orgs []models.Organization
+	if err := database.DB.Scopes(authz.MemberOrganizations(claims.UserID)).
+		Order("name").Find(&orgs).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load organizations", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.OrganizationResponse, 0, len(orgs))
+	for i := range orgs {
+		result = append(result, toOrganizationResponse(&orgs[i]))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// createOrganizationHandler creates an organization
+// @Summary Create Organization
+// @Description Create an organization on the free plan with the caller as its owner. Slugs are unique and case-insensitive; the slug of a deleted organization stays taken.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body schema.CreateOrganization true "Create Organization Request"
+// @Success 201 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations [post]
+func createOrganizationHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var req schema.CreateOrganization
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	// The column is json, so new organizations start with a real document
+	defaults, err := settings.Encode(settings.Defaults())
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to encode settings", fiber.StatusInternalServerError)
+	}
+
+	org := models.Organization{
+		Name:        req.Name,
+		Slug:        strings.ToLower(req.Slug),
+		Description: req.Description,
+		LogoURL:     req.LogoURL,
+		PlanType:    "free",
+		Settings:    defaults,
+	}
+
+	now := time.Now()
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Deleted organizations keep their slug, so look past soft deletes
+		var count int64
+		if err := tx.Unscoped().Model(&models.Organization{}).Where("slug = ?", org.Slug).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errSlugTaken
+		}
+
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         claims.UserID,
+			Role:           authz.OrgOwner,
+			JoinedAt:       &now,
+		}).Error
+	})
+	if errors.Is(err, errSlugTaken) {
+		return response.Fail(c, "SLUG_TAKEN", "Organization slug is already taken", fiber.StatusConflict)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to create organization", fiber.StatusInternalServerError)
+	}
+
+	auditOrganization(c, &org, "organization.created", map[string]any{
+		"name": org.Name,
+		"slug": org.Slug,
+	})
+
+	return response.OK(c, toOrganizationResponse(&org), fiber.StatusCreated)
+}
+
+// getOrganizationHandler returns an organization
+// @Summary Get Organization
+// @Description Get an organization the caller belongs to
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug} [get]
+func getOrganizationHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+	return response.OK(c, toOrganizationResponse(org), fiber.StatusOK)
+}
+
+// updateOrganizationHandler updates an organization
+// @Summary Update Organization
+// @Description Update the name, description or logo of an organization. Omitted fields are kept; an empty description or logo_url clears it. Requires the owner or admin role. The plan is changed by billing.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param request body schema.UpdateOrganization true "Update Organization Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug} [patch]
+func updateOrganizationHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	var req schema.UpdateOrganization
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	updates := map[string]any{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.LogoURL != nil {
+		updates["logo_url"] = *req.LogoURL
+	}
+
+	if len(updates) > 0 {
+		if err := middleware.DB(c).Model(org).Updates(updates).Error; err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to update organization", fiber.StatusInternalServerError)
+		}
+		auditOrganization(c, org, "organization.updated", updates)
+	}
+
+	return response.OK(c, toOrganizationResponse(org), fiber.StatusOK)
+}
+
+// deleteOrganizationHandler deletes an organization
+// @Summary Delete Organization
+// @Description Soft-delete an organization. Requires the owner role. Its slug is not released.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug} [delete]
+func deleteOrganizationHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	if err := middleware.DB(c).Delete(org).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to delete organization", fiber.StatusInternalServerError)
+	}
+
+	auditOrganization(c, org, "organization.deleted", map[string]any{
+		"slug": org.Slug,
+	})
+
+	return response.OK(c, fiber.Map{
+		"message": "Organization deleted",
+	}, fiber.StatusOK)
+}
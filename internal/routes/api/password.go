@@ -0,0 +1,206 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/mailer"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	"gorm.io/gorm"
+)
+
+var errResetTokenInvalid = errors.New("reset token is invalid or expired")
+
+// forgotPassword sends a password reset link
+// @Summary Forgot Password
+// @Description Email a single-use password reset link. The response is the same whether or not the email is registered.
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param request body schema.ForgotPasswordRequest true "Forgot Password Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Router /auth/forgot-password [post]
+func forgotPassword(c *fiber.Ctx) error {
+	var req schema.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	// Every outcome below answers with the same body so the endpoint cannot
+	// be used to find out which emails are registered.
+	accepted := fiber.Map{
+		"message": "If the email is registered, a password reset link has been sent",
+	}
+
+	var user models.User
+	if err := database.DB.Where("email = ?", req.Email).First(&user).Error; err == nil {
+		// Issue the token off the request path so that response timing does
+		// not depend on whether the account exists either.
+		go sendPasswordReset(user, c.IP())
+	}
+
+	return response.OK(c, accepted, fiber.StatusOK)
+}
+
+func sendPasswordReset(user models.User, requestIP string) {
+	token, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("forgot-password: failed to generate token: %v", err)
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link stays usable.
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: auth.HashToken(token),
+			ExpiresAt: time.Now().Add(config.Env.PasswordResetTTL),
+			RequestIP: requestIP,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("forgot-password: failed to store token for user %d: %v", user.ID, err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", config.Env.AppBaseURL, url.QueryEscape(token))
+	mailer.SendAsync(mailer.PasswordResetMessage(user.Email, link, config.Env.PasswordResetTTL))
+}
+
+// resetPassword sets a new password using a reset token
+// @Summary Reset Password
+// @Description Set a new password with a token from the reset email. All existing sessions are revoked.
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param request body schema.ResetPasswordRequest true "Reset Password Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/reset-password [post]
+func resetPassword(c *fiber.Ctx) error {
+	var req schema.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	// The policy needs the account's email, so look the token up first; it
+	// is claimed atomically below.
+	var pending models.PasswordResetToken
+	if err := database.DB.Preload("User").Where("token_hash = ?", auth.HashToken(req.Token)).First(&pending).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.Fail(c, "INVALID_TOKEN", "Reset token is invalid or expired", fiber.StatusBadRequest)
+		}
+		return response.Fail(c, "DATABASE_ERROR", "Failed to reset password", fiber.StatusInternalServerError)
+	}
+	if err := auth.ValidatePassword(req.NewPassword, pending.User.Email); err != nil {
+		return response.Fail(c, "WEAK_PASSWORD", err.Error(), fiber.StatusBadRequest)
+	}
+
+	hashedPassword, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to hash password", fiber.StatusInternalServerError)
+	}
+
+	var resetToken models.PasswordResetToken
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ?", auth.HashToken(req.Token)).First(&resetToken).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errResetTokenInvalid
+			}
+			return err
+		}
+
+		// Claim the token; a concurrent request with the same token loses.
+		res := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", resetToken.ID, time.Now()).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errResetTokenInvalid
+		}
+
+		return setPassword(tx, resetToken.UserID, hashedPassword)
+	})
+	if errors.Is(err, errResetTokenInvalid) {
+		return response.Fail(c, "INVALID_TOKEN", "Reset token is invalid or expired", fiber.StatusBadRequest)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to reset password", fiber.StatusInternalServerError)
+	}
+
+	if err := session.RevokeAllSessions(c.Context(), database.DB, resetToken.UserID); err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to revoke sessions", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Password has been reset",
+	}, fiber.StatusOK)
+}
+
+// setPassword updates the user's password auth method, creating it for
+// accounts that so far only signed in through an OAuth provider.
+func setPassword(tx *gorm.DB, userID uint, hashedPassword string) error {
+	var method models.UserAuthMethod
+	err := tx.Where("user_id = ? AND auth_type = ?", userID, "password").First(&method).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Create(&models.UserAuthMethod{
+			UserID:       userID,
+			AuthType:     "password",
+			PasswordHash: hashedPassword,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Model(&method).Update("password_hash", hashedPassword).Error
+}
+
+// upgradePasswordHash re-hashes a verified password made with an older
+// scheme or outdated parameters. Failures only leave the old hash in place.
+func upgradePasswordHash(method *models.UserAuthMethod, password string) {
+	hashedPassword, err := auth.HashPassword(password)
+	if err == nil {
+		err = database.DB.Model(method).Update("password_hash", hashedPassword).Error
+	}
+	if err != nil {
+		log.Printf("auth: failed to upgrade password hash of user %d: %v", method.UserID, err)
+	}
+}
+
+// findAuthMethod returns the first auth method of the given type, or nil.
+func findAuthMethod(methods []models.UserAuthMethod, authType string) *models.UserAuthMethod {
+	for i := range methods {
+		if methods[i].AuthType == authType {
+			return &methods[i]
+		}
+	}
+	return nil
+}
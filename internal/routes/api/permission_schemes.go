@@ -0,0 +1,483 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/authz"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+func toPermissionSchemeResponse(scheme *models.PermissionScheme) schema.PermissionSchemeResponse {
+	roles, _ := authz.ParseScheme(scheme)
+	return schema.PermissionSchemeResponse{
+		ID:          scheme.ID,
+		Name:        scheme.Name,
+		Description: scheme.Description,
+		IsDefault:   scheme.IsDefault,
+		Roles:       roles,
+		CreatedAt:   scheme.CreatedAt,
+		UpdatedAt:   scheme.UpdatedAt,
+	}
+}
+
+var (
+	errReservedSchemeRole = errors.New("project owners and admins always hold every permission")
+	errSchemeRoleInUse    = errors.New("project members hold a role the permission scheme no longer defines")
+)
+
+// unknownPermissionError is returned for a scheme granting a permission
+// that does not exist.
+type unknownPermissionError struct {
+	permission string
+}
+
+func (e *unknownPermissionError) Error() string {
+	return "unknown permission " + e.permission
+}
+
+// validateSchemeRoles checks the roles of a scheme beyond what the schema
+// validates.
+func validateSchemeRoles(roles map[string][]string) error {
+	for role, permissions := range roles {
+		if role == authz.ProjectOwner || role == authz.ProjectAdmin {
+			return errReservedSchemeRole
+		}
+		for _, permission := range permissions {
+			if !authz.IsPermission(permission) {
+				return &unknownPermissionError{permission: permission}
+			}
+		}
+	}
+	return nil
+}
+
+// schemeFailure writes the response for an error of validateSchemeRoles or
+// checkSchemeRolesKept.
+func schemeFailure(c *fiber.Ctx, err error) error {
+	var unknown *unknownPermissionError
+	switch {
+	case errors.Is(err, errReservedSchemeRole):
+		return response.Fail(c, "VALIDATION_FAILED", "Project owners and admins always hold every permission", fiber.StatusBadRequest)
+	case errors.As(err, &unknown):
+		return response.Fail(c, "VALIDATION_FAILED", "Unknown permission "+unknown.permission, fiber.StatusBadRequest)
+	case errors.Is(err, errSchemeRoleInUse):
+		return response.Fail(c, "ROLE_IN_USE", "Project members still hold a role this change removes; give them another role first", fiber.StatusConflict)
+	default:
+		return response.Fail(c, "DATABASE_ERROR", "Failed to check project roles", fiber.StatusInternalServerError)
+	}
+}
+
+// checkSchemeRolesKept returns errSchemeRoleInUse if replacing scheme with
+// next, or deleting it when next is nil, would leave a project member with
+// a role their project's scheme no longer defines. Such members would
+// silently lose every permission.
+//
+// Projects use the scheme when they chose it, or when it is the
+// organization's default and they chose none. Those fall back to the
+// built-in scheme once it stops being the default, and switch to it when
+// it becomes the default.
+func checkSchemeRolesKept(db *gorm.DB, scheme *models.PermissionScheme, next authz.Scheme, nextDefault bool) error {
+	current, err := authz.ParseScheme(scheme)
+	if err != nil {
+		return err
+	}
+	orgDefault, err := authz.SchemeFor(db, &models.Project{OrganizationID: &scheme.OrganizationID})
+	if err != nil {
+		return err
+	}
+
+	pinned := next
+	if pinned == nil {
+		// Projects of a deleted scheme use the organization's default
+		pinned = orgDefault
+		if scheme.IsDefault {
+			pinned = authz.DefaultScheme
+		}
+	}
+	if err := checkRolesKept(db.Where("projects.permission_scheme_id = ?", scheme.ID), current, pinned); err != nil {
+		return err
+	}
+
+	unpinned := db.Where("projects.organization_id = ? AND projects.permission_scheme_id IS NULL", scheme.OrganizationID)
+	switch {
+	case nextDefault:
+		return checkRolesKept(unpinned, orgDefault, next)
+	case scheme.IsDefault:
+		return checkRolesKept(unpinned, current, authz.DefaultScheme)
+	}
+	return nil
+}
+
+// checkRolesKept returns errSchemeRoleInUse if a member of the projects
+// matched by projects holds a role that from defines and to does not.
+func checkRolesKept(projects *gorm.DB, from, to authz.Scheme) error {
+	var removed []string
+	for role := range from {
+		if !to.HasRole(role) {
+			removed = append(removed, role)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	var holders int64
+	err := projects.Model(&models.ProjectMember{}).
+		Joins("JOIN projects ON projects.id = project_members.project_id AND projects.deleted_at IS NULL").
+		Where("project_members.role IN ?", removed).
+		Count(&holders).Error
+	if err != nil {
+		return err
+	}
+	if holders > 0 {
+		return errSchemeRoleInUse
+	}
+	return nil
+}
+
+// savePermissionScheme stores a scheme. Making it the default takes that
+// flag away from the organization's other schemes.
+func savePermissionScheme(db *gorm.DB, scheme *models.PermissionScheme) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if scheme.IsDefault {
+			if err := tx.Model(&models.PermissionScheme{}).
+				Where("organization_id = ? AND id <> ? AND is_default = ?", scheme.OrganizationID, scheme.ID, true).
+				Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(scheme).Error
+	})
+}
+
+// loadPermissionScheme finds the :id scheme of the current organization.
+func loadPermissionScheme(c *fiber.Ctx) (*models.PermissionScheme, error) {
+	org := c.Locals("organization").(*models.Organization)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return nil, errInvalidID
+	}
+
+	var scheme models.PermissionScheme
+	if err := middleware.DB(c).Where("id = ? AND organization_id = ?", id, org.ID).First(&scheme).Error; err != nil {
+		return nil, err
+	}
+	return &scheme, nil
+}
+
+// listPermissionSchemesHandler lists the permission schemes of an organization
+// @Summary List Permission Schemes
+// @Description List the permission schemes of the organization. Projects without a scheme use the default one, or the built-in scheme if none is marked default.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/permission-schemes [get]
+func listPermissionSchemesHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	var schemes []models.PermissionScheme
+	if err := middleware.DB(c).Where("organization_id = ?", org.ID).Order("name").Find(&schemes).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load permission schemes", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.PermissionSchemeResponse, 0, len(schemes))
+	for i := range schemes {
+		result = append(result, toPermissionSchemeResponse(&schemes[i]))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// createPermissionSchemeHandler adds a permission scheme
+// @Summary Create Permission Scheme
+// @Description Create a named mapping of project roles to permissions. Roles may be built-in (member, viewer) or custom, e.g. qa or contractor. Owners and admins always hold every permission.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param request body schema.PermissionSchemeRequest true "Permission Scheme"
+// @Success 201 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/permission-schemes [post]
+func createPermissionSchemeHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	org := c.Locals("organization").(*models.Organization)
+
+	var req schema.PermissionSchemeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	if err := validateSchemeRoles(req.Roles); err != nil {
+		return schemeFailure(c, err)
+	}
+
+	roles, err := json.Marshal(req.Roles)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to encode roles", fiber.StatusInternalServerError)
+	}
+
+	scheme := models.PermissionScheme{
+		OrganizationID: org.ID,
+		Name:           req.Name,
+		Description:    req.Description,
+		IsDefault:      req.IsDefault,
+		Roles:          string(roles),
+		CreatedBy:      claims.UserID,
+	}
+	if err := checkSchemeRolesKept(middleware.DB(c), &models.PermissionScheme{OrganizationID: org.ID}, req.Roles, req.IsDefault); err != nil {
+		return schemeFailure(c, err)
+	}
+	if err := savePermissionScheme(middleware.DB(c), &scheme); err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to create permission scheme", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, toPermissionSchemeResponse(&scheme), fiber.StatusCreated)
+}
+
+// getPermissionSchemeHandler returns a permission scheme
+// @Summary Get Permission Scheme
+// @Description Get a permission scheme of the organization
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Permission scheme ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/permission-schemes/{id} [get]
+func getPermissionSchemeHandler(c *fiber.Ctx) error {
+	scheme, err := loadPermissionScheme(c)
+	if err != nil {
+		return loadFailure(c, err, "Permission scheme")
+	}
+	return response.OK(c, toPermissionSchemeResponse(scheme), fiber.StatusOK)
+}
+
+// updatePermissionSchemeHandler replaces a permission scheme
+// @Summary Update Permission Scheme
+// @Description Replace the name, description, default flag and roles of a permission scheme. Projects using it are affected immediately. A role that project members still hold cannot be removed.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Permission scheme ID"
+// @Param request body schema.PermissionSchemeRequest true "Permission Scheme"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/permission-schemes/{id} [put]
+func updatePermissionSchemeHandler(c *fiber.Ctx) error {
+	scheme, err := loadPermissionScheme(c)
+	if err != nil {
+		return loadFailure(c, err, "Permission scheme")
+	}
+
+	var req schema.PermissionSchemeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	if err := validateSchemeRoles(req.Roles); err != nil {
+		return schemeFailure(c, err)
+	}
+
+	roles, err := json.Marshal(req.Roles)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to encode roles", fiber.StatusInternalServerError)
+	}
+
+	if err := checkSchemeRolesKept(middleware.DB(c), scheme, req.Roles, req.IsDefault); err != nil {
+		return schemeFailure(c, err)
+	}
+
+	scheme.Name = req.Name
+	scheme.Description = req.Description
+	scheme.IsDefault = req.IsDefault
+	scheme.Roles = string(roles)
+	if err := savePermissionScheme(middleware.DB(c), scheme); err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to update permission scheme", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, toPermissionSchemeResponse(scheme), fiber.StatusOK)
+}
+
+// deletePermissionSchemeHandler removes a permission scheme
+// @Summary Delete Permission Scheme
+// @Description Delete a permission scheme. Projects using it fall back to the organization's default scheme. It cannot be deleted while project members hold a role the fallback scheme does not define.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Permission scheme ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/permission-schemes/{id} [delete]
+func deletePermissionSchemeHandler(c *fiber.Ctx) error {
+	scheme, err := loadPermissionScheme(c)
+	if err != nil {
+		return loadFailure(c, err, "Permission scheme")
+	}
+
+	if err := checkSchemeRolesKept(middleware.DB(c), scheme, nil, false); err != nil {
+		return schemeFailure(c, err)
+	}
+
+	err = middleware.DB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Project{}).Where("permission_scheme_id = ?", scheme.ID).
+			Update("permission_scheme_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(scheme).Error
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to delete permission scheme", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Permission scheme deleted",
+	}, fiber.StatusOK)
+}
+
+// assignPermissionSchemeHandler sets the permission scheme of a project
+// @Summary Assign Permission Scheme
+// @Description Use one of the organization's permission schemes for the project, or the organization's default scheme when scheme_id is null
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param projectKey path string true "Project key"
+// @Param request body schema.AssignPermissionScheme true "Assign Permission Scheme"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/projects/{projectKey}/permission-scheme [put]
+func assignPermissionSchemeHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+	project := c.Locals("project").(*models.Project)
+
+	var req schema.AssignPermissionScheme
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if req.SchemeID != nil {
+		var scheme models.PermissionScheme
+		err := middleware.DB(c).Where("id = ? AND organization_id = ?", *req.SchemeID, org.ID).First(&scheme).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.Fail(c, "VALIDATION_FAILED", "Permission scheme not found in this organization", fiber.StatusBadRequest)
+		}
+		if err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to load permission scheme", fiber.StatusInternalServerError)
+		}
+	}
+
+	if err := middleware.DB(c).Model(project).Update("permission_scheme_id", req.SchemeID).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to assign permission scheme", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message":              "Permission scheme assigned",
+		"permission_scheme_id": req.SchemeID,
+	}, fiber.StatusOK)
+}
+
+// projectPermissionsHandler reports what a user may do in a project
+// @Summary Project Permissions
+// @Description Report the effective role and permissions of the caller in the project. Project admins may ask about another user with user_id.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param projectKey path string true "Project key"
+// @Param user_id query int false "User to report on instead of the caller"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/projects/{projectKey}/permissions [get]
+func projectPermissionsHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	project := c.Locals("project").(*models.Project)
+
+	resolver := middleware.Authz(c)
+	userID := claims.UserID
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+		}
+		if uint(id) != claims.UserID {
+			if !authz.ProjectRoleAtLeast(c.Locals("project_role").(string), authz.ProjectAdmin) {
+				return response.Fail(c, "FORBIDDEN", "Project admin access required", fiber.StatusForbidden)
+			}
+			userID = uint(id)
+			resolver = authz.NewResolver(middleware.DB(c), userID)
+		}
+	}
+
+	role, err := resolver.ProjectRole(project)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load membership", fiber.StatusInternalServerError)
+	}
+	permissions, err := resolver.Permissions(project)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load permissions", fiber.StatusInternalServerError)
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	return response.OK(c, schema.ProjectPermissionsResponse{
+		ProjectKey:  project.Key,
+		UserID:      userID,
+		Role:        role,
+		Permissions: permissions,
+	}, fiber.StatusOK)
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/authz"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"gorm.io/gorm"
+)
+
+func toProjectMemberResponse(member *models.ProjectMember, project *models.Project) schema.ProjectMemberResponse {
+	var orgID uint
+	if project.OrganizationID != nil {
+		orgID = *project.OrganizationID
+	}
+	return schema.ProjectMemberResponse{
+		ID:       member.ID,
+		Role:     member.Role,
+		JoinedAt: member.JoinedAt,
+		User:     toUserInfo(&member.User),
+		Project: schema.ProjectResponse{
+			ID:             project.ID,
+			Name:           project.Name,
+			Key:            project.Key,
+			Description:    project.Description,
+			IsPrivate:      project.IsPrivate,
+			CreatedAt:      project.CreatedAt,
+			UpdatedAt:      project.UpdatedAt,
+			OrganizationID: orgID,
+			OwnerID:        project.OwnerID,
+		},
+	}
+}
+
+var errUnknownProjectRole = errors.New("unknown project role")
+
+// checkProjectRole reports whether a caller with callerRole may give role
+// in the project. It must be a role of the project's permission scheme,
+// and only project owners can make someone an owner.
+func checkProjectRole(db *gorm.DB, project *models.Project, callerRole, role string) error {
+	scheme, err := authz.SchemeFor(db, project)
+	if err != nil {
+		return err
+	}
+	if !scheme.HasRole(role) {
+		return errUnknownProjectRole
+	}
+	if role == authz.ProjectOwner && callerRole != authz.ProjectOwner {
+		return errOwnerRequired
+	}
+	return nil
+}
+
+// projectRoleFailure writes the response for an error of checkProjectRole.
+func projectRoleFailure(c *fiber.Ctx, err error, role string) error {
+	switch {
+	case errors.Is(err, errUnknownProjectRole):
+		return response.Fail(c, "VALIDATION_FAILED", "Unknown project role "+role, fiber.StatusBadRequest)
+	case errors.Is(err, errOwnerRequired):
+		return response.Fail(c, "FORBIDDEN", "Only project owners can grant or remove the owner role", fiber.StatusForbidden)
+	default:
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load permission scheme", fiber.StatusInternalServerError)
+	}
+}
+
+// addProjectMemberHandler adds a member to a project
+// @Summary Add Project Member
+// @Description Add an active member of the organization to the project. The role must be built-in (owner, admin, member, viewer) or defined by the project's permission scheme; only project owners can grant owner.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param projectKey path string true "Project key"
+// @Param request body schema.AddProjectMember true "Add Project Member Request"
+// @Success 201 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/projects/{projectKey}/members [post]
+func addProjectMemberHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+	project := c.Locals("project").(*models.Project)
+
+	var req schema.AddProjectMember
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	db := middleware.DB(c)
+	if err := checkProjectRole(db, project, c.Locals("project_role").(string), req.Role); err != nil {
+		return projectRoleFailure(c, err, req.Role)
+	}
+
+	orgRole, err := authz.NewResolver(db, req.UserID).OrgRole(org.ID)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load membership", fiber.StatusInternalServerError)
+	}
+	if orgRole == "" {
+		return response.Fail(c, "VALIDATION_FAILED", "User is not a member of the organization", fiber.StatusBadRequest)
+	}
+
+	var existing int64
+	if err := db.Model(&models.ProjectMember{}).Where("project_id = ? AND user_id = ?", project.ID, req.UserID).Count(&existing).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load membership", fiber.StatusInternalServerError)
+	}
+	if existing > 0 {
+		return response.Fail(c, "ALREADY_MEMBER", "User is already a member of the project", fiber.StatusConflict)
+	}
+
+	member := models.ProjectMember{ProjectID: project.ID, UserID: req.UserID, Role: req.Role}
+	if err := db.Create(&member).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to add project member", fiber.StatusInternalServerError)
+	}
+
+	auditOrganization(c, org, "project.member_added", map[string]any{
+		"project_id": project.ID,
+		"user_id":    member.UserID,
+		"role":       member.Role,
+	})
+
+	if err := db.Preload("User").First(&member, member.ID).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load member", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, toProjectMemberResponse(&member, project), fiber.StatusCreated)
+}
+
+// updateProjectMemberRoleHandler changes a project member's role
+// @Summary Update Project Member Role
+// @Description Change the role of a project member. The role must be built-in (owner, admin, member, viewer) or defined by the project's permission scheme; only project owners can grant or take away owner.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param projectKey path string true "Project key"
+// @Param userId path int true "User ID"
+// @Param request body schema.UpdateProjectMemberRole true "Update Project Member Role Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/projects/{projectKey}/members/{userId} [patch]
+func updateProjectMemberRoleHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+	project := c.Locals("project").(*models.Project)
+
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+	}
+
+	var req schema.UpdateProjectMemberRole
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	db := middleware.DB(c)
+	var member models.ProjectMember
+	err = db.Preload("User").Where("project_id = ? AND user_id = ?", project.ID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.Fail(c, "NOT_FOUND", "Member not found", fiber.StatusNotFound)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load member", fiber.StatusInternalServerError)
+	}
+
+	callerRole := c.Locals("project_role").(string)
+	err = checkProjectRole(db, project, callerRole, req.Role)
+	if err == nil && member.Role == authz.ProjectOwner && callerRole != authz.ProjectOwner {
+		err = errOwnerRequired
+	}
+	if err != nil {
+		return projectRoleFailure(c, err, req.Role)
+	}
+
+	if err := db.Model(&member).Update("role", req.Role).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to update member role", fiber.StatusInternalServerError)
+	}
+
+	auditOrganization(c, org, "project.member_role_changed", map[string]any{
+		"project_id": project.ID,
+		"user_id":    member.UserID,
+		"role":       req.Role,
+	})
+
+	return response.OK(c, toProjectMemberResponse(&member, project), fiber.StatusOK)
+}
@@ -0,0 +1,392 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/audit"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+// botEmailDomain is used for the bot users of service accounts. The .invalid
+// TLD guarantees that nothing is ever delivered to them.
+const botEmailDomain = "service-accounts.invalid"
+
+func toServiceAccountResponse(account *models.ServiceAccount) schema.ServiceAccountResponse {
+	return schema.ServiceAccountResponse{
+		ID:          account.ID,
+		Name:        account.Name,
+		Description: account.Description,
+		Role:        account.Role,
+		Scopes:      strings.Fields(account.Scopes),
+		ClientID:    account.ClientID,
+		LastUsedAt:  account.LastUsedAt,
+		DisabledAt:  account.DisabledAt,
+		CreatedAt:   account.CreatedAt,
+		User:        toUserInfo(&account.User),
+	}
+}
+
+func auditServiceAccount(c *fiber.Ctx, account *models.ServiceAccount, action string, metadata map[string]any) {
+	err := audit.Record(middleware.DB(c), c, audit.Entry{
+		OrganizationID: &account.OrganizationID,
+		Action:         action,
+		TargetType:     "service_account",
+		TargetID:       strconv.FormatUint(uint64(account.ID), 10),
+		Metadata:       metadata,
+	})
+	if err != nil {
+		log.Printf("audit: failed to record %s for service account %d: %v", action, account.ID, err)
+	}
+}
+
+// errInvalidID is returned by the loaders of :id resources when the
+// parameter is not a number
+var errInvalidID = errors.New("invalid id")
+
+// loadFailure answers a failed load of a :id resource, what names it in
+// the message
+func loadFailure(c *fiber.Ctx, err error, what string) error {
+	switch {
+	case errors.Is(err, errInvalidID):
+		return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return response.Fail(c, "NOT_FOUND", what+" not found", fiber.StatusNotFound)
+	default:
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load "+strings.ToLower(what), fiber.StatusInternalServerError)
+	}
+}
+
+// loadServiceAccount finds the :id service account of the current
+// organization
+func loadServiceAccount(c *fiber.Ctx) (*models.ServiceAccount, error) {
+	org := c.Locals("organization").(*models.Organization)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return nil, errInvalidID
+	}
+
+	var account models.ServiceAccount
+	if err := middleware.DB(c).Preload("User").Where("id = ? AND organization_id = ?", id, org.ID).First(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// listServiceAccountsHandler lists the organization's service accounts
+// @Summary List Service Accounts
+// @Description List the service accounts of an organization. Requires the owner or admin role.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/service-accounts [get]
+func listServiceAccountsHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	var accounts []models.ServiceAccount
+	if err := middleware.DB(c).Preload("User").Where("organization_id = ?", org.ID).
+		Order("created_at").Find(&accounts).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load service accounts", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.ServiceAccountResponse, 0, len(accounts))
+	for i := range accounts {
+		result = append(result, toServiceAccountResponse(&accounts[i]))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// createServiceAccountHandler creates a service account
+// @Summary Create Service Account
+// @Description Create a bot identity for integrations such as CI pipelines. The client secret is returned only once and is exchanged for access tokens at /oauth/token, which carry the account's scopes. Service accounts do not count as members.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param request body schema.CreateServiceAccount true "Create Service Account Request"
+// @Success 201 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/service-accounts [post]
+func createServiceAccountHandler(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	org := c.Locals("organization").(*models.Organization)
+
+	var req schema.CreateServiceAccount
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	clientID, secret, secretHash, err := session.NewClientCredentials()
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate credentials", fiber.StatusInternalServerError)
+	}
+
+	account := models.ServiceAccount{
+		OrganizationID: org.ID,
+		Name:           req.Name,
+		Description:    req.Description,
+		Role:           req.Role,
+		Scopes:         strings.Join(req.Scopes, " "),
+		ClientID:       clientID,
+		SecretHash:     secretHash,
+		CreatedBy:      claims.UserID,
+		User: models.User{
+			Email:           clientID + "@" + botEmailDomain,
+			DisplayName:     req.Name,
+			IsBot:           true,
+			IsEmailVerified: true,
+		},
+	}
+	err = middleware.DB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&account.User).Error; err != nil {
+			return err
+		}
+		account.UserID = account.User.ID
+		return tx.Omit("User").Create(&account).Error
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to create service account", fiber.StatusInternalServerError)
+	}
+
+	auditServiceAccount(c, &account, "service_account.created", map[string]any{
+		"name":   account.Name,
+		"role":   account.Role,
+		"scopes": account.Scopes,
+	})
+
+	return response.OK(c, schema.ServiceAccountCredentialsResponse{
+		ServiceAccountResponse: toServiceAccountResponse(&account),
+		ClientSecret:           secret,
+	}, fiber.StatusCreated)
+}
+
+// updateServiceAccountHandler updates a service account
+// @Summary Update Service Account
+// @Description Rename a service account, change its role or scopes, or disable and re-enable it
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Service account ID"
+// @Param request body schema.UpdateServiceAccount true "Update Service Account Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/service-accounts/{id} [patch]
+func updateServiceAccountHandler(c *fiber.Ctx) error {
+	account, err := loadServiceAccount(c)
+	if err != nil {
+		return loadFailure(c, err, "Service account")
+	}
+
+	var req schema.UpdateServiceAccount
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	updates := map[string]any{}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+	if req.Role != "" {
+		updates["role"] = req.Role
+	}
+	if req.Scopes != nil {
+		updates["scopes"] = strings.Join(req.Scopes, " ")
+	}
+	if req.Disabled != nil {
+		if *req.Disabled && account.DisabledAt == nil {
+			updates["disabled_at"] = time.Now()
+		} else if !*req.Disabled && account.DisabledAt != nil {
+			updates["disabled_at"] = nil
+		}
+	}
+	if len(updates) == 0 {
+		return response.OK(c, toServiceAccountResponse(account), fiber.StatusOK)
+	}
+
+	err = middleware.DB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Updates(updates).Error; err != nil {
+			return err
+		}
+		if name, ok := updates["name"]; ok {
+			return tx.Model(&account.User).Update("display_name", name).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to update service account", fiber.StatusInternalServerError)
+	}
+
+	auditServiceAccount(c, account, "service_account.updated", updates)
+
+	return response.OK(c, toServiceAccountResponse(account), fiber.StatusOK)
+}
+
+// rotateServiceAccountSecretHandler replaces a service account's secret
+// @Summary Rotate Service Account Secret
+// @Description Issue a new client secret. The old secret stops working immediately; tokens already issued stay valid until they expire.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Service account ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/service-accounts/{id}/rotate-secret [post]
+func rotateServiceAccountSecretHandler(c *fiber.Ctx) error {
+	account, err := loadServiceAccount(c)
+	if err != nil {
+		return loadFailure(c, err, "Service account")
+	}
+
+	secret, err := session.NewClientSecret()
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate credentials", fiber.StatusInternalServerError)
+	}
+	if err := middleware.DB(c).Model(account).Update("secret_hash", auth.HashToken(secret)).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to rotate secret", fiber.StatusInternalServerError)
+	}
+
+	auditServiceAccount(c, account, "service_account.secret_rotated", nil)
+
+	return response.OK(c, schema.ServiceAccountCredentialsResponse{
+		ServiceAccountResponse: toServiceAccountResponse(account),
+		ClientSecret:           secret,
+	}, fiber.StatusOK)
+}
+
+// deleteServiceAccountHandler deletes a service account
+// @Summary Delete Service Account
+// @Description Delete a service account. Its bot user is kept so tickets and comments stay attributed.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Service account ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/service-accounts/{id} [delete]
+func deleteServiceAccountHandler(c *fiber.Ctx) error {
+	account, err := loadServiceAccount(c)
+	if err != nil {
+		return loadFailure(c, err, "Service account")
+	}
+
+	err = middleware.DB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Update("disabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Delete(account).Error
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to delete service account", fiber.StatusInternalServerError)
+	}
+
+	auditServiceAccount(c, account, "service_account.deleted", nil)
+
+	return response.OK(c, fiber.Map{
+		"message": "Service account deleted",
+	}, fiber.StatusOK)
+}
+
+// serviceAccountAuditLogsHandler lists the audit trail of a service account
+// @Summary Service Account Audit Log
+// @Description List the latest changes to a service account and the actions it performed
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Service account ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/service-accounts/{id}/audit-logs [get]
+func serviceAccountAuditLogsHandler(c *fiber.Ctx) error {
+	account, err := loadServiceAccount(c)
+	if err != nil {
+		return loadFailure(c, err, "Service account")
+	}
+
+	var logs []models.AuditLog
+	err = middleware.DB(c).Where("organization_id = ?", account.OrganizationID).
+		Where("(target_type = ? AND target_id = ?) OR actor_id = ?", "service_account", strconv.FormatUint(uint64(account.ID), 10), account.UserID).
+		Order("created_at DESC").Limit(100).Find(&logs).Error
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load audit log", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.AuditLogResponse, 0, len(logs))
+	for _, entry := range logs {
+		result = append(result, toAuditLogResponse(&entry))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+func toAuditLogResponse(entry *models.AuditLog) schema.AuditLogResponse {
+	result := schema.AuditLogResponse{
+		ID:         entry.ID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		ActorID:    entry.ActorID,
+		SubjectID:  entry.SubjectID,
+		IPAddress:  entry.IPAddress,
+		CreatedAt:  entry.CreatedAt,
+	}
+	if entry.Metadata != "" {
+		result.Metadata = json.RawMessage(entry.Metadata)
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+// listSessionsHandler lists where the user is logged in
+// @Summary List Sessions
+// @Description List the active sessions of the authenticated user with device, IP and last activity
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/sessions [get]
+func listSessionsHandler(c *fiber.Ctx) error {
+	user := c.Locals("user").(*jwt.Claims)
+
+	sessions, err := session.Active(database.DB, user.UserID)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load sessions", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, schema.SessionResponse{
+			ID:         s.ID,
+			DeviceID:   s.DeviceID,
+			UserAgent:  s.UserAgent,
+			IPAddress:  s.IPAddress,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    s.ID == user.SessionID,
+		})
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// revokeSessionHandler logs out one session
+// @Summary Revoke Session
+// @Description End one of the authenticated user's sessions, e.g. a lost device
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/sessions/{id} [delete]
+func revokeSessionHandler(c *fiber.Ctx) error {
+	db := database.DB
+	user := c.Locals("user").(*jwt.Claims)
+
+	var record models.UserSession
+	if err := db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Params("id"), user.UserID).First(&record).Error; err != nil {
+		return response.Fail(c, "NOT_FOUND", "Session not found", fiber.StatusNotFound)
+	}
+
+	if err := session.RevokeSession(c.Context(), db, record.ID); err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to revoke session", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Session revoked",
+	}, fiber.StatusOK)
+}
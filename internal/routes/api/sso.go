@@ -0,0 +1,806 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/authz"
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/entitlements"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/sso"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	"gorm.io/gorm"
+)
+
+// ssoCodeTTL is how long the frontend has to redeem the code the ACS
+// endpoint redirects with.
+const ssoCodeTTL = time.Minute
+
+var (
+	errSSONotConfigured     = errors.New("single sign-on is not configured")
+	errSSOEmailConflict     = errors.New("email belongs to an account that is not an active member of the organization")
+	errSSODomainNotVerified = errors.New("email domain is not verified by the organization")
+)
+
+// loadSSO loads an enterprise organization and its enabled SSO configuration
+func loadSSO(db *gorm.DB, orgSlug string) (*models.Organization, *models.OrganizationSSO, error) {
+	var org models.Organization
+	if err := db.Where("slug = ?", orgSlug).First(&org).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errSSONotConfigured
+		}
+		return nil, nil, err
+	}
+	if org.PlanType != "enterprise" {
+		return nil, nil, errSSONotConfigured
+	}
+
+	var cfg models.OrganizationSSO
+	if err := db.Where("organization_id = ? AND enabled = ?", org.ID, true).First(&cfg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errSSONotConfigured
+		}
+		return nil, nil, err
+	}
+	return &org, &cfg, nil
+}
+
+func ssoMapping(cfg *models.OrganizationSSO) sso.AttributeMapping {
+	var mapping sso.AttributeMapping
+	if cfg.AttributeMapping != "" {
+		if err := json.Unmarshal([]byte(cfg.AttributeMapping), &mapping); err != nil {
+			log.Printf("sso: organization %d has an invalid attribute mapping: %v", cfg.OrganizationID, err)
+		}
+	}
+	return mapping
+}
+
+// verifiedBy selects the organizations that verified domain
+func verifiedBy(db *gorm.DB, domain string) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&models.OrganizationDomain{}).
+		Select("organization_id").Where("domain = ? AND verified_at IS NOT NULL", domain)
+}
+
+// verifiedDomains lists the domains an organization has verified
+func verifiedDomains(db *gorm.DB, orgID uint) ([]string, error) {
+	domains := []string{}
+	err := db.Model(&models.OrganizationDomain{}).
+		Where("organization_id = ? AND verified_at IS NOT NULL", orgID).
+		Order("domain").Pluck("domain", &domains).Error
+	return domains, err
+}
+
+// ssoEnforcedFor returns the organization that requires single sign-on of
+// the user with this email, or nil. Only active members of an organization
+// that verified the email's domain are held to it.
+func ssoEnforcedFor(db *gorm.DB, email string) (*models.Organization, error) {
+	domain := sso.EmailDomain(email)
+	if domain == "" {
+		return nil, nil
+	}
+
+	memberOf := db.Session(&gorm.Session{NewDB: true}).Model(&models.OrganizationMember{}).
+		Scopes(authz.ActiveMembers).
+		Joins("JOIN users ON users.id = organization_members.user_id").
+		Select("organization_members.organization_id").
+		Where("users.email = ?", email)
+
+	var configs []models.OrganizationSSO
+	err := db.Preload("Organization").
+		Where("enabled = ? AND force_sso = ?", true, true).
+		Where("organization_id IN (?) AND organization_id IN (?)", verifiedBy(db, domain), memberOf).
+		Find(&configs).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range configs {
+		cfg := &configs[i]
+		if cfg.Organization.ID != 0 && cfg.Organization.PlanType == "enterprise" {
+			return &cfg.Organization, nil
+		}
+	}
+	return nil, nil
+}
+
+// ssoRequired answers a login that has to go through the organization's
+// identity provider. The login page finds the organization with
+// /auth/sso/discover.
+func ssoRequired(c *fiber.Ctx) error {
+	return response.Fail(c, "SSO_REQUIRED", "Sign in with your organization's single sign-on", fiber.StatusForbidden)
+}
+
+// ssoDiscover tells the login page whether an email uses single sign-on
+// @Summary Discover SSO
+// @Description Look up whether the email's domain is verified by an organization with SAML single sign-on, and whether the organization disables other logins for its members
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param request body schema.SSODiscoverRequest true "SSO Discover Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/sso/discover [post]
+func ssoDiscover(c *fiber.Ctx) error {
+	var req schema.SSODiscoverRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	db := database.DB
+	var configs []models.OrganizationSSO
+	if err := db.Preload("Organization").
+		Where("enabled = ? AND organization_id IN (?)", true, verifiedBy(db, sso.EmailDomain(req.Email))).
+		Find(&configs).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to look up single sign-on", fiber.StatusInternalServerError)
+	}
+
+	result := schema.SSODiscoverResponse{}
+	for i := range configs {
+		cfg := &configs[i]
+		if cfg.Organization.PlanType != "enterprise" {
+			continue
+		}
+		result.SSOAvailable = true
+		result.OrganizationSlug = cfg.Organization.Slug
+		if cfg.ForceSSO {
+			result.SSORequired = true
+			break
+		}
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// ssoLogin starts SP-initiated SAML login
+// @Summary SAML Login
+// @Description Start single sign-on for an enterprise organization. The frontend redirects the user to authorization_url; the identity provider posts back to the ACS endpoint.
+// @Tags AUTH
+// @Produce json
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/sso/saml/{orgSlug}/login [get]
+func ssoLogin(c *fiber.Ctx) error {
+	org, cfg, err := loadSSO(database.DB, c.Params("orgSlug"))
+	if errors.Is(err, errSSONotConfigured) {
+		return response.Fail(c, "SSO_NOT_CONFIGURED", "Single sign-on is not configured for this organization", fiber.StatusNotFound)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load single sign-on", fiber.StatusInternalServerError)
+	}
+
+	sp, err := sso.ServiceProvider(org, cfg)
+	if err != nil {
+		log.Printf("sso: organization %d: %v", org.ID, err)
+		return response.Fail(c, "SSO_MISCONFIGURED", "Single sign-on is misconfigured", fiber.StatusInternalServerError)
+	}
+
+	authURL, err := sso.Begin(c.Context(), sp, org.ID)
+	if err != nil {
+		log.Printf("sso: organization %d: %v", org.ID, err)
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to start single sign-on", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"authorization_url": authURL,
+	}, fiber.StatusOK)
+}
+
+// ssoMetadata publishes the service provider metadata
+// @Summary SAML SP Metadata
+// @Description Service provider metadata to register this API with the organization's identity provider
+// @Tags AUTH
+// @Produce xml
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {string} string "SAML metadata"
+// @Failure 404 {object} response.SWErrorResponse
+// @Router /auth/sso/saml/{orgSlug}/metadata [get]
+func ssoMetadata(c *fiber.Ctx) error {
+	org, cfg, err := loadSSO(database.DB, c.Params("orgSlug"))
+	if errors.Is(err, errSSONotConfigured) {
+		return response.Fail(c, "SSO_NOT_CONFIGURED", "Single sign-on is not configured for this organization", fiber.StatusNotFound)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load single sign-on", fiber.StatusInternalServerError)
+	}
+
+	sp, err := sso.ServiceProvider(org, cfg)
+	if err != nil {
+		return response.Fail(c, "SSO_MISCONFIGURED", "Single sign-on is misconfigured", fiber.StatusInternalServerError)
+	}
+
+	metadata, err := xml.MarshalIndent(sp.Metadata(), "", "  ")
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to render metadata", fiber.StatusInternalServerError)
+	}
+	c.Set(fiber.HeaderContentType, "application/samlmetadata+xml")
+	return c.Send(metadata)
+}
+
+// ssoACS receives the identity provider's response
+// @Summary SAML Assertion Consumer Service
+// @Description Receives the SAMLResponse (HTTP-POST binding), provisions the user into the organization and redirects the browser to APP_BASE_URL/sso/callback with a one-time code for /auth/sso/exchange, or with an error.
+// @Tags AUTH
+// @Accept x-www-form-urlencoded
+// @Param orgSlug path string true "Organization slug"
+// @Param SAMLResponse formData string true "Base64 encoded SAML response"
+// @Param RelayState formData string true "Relay state from the login request"
+// @Success 302
+// @Router /auth/sso/saml/{orgSlug}/acs [post]
+func ssoACS(c *fiber.Ctx) error {
+	fail := func(code string) error {
+		return c.Redirect(fmt.Sprintf("%s/sso/callback?error=%s", config.Env.AppBaseURL, url.QueryEscape(code)), fiber.StatusSeeOther)
+	}
+
+	db := database.DB
+	org, cfg, err := loadSSO(db, c.Params("orgSlug"))
+	if err != nil {
+		return fail("sso_not_configured")
+	}
+	sp, err := sso.ServiceProvider(org, cfg)
+	if err != nil {
+		log.Printf("sso: organization %d: %v", org.ID, err)
+		return fail("sso_misconfigured")
+	}
+
+	identity, err := sso.Complete(c.Context(), sp, org.ID, ssoMapping(cfg), c.FormValue("SAMLResponse"), c.FormValue("RelayState"))
+	if err != nil {
+		log.Printf("sso: organization %d: %v", org.ID, err)
+		switch {
+		case errors.Is(err, sso.ErrInvalidRelayState):
+			return fail("invalid_state")
+		case errors.Is(err, sso.ErrMissingEmail):
+			return fail("email_required")
+		default:
+			return fail("invalid_response")
+		}
+	}
+
+	user, err := provisionSSOUser(db, org, cfg, identity)
+	if errors.Is(err, errSSOEmailConflict) {
+		return fail("email_exists")
+	}
+	if errors.Is(err, errSSODomainNotVerified) {
+		return fail("domain_not_verified")
+	}
+	var quota *entitlements.QuotaError
+	if errors.As(err, &quota) {
+		return fail("quota_exceeded")
+	}
+	if err != nil {
+		log.Printf("sso: organization %d: failed to provision %s: %v", org.ID, identity.Email, err)
+		return fail("server_error")
+	}
+
+	code, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return fail("server_error")
+	}
+	if err := cache.Default.Set(c.Context(), "sso:code:"+code, strconv.FormatUint(uint64(user.ID), 10), ssoCodeTTL); err != nil {
+		return fail("server_error")
+	}
+	return c.Redirect(fmt.Sprintf("%s/sso/callback?code=%s", config.Env.AppBaseURL, url.QueryEscape(code)), fiber.StatusSeeOther)
+}
+
+// provisionSSOUser finds or creates the user behind a SAML identity and
+// makes sure they are a member of the organization. Identities not seen
+// before must have an email in one of the organization's verified domains.
+// Such an identity is only linked to an existing account that is already
+// an active member, so an identity provider cannot take over accounts of
+// outsiders.
+func provisionSSOUser(db *gorm.DB, org *models.Organization, cfg *models.OrganizationSSO, identity *sso.Identity) (*models.User, error) {
+	provider := fmt.Sprintf("saml:%d", org.ID)
+	now := time.Now()
+	var user models.User
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		domains, err := verifiedDomains(tx, org.ID)
+		if err != nil {
+			return err
+		}
+		covered := slices.Contains(domains, sso.EmailDomain(identity.Email))
+
+		var method models.UserAuthMethod
+		err = tx.Where("auth_type = ? AND auth_provider = ? AND provider_id = ?", "sso", provider, identity.Subject).First(&method).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&user, method.UserID).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if !covered {
+				return errSSODomainNotVerified
+			}
+			err := tx.Where("email = ?", identity.Email).First(&user).Error
+			switch {
+			case err == nil:
+				var members int64
+				if err := tx.Model(&models.OrganizationMember{}).Scopes(authz.ActiveMembers).
+					Where("organization_members.organization_id = ? AND organization_members.user_id = ?", org.ID, user.ID).
+					Count(&members).Error; err != nil {
+					return err
+				}
+				if members == 0 {
+					return errSSOEmailConflict
+				}
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				user = models.User{
+					Email:           identity.Email,
+					FirstName:       identity.FirstName,
+					LastName:        identity.LastName,
+					DisplayName:     identity.DisplayName,
+					IsEmailVerified: true, // the domain is verified
+				}
+				if err := tx.Create(&user).Error; err != nil {
+					return err
+				}
+			default:
+				return err
+			}
+
+			subject := identity.Subject
+			if err := tx.Create(&models.UserAuthMethod{
+				UserID:       user.ID,
+				AuthType:     "sso",
+				AuthProvider: &provider,
+				ProviderID:   &subject,
+			}).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		// The identity provider is the source of truth for the profile
+		updates := map[string]any{"last_login_at": &now}
+		if identity.FirstName != "" {
+			updates["first_name"] = identity.FirstName
+		}
+		if identity.LastName != "" {
+			updates["last_name"] = identity.LastName
+		}
+		if identity.DisplayName != "" {
+			updates["display_name"] = identity.DisplayName
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// Just-in-time membership, which takes a seat
+		var member models.OrganizationMember
+		err = tx.Where("organization_id = ? AND user_id = ?", org.ID, user.ID).First(&member).Error
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := entitlements.Check(context.Background(), tx, org, entitlements.Members, 1); err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         user.ID,
+			Role:           cfg.DefaultRole,
+			JoinedAt:       &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ssoExchange redeems the one-time code from the ACS redirect
+// @Summary SSO Exchange
+// @Description Exchange the code the SAML login redirected with for the usual token response. Users with 2FA get a challenge token instead.
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param X-Device-ID header string false "Device identifier the session is bound to"
+// @Param request body schema.SSOExchangeRequest true "SSO Exchange Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/sso/exchange [post]
+func ssoExchange(c *fiber.Ctx) error {
+	var req schema.SSOExchangeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	key := "sso:code:" + req.Code
+	userID, err := cache.Default.Get(c.Context(), key)
+	if errors.Is(err, cache.ErrNotFound) {
+		return response.Fail(c, "INVALID_CODE", "Sign-in code is invalid or expired", fiber.StatusBadRequest)
+	}
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to redeem sign-in code", fiber.StatusInternalServerError)
+	}
+	fresh, err := cache.Default.SetNX(c.Context(), key+":used", "1", ssoCodeTTL)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to redeem sign-in code", fiber.StatusInternalServerError)
+	}
+	if !fresh {
+		return response.Fail(c, "INVALID_CODE", "Sign-in code is invalid or expired", fiber.StatusBadRequest)
+	}
+	_ = cache.Default.Delete(c.Context(), key)
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
+	}
+	return completeLogin(c, &user, fiber.StatusOK)
+}
+
+func toOrganizationSSOResponse(org *models.Organization, cfg *models.OrganizationSSO, domains []string) schema.OrganizationSSOResponse {
+	mapping := ssoMapping(cfg)
+	return schema.OrganizationSSOResponse{
+		Enabled:      cfg.Enabled,
+		IdPEntityID:  cfg.IdPEntityID,
+		EmailDomains: domains,
+		ForceSSO:     cfg.ForceSSO,
+		DefaultRole:  cfg.DefaultRole,
+		AttributeMapping: schema.SSOAttributeMapping{
+			Email:       mapping.Email,
+			FirstName:   mapping.FirstName,
+			LastName:    mapping.LastName,
+			DisplayName: mapping.DisplayName,
+		},
+		SPEntityID: sso.MetadataURL(org.Slug),
+		ACSURL:     sso.ACSURL(org.Slug),
+		UpdatedAt:  cfg.UpdatedAt,
+	}
+}
+
+// getOrganizationSSOHandler returns the SSO configuration
+// @Summary Get Organization SSO
+// @Description Get the SAML single sign-on configuration and the service provider URLs to register with the identity provider
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/sso [get]
+func getOrganizationSSOHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	var cfg models.OrganizationSSO
+	err := middleware.DB(c).Where("organization_id = ?", org.ID).First(&cfg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.Fail(c, "SSO_NOT_CONFIGURED", "Single sign-on is not configured for this organization", fiber.StatusNotFound)
+	}
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load single sign-on", fiber.StatusInternalServerError)
+	}
+	domains, err := verifiedDomains(middleware.DB(c), org.ID)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load domains", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, toOrganizationSSOResponse(org, &cfg, domains), fiber.StatusOK)
+}
+
+// updateOrganizationSSOHandler configures SAML single sign-on
+// @Summary Configure Organization SSO
+// @Description Upload the identity provider metadata and set forced SSO, default role and attribute mapping. Logins are tied to the organization's verified domains; forcing SSO requires at least one. Enterprise plan only.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param request body schema.UpdateOrganizationSSO true "SSO Configuration"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/sso [put]
+func updateOrganizationSSOHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+	if org.PlanType != "enterprise" {
+		return response.Fail(c, "PLAN_REQUIRED", "Single sign-on requires the enterprise plan", fiber.StatusForbidden)
+	}
+
+	var req schema.UpdateOrganizationSSO
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	idp, err := sso.ParseMetadata([]byte(req.IdPMetadata))
+	if err != nil {
+		return response.Fail(c, "INVALID_METADATA", err.Error(), fiber.StatusBadRequest)
+	}
+	domains, err := verifiedDomains(middleware.DB(c), org.ID)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load domains", fiber.StatusInternalServerError)
+	}
+	if req.ForceSSO && len(domains) == 0 {
+		return response.Fail(c, "DOMAIN_NOT_VERIFIED", "Verify an email domain before forcing single sign-on", fiber.StatusBadRequest)
+	}
+
+	var cfg models.OrganizationSSO
+	err = middleware.DB(c).Where("organization_id = ?", org.ID).First(&cfg).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load single sign-on", fiber.StatusInternalServerError)
+	}
+
+	cfg.OrganizationID = org.ID
+	cfg.IdPMetadata = req.IdPMetadata
+	cfg.IdPEntityID = idp.EntityID
+	cfg.ForceSSO = req.ForceSSO
+	cfg.DefaultRole = "member"
+	if req.DefaultRole != "" {
+		cfg.DefaultRole = req.DefaultRole
+	}
+	cfg.Enabled = req.Enabled == nil || *req.Enabled
+	cfg.AttributeMapping = ""
+	if req.AttributeMapping != nil {
+		mapping, err := json.Marshal(sso.AttributeMapping{
+			Email:       req.AttributeMapping.Email,
+			FirstName:   req.AttributeMapping.FirstName,
+			LastName:    req.AttributeMapping.LastName,
+			DisplayName: req.AttributeMapping.DisplayName,
+		})
+		if err != nil {
+			return response.Fail(c, "INTERNAL_ERROR", "Failed to encode attribute mapping", fiber.StatusInternalServerError)
+		}
+		cfg.AttributeMapping = string(mapping)
+	}
+
+	// Save writes zero values too, so enabled=false and force_sso=false stick
+	if err := middleware.DB(c).Save(&cfg).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to save single sign-on", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, toOrganizationSSOResponse(org, &cfg, domains), fiber.StatusOK)
+}
+
+// deleteOrganizationSSOHandler removes the SSO configuration
+// @Summary Delete Organization SSO
+// @Description Remove the SAML configuration. Members keep their accounts but have to sign in with another method.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/sso [delete]
+func deleteOrganizationSSOHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	res := middleware.DB(c).Where("organization_id = ?", org.ID).Delete(&models.OrganizationSSO{})
+	if res.Error != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to delete single sign-on", fiber.StatusInternalServerError)
+	}
+	if res.RowsAffected == 0 {
+		return response.Fail(c, "SSO_NOT_CONFIGURED", "Single sign-on is not configured for this organization", fiber.StatusNotFound)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Single sign-on removed",
+	}, fiber.StatusOK)
+}
+
+func toSSODomainResponse(domain *models.OrganizationDomain) schema.SSODomainResponse {
+	name, value := sso.VerificationRecord(domain.Domain, domain.VerificationToken)
+	return schema.SSODomainResponse{
+		ID:          domain.ID,
+		Domain:      domain.Domain,
+		Verified:    domain.VerifiedAt != nil,
+		VerifiedAt:  domain.VerifiedAt,
+		RecordName:  name,
+		RecordValue: value,
+		CreatedAt:   domain.CreatedAt,
+	}
+}
+
+// loadSSODomain finds the :id domain of the current organization
+func loadSSODomain(c *fiber.Ctx) (*models.OrganizationDomain, error) {
+	org := c.Locals("organization").(*models.Organization)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return nil, errInvalidID
+	}
+
+	var domain models.OrganizationDomain
+	if err := middleware.DB(c).Where("id = ? AND organization_id = ?", id, org.ID).First(&domain).Error; err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// listSSODomainsHandler lists the organization's domains
+// @Summary List SSO Domains
+// @Description List the email domains the organization claimed for single sign-on, with the TXT record that verifies each
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/sso/domains [get]
+func listSSODomainsHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	var domains []models.OrganizationDomain
+	if err := middleware.DB(c).Where("organization_id = ?", org.ID).Order("domain").Find(&domains).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load domains", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.SSODomainResponse, 0, len(domains))
+	for i := range domains {
+		result = append(result, toSSODomainResponse(&domains[i]))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// addSSODomainHandler claims a domain
+// @Summary Add SSO Domain
+// @Description Claim an email domain for single sign-on. The domain is used for logins only after the returned TXT record is published and verified.
+// @Tags ORGANIZATION
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param request body schema.AddSSODomain true "Add SSO Domain Request"
+// @Success 201 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/sso/domains [post]
+func addSSODomainHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+
+	var req schema.AddSSODomain
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	db := middleware.DB(c)
+	domain := models.OrganizationDomain{OrganizationID: org.ID, Domain: strings.ToLower(req.Domain)}
+	var count int64
+	if err := db.Model(&models.OrganizationDomain{}).
+		Where("organization_id = ? AND domain = ?", org.ID, domain.Domain).Count(&count).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to check domain", fiber.StatusInternalServerError)
+	}
+	if count > 0 {
+		return response.Fail(c, "DOMAIN_EXISTS", "The organization already claimed this domain", fiber.StatusConflict)
+	}
+
+	token, err := sso.NewVerificationToken()
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate verification token", fiber.StatusInternalServerError)
+	}
+	domain.VerificationToken = token
+	if err := db.Create(&domain).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to add domain", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, toSSODomainResponse(&domain), fiber.StatusCreated)
+}
+
+// verifySSODomainHandler checks a domain's TXT record
+// @Summary Verify SSO Domain
+// @Description Look up the domain's verification TXT record. Once found, single sign-on links and enforces logins of the domain's emails. A domain can be verified by one organization only.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Domain ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Failure 502 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/sso/domains/{id}/verify [post]
+func verifySSODomainHandler(c *fiber.Ctx) error {
+	domain, err := loadSSODomain(c)
+	if err != nil {
+		return loadFailure(c, err, "Domain")
+	}
+	if domain.VerifiedAt != nil {
+		return response.OK(c, toSSODomainResponse(domain), fiber.StatusOK)
+	}
+
+	db := middleware.DB(c)
+	var taken int64
+	if err := db.Model(&models.OrganizationDomain{}).
+		Where("domain = ? AND verified_at IS NOT NULL AND organization_id <> ?", domain.Domain, domain.OrganizationID).
+		Count(&taken).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to check domain", fiber.StatusInternalServerError)
+	}
+	if taken > 0 {
+		return response.Fail(c, "DOMAIN_TAKEN", "Another organization has verified this domain", fiber.StatusConflict)
+	}
+
+	found, err := sso.VerifyDomain(c.Context(), domain.Domain, domain.VerificationToken)
+	if err != nil {
+		log.Printf("sso: failed to look up TXT records of %s: %v", domain.Domain, err)
+		return response.Fail(c, "DNS_ERROR", "Failed to look up the domain's DNS records", fiber.StatusBadGateway)
+	}
+	if !found {
+		return response.Fail(c, "DOMAIN_NOT_VERIFIED", "The verification TXT record was not found", fiber.StatusBadRequest)
+	}
+
+	now := time.Now()
+	if err := db.Model(domain).Update("verified_at", &now).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to verify domain", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, toSSODomainResponse(domain), fiber.StatusOK)
+}
+
+// deleteSSODomainHandler releases a domain
+// @Summary Delete SSO Domain
+// @Description Release a claimed domain. Logins of its emails are no longer linked or forced through single sign-on.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Param id path int true "Domain ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/sso/domains/{id} [delete]
+func deleteSSODomainHandler(c *fiber.Ctx) error {
+	domain, err := loadSSODomain(c)
+	if err != nil {
+		return loadFailure(c, err, "Domain")
+	}
+
+	if err := middleware.DB(c).Delete(domain).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to delete domain", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, fiber.Map{
+		"message": "Domain removed",
+	}, fiber.StatusOK)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/database/dbtest"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/sso"
+	"gorm.io/gorm"
+)
+
+// ssoFixture is an enterprise organization with single sign-on and a
+// verified domain
+type ssoFixture struct {
+	db     *gorm.DB
+	org    *models.Organization
+	cfg    *models.OrganizationSSO
+	domain string
+}
+
+func newSSOFixture(t *testing.T) *ssoFixture {
+	t.Helper()
+	db := dbtest.Open(t)
+
+	org := &models.Organization{Name: "Acme", Slug: dbtest.Unique("acme-"), PlanType: "enterprise", Settings: "{}"}
+	if err := db.Create(org).Error; err != nil {
+		t.Fatal(err)
+	}
+	cfg := &models.OrganizationSSO{OrganizationID: org.ID, Enabled: true, IdPMetadata: "<EntityDescriptor/>", DefaultRole: "guest"}
+	if err := db.Create(cfg).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	domain := dbtest.Unique("acme") + ".example"
+	if err := db.Create(&models.OrganizationDomain{OrganizationID: org.ID, Domain: domain, VerificationToken: "t", VerifiedAt: &now}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Claimed but never verified
+	if err := db.Create(&models.OrganizationDomain{OrganizationID: org.ID, Domain: "unverified-" + domain, VerificationToken: "t"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &ssoFixture{db: db, org: org, cfg: cfg, domain: domain}
+}
+
+func (f *ssoFixture) identity(local string) *sso.Identity {
+	return &sso.Identity{Subject: dbtest.Unique("sub-"), Email: local + "@" + f.domain, FirstName: "Jane", LastName: "Doe"}
+}
+
+func (f *ssoFixture) createUser(t *testing.T, email string) *models.User {
+	t.Helper()
+	user := &models.User{Email: email}
+	if err := f.db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func (f *ssoFixture) member(t *testing.T, userID uint) *models.OrganizationMember {
+	t.Helper()
+	var member models.OrganizationMember
+	if err := f.db.Where("organization_id = ? AND user_id = ?", f.org.ID, userID).First(&member).Error; err != nil {
+		t.Fatalf("membership of user %d: %v", userID, err)
+	}
+	return &member
+}
+
+func TestProvisionSSOUserCreatesMember(t *testing.T) {
+	f := newSSOFixture(t)
+	identity := f.identity(dbtest.Unique("jane"))
+
+	user, err := provisionSSOUser(f.db, f.org, f.cfg, identity)
+	if err != nil {
+		t.Fatalf("provisionSSOUser: %v", err)
+	}
+	if user.Email != identity.Email || !user.IsEmailVerified || user.FirstName != "Jane" || user.LastName != "Doe" {
+		t.Fatalf("user = %+v", user)
+	}
+	if member := f.member(t, user.ID); member.Role != "guest" {
+		t.Fatalf("role = %s, want the default role guest", member.Role)
+	}
+
+	// The same identity signs in to the same account, with the profile
+	// kept in sync with the identity provider
+	identity.FirstName = "Janet"
+	again, err := provisionSSOUser(f.db, f.org, f.cfg, identity)
+	if err != nil {
+		t.Fatalf("second sign-in: %v", err)
+	}
+	if again.ID != user.ID {
+		t.Fatalf("second sign-in: user %d, want %d", again.ID, user.ID)
+	}
+	var stored models.User
+	if err := f.db.First(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.FirstName != "Janet" {
+		t.Fatalf("first name = %q, want Janet", stored.FirstName)
+	}
+}
+
+func TestProvisionSSOUserRequiresVerifiedDomain(t *testing.T) {
+	f := newSSOFixture(t)
+	identity := &sso.Identity{Subject: dbtest.Unique("sub-"), Email: dbtest.Unique("jane") + "@unverified-" + f.domain}
+
+	if _, err := provisionSSOUser(f.db, f.org, f.cfg, identity); !errors.Is(err, errSSODomainNotVerified) {
+		t.Fatalf("err = %v, want errSSODomainNotVerified", err)
+	}
+	var count int64
+	f.db.Model(&models.User{}).Where("email = ?", identity.Email).Count(&count)
+	if count != 0 {
+		t.Fatal("a user was created for an unverified domain")
+	}
+}
+
+func TestProvisionSSOUserDoesNotLinkOutsiders(t *testing.T) {
+	f := newSSOFixture(t)
+	identity := f.identity(dbtest.Unique("outsider"))
+	outsider := f.createUser(t, identity.Email)
+
+	if _, err := provisionSSOUser(f.db, f.org, f.cfg, identity); !errors.Is(err, errSSOEmailConflict) {
+		t.Fatalf("err = %v, want errSSOEmailConflict", err)
+	}
+	var methods int64
+	f.db.Model(&models.UserAuthMethod{}).Where("user_id = ? AND auth_type = ?", outsider.ID, "sso").Count(&methods)
+	if methods != 0 {
+		t.Fatal("the identity was linked to an account outside the organization")
+	}
+}
+
+func TestProvisionSSOUserLinksActiveMember(t *testing.T) {
+	f := newSSOFixture(t)
+	identity := f.identity(dbtest.Unique("member"))
+	existing := f.createUser(t, identity.Email)
+	now := time.Now()
+	if err := f.db.Create(&models.OrganizationMember{OrganizationID: f.org.ID, UserID: existing.ID, Role: "admin", JoinedAt: &now}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := provisionSSOUser(f.db, f.org, f.cfg, identity)
+	if err != nil {
+		t.Fatalf("provisionSSOUser: %v", err)
+	}
+	if user.ID != existing.ID {
+		t.Fatalf("signed in as user %d, want the existing member %d", user.ID, existing.ID)
+	}
+	if member := f.member(t, user.ID); member.Role != "admin" {
+		t.Fatalf("role = %s, the existing membership must be kept", member.Role)
+	}
+}
+
+func TestSSOEnforcedOnlyForMembers(t *testing.T) {
+	f := newSSOFixture(t)
+	if err := f.db.Model(f.cfg).Update("force_sso", true).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	member := f.createUser(t, dbtest.Unique("member")+"@"+f.domain)
+	if err := f.db.Create(&models.OrganizationMember{OrganizationID: f.org.ID, UserID: member.ID, Role: "member"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	outsider := f.createUser(t, dbtest.Unique("outsider")+"@"+f.domain)
+	unverified := f.createUser(t, dbtest.Unique("member")+"@unverified-"+f.domain)
+	if err := f.db.Create(&models.OrganizationMember{OrganizationID: f.org.ID, UserID: unverified.ID, Role: "member"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{member.Email, true},
+		{outsider.Email, false},
+		{unverified.Email, false},
+	}
+	for _, tt := range tests {
+		org, err := ssoEnforcedFor(f.db, tt.email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := org != nil; got != tt.want {
+			t.Errorf("ssoEnforcedFor(%s) = %t, want %t", tt.email, got, tt.want)
+		}
+	}
+}
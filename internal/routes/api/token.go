@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+// deviceFromRequest identifies the calling client. Mobile and SPA clients
+// send a stable X-Device-ID so refresh tokens can be bound to it.
+func deviceFromRequest(c *fiber.Ctx) session.Device {
+	return session.Device{
+		ID:        c.Get("X-Device-ID"),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IP:        c.IP(),
+	}
+}
+
+func toUserInfo(user *models.User) schema.UserInfo {
+	return schema.UserInfo{
+		ID:              user.ID,
+		Email:           user.Email,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		DisplayName:     user.DisplayName,
+		Avatar:          user.Avatar,
+		IsEmailVerified: user.IsEmailVerified,
+		IsBot:           user.IsBot,
+		LastLoginAt:     user.LastLoginAt,
+	}
+}
+
+func toLoginResponse(user *models.User, pair *session.TokenPair) schema.LoginResponse {
+	return schema.LoginResponse{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    pair.ExpiresIn,
+		User:         toUserInfo(user),
+	}
+}
+
+// respondWithTokenPair starts a new session for the user and writes the
+// token pair as the response body.
+func respondWithTokenPair(c *fiber.Ctx, user *models.User, status int) error {
+	pair, err := session.Issue(database.DB, user, deviceFromRequest(c))
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate token", fiber.StatusInternalServerError)
+	}
+	return response.OK(c, toLoginResponse(user, pair), status)
+}
+
+// refreshToken rotates a refresh token
+// @Summary Refresh Token
+// @Description Exchange a refresh token for a new access/refresh token pair. Reusing a rotated refresh token revokes the whole session.
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param X-Device-ID header string false "Device identifier the session is bound to"
+// @Param refresh body schema.RefreshTokenRequest true "Refresh Token Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/refresh [post]
+func refreshToken(c *fiber.Ctx) error {
+	var req schema.RefreshTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	pair, user, err := session.Rotate(database.DB, req.RefreshToken, deviceFromRequest(c))
+	switch {
+	case errors.Is(err, session.ErrRefreshTokenReused):
+		return response.Fail(c, "REFRESH_TOKEN_REUSED", "Refresh token has already been used, session revoked", fiber.StatusUnauthorized)
+	case errors.Is(err, session.ErrDeviceMismatch):
+		return response.Fail(c, "DEVICE_MISMATCH", "Refresh token was issued to another device, session revoked", fiber.StatusUnauthorized)
+	case errors.Is(err, session.ErrInvalidRefreshToken):
+		return response.Fail(c, "INVALID_REFRESH_TOKEN", "Invalid or expired refresh token", fiber.StatusUnauthorized)
+	case errors.Is(err, session.ErrUserDisabled):
+		return response.Fail(c, "ACCOUNT_SUSPENDED", "This account has been suspended, session revoked", fiber.StatusForbidden)
+	case err != nil:
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to refresh token", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, toLoginResponse(user, pair), fiber.StatusOK)
+}
+
+// logout ends the current session
+// @Summary Logout
+// @Description Revoke the current access token and the refresh tokens of its session
+// @Tags AUTH
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/logout [post]
+func logout(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	if err := session.RevokeAccessToken(c.Context(), claims); err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to revoke token", fiber.StatusInternalServerError)
+	}
+	if err := session.RevokeSession(c.Context(), database.DB, claims.SessionID); err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to revoke session", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Logged out successfully",
+	}, fiber.StatusOK)
+}
+
+// logoutAll ends every session of the current user
+// @Summary Logout All Sessions
+// @Description Revoke every access and refresh token of the authenticated user on all devices
+// @Tags AUTH
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/logout-all [post]
+func logoutAll(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	if err := session.RevokeAllSessions(c.Context(), database.DB, claims.UserID); err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to revoke sessions", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Logged out from all sessions",
+	}, fiber.StatusOK)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+const defaultPATLifetimeDays = 90
+
+func toPATResponse(token *models.PersonalAccessToken) schema.PATResponse {
+	return schema.PATResponse{
+		ID:          token.ID,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		Scopes:      strings.Fields(token.Scopes),
+		ExpiresAt:   token.ExpiresAt,
+		LastUsedAt:  token.LastUsedAt,
+		CreatedAt:   token.CreatedAt,
+	}
+}
+
+// listTokensHandler lists the user's personal access tokens
+// @Summary List Personal Access Tokens
+// @Description List the authenticated user's active personal access tokens. Secrets are never returned.
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/tokens [get]
+func listTokensHandler(c *fiber.Ctx) error {
+	user := c.Locals("user").(*jwt.Claims)
+
+	var tokens []models.PersonalAccessToken
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL", user.UserID).
+		Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load tokens", fiber.StatusInternalServerError)
+	}
+
+	result := make([]schema.PATResponse, 0, len(tokens))
+	for i := range tokens {
+		result = append(result, toPATResponse(&tokens[i]))
+	}
+	return response.OK(c, result, fiber.StatusOK)
+}
+
+// createTokenHandler creates a personal access token
+// @Summary Create Personal Access Token
+// @Description Create a token for scripts and CI. The token is returned only once and is sent as "Authorization: Bearer pat_...".
+// @Tags PROFILE
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body schema.CreatePATRequest true "Create Token Request"
+// @Success 201 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/tokens [post]
+func createTokenHandler(c *fiber.Ctx) error {
+	user := c.Locals("user").(*jwt.Claims)
+
+	var req schema.CreatePATRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultPATLifetimeDays
+	}
+	expiresAt := time.Now().AddDate(0, 0, days)
+
+	record, token, err := session.CreatePAT(database.DB, user.UserID, req.Name, req.Scopes, &expiresAt)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to create token", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, schema.PATCreatedResponse{
+		PATResponse: toPATResponse(record),
+		Token:       token,
+	}, fiber.StatusCreated)
+}
+
+// revokeTokenHandler revokes a personal access token
+// @Summary Revoke Personal Access Token
+// @Description Revoke one of the authenticated user's personal access tokens
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Token ID"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/tokens/{id} [delete]
+func revokeTokenHandler(c *fiber.Ctx) error {
+	user := c.Locals("user").(*jwt.Claims)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Fail(c, "INVALID_ID", "Invalid ID format", fiber.StatusBadRequest)
+	}
+
+	res := database.DB.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, user.UserID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to revoke token", fiber.StatusInternalServerError)
+	}
+	if res.RowsAffected == 0 {
+		return response.Fail(c, "NOT_FOUND", "Token not found", fiber.StatusNotFound)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Token revoked",
+	}, fiber.StatusOK)
+}
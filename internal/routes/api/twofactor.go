@@ -0,0 +1,474 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/session"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	purposeMFAChallenge = "mfa_challenge"
+	mfaChallengeTTL     = 5 * time.Minute
+	mfaMaxAttempts      = 5
+	recoveryCodeCount   = 10
+)
+
+var errInvalidSecondFactor = errors.New("invalid second factor code")
+
+var totpOpts = totp.ValidateOpts{
+	Period:    30,
+	Skew:      1,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// completeLogin finishes a primary authentication step. Users with 2FA get
+// a short-lived challenge token instead of a token pair.
+func completeLogin(c *fiber.Ctx, user *models.User, status int) error {
+	suspended, err := isSuspended(database.DB, user)
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to resolve user status", fiber.StatusInternalServerError)
+	}
+	if suspended {
+		return response.Fail(c, "ACCOUNT_SUSPENDED", "This account has been suspended", fiber.StatusForbidden)
+	}
+
+	if _, err := loadTOTPMethod(database.DB, user.ID, true); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return respondWithTokenPair(c, user, status)
+		}
+		return response.Fail(c, "DATABASE_ERROR", "Failed to load auth methods", fiber.StatusInternalServerError)
+	}
+
+	challenge, err := jwt.GeneratePurposeToken(purposeMFAChallenge, strconv.FormatUint(uint64(user.ID), 10), "", mfaChallengeTTL)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate token", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, schema.MFAChallengeResponse{
+		MFARequired:    true,
+		ChallengeToken: challenge,
+		Methods:        []string{"totp", "recovery_code"},
+		ExpiresIn:      int(mfaChallengeTTL.Seconds()),
+	}, fiber.StatusOK)
+}
+
+// loadTOTPMethod returns the user's TOTP method, optionally only if confirmed.
+func loadTOTPMethod(db *gorm.DB, userID uint, confirmed bool) (*models.UserAuthMethod, error) {
+	query := db.Where("user_id = ? AND auth_type = ?", userID, "totp")
+	if confirmed {
+		query = query.Where("confirmed_at IS NOT NULL")
+	}
+	var method models.UserAuthMethod
+	if err := query.First(&method).Error; err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+// verifySecondFactor accepts a current TOTP code or an unused recovery code.
+func verifySecondFactor(ctx context.Context, db *gorm.DB, method *models.UserAuthMethod, code string) error {
+	code = strings.TrimSpace(code)
+	if _, err := strconv.Atoi(code); err == nil && len(code) == int(otp.DigitsSix) {
+		return verifyTOTPCode(ctx, method, code)
+	}
+	return useRecoveryCode(db, method.UserID, code)
+}
+
+func verifyTOTPCode(ctx context.Context, method *models.UserAuthMethod, code string) error {
+	if method.Secret == nil {
+		return errInvalidSecondFactor
+	}
+	secret, err := auth.Decrypt(config.Env.EncryptionKey, *method.Secret)
+	if err != nil {
+		return err
+	}
+
+	valid, err := totp.ValidateCustom(code, secret, time.Now().UTC(), totpOpts)
+	if err != nil || !valid {
+		return errInvalidSecondFactor
+	}
+
+	// With skew a code stays valid for three periods; it may only be used once.
+	fresh, err := cache.Default.SetNX(ctx, fmt.Sprintf("totp:used:%d:%s", method.UserID, code), "1", 3*time.Duration(totpOpts.Period)*time.Second)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return errInvalidSecondFactor
+	}
+	return nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func useRecoveryCode(db *gorm.DB, userID uint, code string) error {
+	res := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, auth.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errInvalidSecondFactor
+	}
+	return nil
+}
+
+// generateRecoveryCodes replaces the user's recovery codes and returns the
+// new plaintext codes. They cannot be shown again afterwards.
+func generateRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := auth.GenerateRandomToken(8)
+		if err != nil {
+			return nil, err
+		}
+		raw = strings.ToLower(strings.NewReplacer("-", "x", "_", "y").Replace(raw))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: auth.HashToken(raw)})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifyMFA completes a login that requires a second factor
+// @Summary Verify Second Factor
+// @Description Exchange the challenge token from login plus a TOTP or recovery code for a token pair
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param X-Device-ID header string false "Device identifier the session is bound to"
+// @Param request body schema.MFAVerifyRequest true "MFA Verify Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 429 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/2fa/verify [post]
+func verifyMFA(c *fiber.Ctx) error {
+	var req schema.MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	challenge, err := jwt.ValidatePurposeToken(purposeMFAChallenge, req.ChallengeToken)
+	if err != nil {
+		return response.Fail(c, "INVALID_TOKEN", "Challenge token is invalid or expired", fiber.StatusUnauthorized)
+	}
+
+	attempts, err := cache.Default.Incr(c.Context(), "mfa:attempts:"+challenge.ID, mfaChallengeTTL)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to verify code", fiber.StatusInternalServerError)
+	}
+	if attempts > mfaMaxAttempts {
+		return response.Fail(c, "TOO_MANY_ATTEMPTS", "Too many attempts, please log in again", fiber.StatusTooManyRequests)
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", challenge.Subject).First(&user).Error; err != nil {
+		return response.Fail(c, "INVALID_TOKEN", "Challenge token is invalid or expired", fiber.StatusUnauthorized)
+	}
+
+	// The user may have been suspended since the password step
+	if err := session.CheckUserActive(database.DB, user.ID); err != nil {
+		if errors.Is(err, session.ErrUserDisabled) {
+			return response.Fail(c, "ACCOUNT_SUSPENDED", "This account has been suspended", fiber.StatusForbidden)
+		}
+		return response.Fail(c, "DATABASE_ERROR", "Failed to resolve user status", fiber.StatusInternalServerError)
+	}
+
+	method, err := loadTOTPMethod(database.DB, user.ID, true)
+	if err != nil {
+		return response.Fail(c, "INVALID_TOKEN", "Challenge token is invalid or expired", fiber.StatusUnauthorized)
+	}
+
+	// Claim the challenge before checking the code, so that replaying a
+	// spent challenge cannot burn a recovery code. A wrong code gives it
+	// back for another attempt.
+	usedKey := "mfa:used:" + challenge.ID
+	fresh, err := cache.Default.SetNX(c.Context(), usedKey, "1", mfaChallengeTTL)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to verify code", fiber.StatusInternalServerError)
+	}
+	if !fresh {
+		return response.Fail(c, "INVALID_TOKEN", "Challenge token is invalid or expired", fiber.StatusUnauthorized)
+	}
+
+	if err := verifySecondFactor(c.Context(), database.DB, method, req.Code); err != nil {
+		if delErr := cache.Default.Delete(c.Context(), usedKey); delErr != nil {
+			log.Printf("2fa: release challenge %s: %v", challenge.ID, delErr)
+		}
+		if errors.Is(err, errInvalidSecondFactor) {
+			return response.Fail(c, "INVALID_CODE", "Invalid verification code", fiber.StatusUnauthorized)
+		}
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to verify code", fiber.StatusInternalServerError)
+	}
+
+	return respondWithTokenPair(c, &user, fiber.StatusOK)
+}
+
+// enrollTOTPHandler starts TOTP enrollment
+// @Summary Enroll TOTP
+// @Description Generate a new TOTP secret and provisioning URI. 2FA is only enabled after confirmation.
+// @Tags PROFILE
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 409 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/2fa/totp [post]
+func enrollTOTPHandler(c *fiber.Ctx) error {
+	db := database.DB
+	user := c.Locals("user").(*jwt.Claims)
+
+	if _, err := loadTOTPMethod(db, user.UserID, true); err == nil {
+		return response.Fail(c, "ALREADY_ENABLED", "Two-factor authentication is already enabled", fiber.StatusConflict)
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      config.Env.TOTPIssuer,
+		AccountName: user.Email,
+		Period:      uint(totpOpts.Period),
+		Digits:      totpOpts.Digits,
+		Algorithm:   totpOpts.Algorithm,
+	})
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to generate secret", fiber.StatusInternalServerError)
+	}
+
+	encrypted, err := auth.Encrypt(config.Env.EncryptionKey, key.Secret())
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to store secret", fiber.StatusInternalServerError)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Drop an earlier enrollment that was never confirmed
+		if err := tx.Unscoped().Where("user_id = ? AND auth_type = ?", user.UserID, "totp").
+			Delete(&models.UserAuthMethod{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserAuthMethod{
+			UserID:   user.UserID,
+			AuthType: "totp",
+			Secret:   &encrypted,
+		}).Error
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to store secret", fiber.StatusInternalServerError)
+	}
+
+	img, err := key.Image(200, 200)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to render QR code", fiber.StatusInternalServerError)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to render QR code", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, schema.TOTPEnrollResponse{
+		Secret:          key.Secret(),
+		ProvisioningURI: key.URL(),
+		QRCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, fiber.StatusOK)
+}
+
+// confirmTOTPHandler enables TOTP after checking a first code
+// @Summary Confirm TOTP
+// @Description Confirm TOTP enrollment with a code from the authenticator app. Returns recovery codes, which are shown only once.
+// @Tags PROFILE
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body schema.TOTPCodeRequest true "TOTP Code"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/2fa/totp/confirm [post]
+func confirmTOTPHandler(c *fiber.Ctx) error {
+	db := database.DB
+	user := c.Locals("user").(*jwt.Claims)
+
+	var req schema.TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	method, err := loadTOTPMethod(db, user.UserID, false)
+	if err != nil {
+		return response.Fail(c, "NOT_ENROLLED", "Start TOTP enrollment first", fiber.StatusBadRequest)
+	}
+	if method.ConfirmedAt != nil {
+		return response.Fail(c, "ALREADY_ENABLED", "Two-factor authentication is already enabled", fiber.StatusConflict)
+	}
+
+	if err := verifyTOTPCode(c.Context(), method, strings.TrimSpace(req.Code)); err != nil {
+		return response.Fail(c, "INVALID_CODE", "Invalid verification code", fiber.StatusBadRequest)
+	}
+
+	var codes []string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(method).Update("confirmed_at", time.Now()).Error; err != nil {
+			return err
+		}
+		codes, err = generateRecoveryCodes(tx, user.UserID)
+		return err
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to enable two-factor authentication", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	}, fiber.StatusOK)
+}
+
+// disableTOTPHandler turns off TOTP
+// @Summary Disable TOTP
+// @Description Disable two-factor authentication. Requires a current TOTP or recovery code.
+// @Tags PROFILE
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body schema.TOTPCodeRequest true "TOTP or recovery code"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/2fa/totp [delete]
+func disableTOTPHandler(c *fiber.Ctx) error {
+	db := database.DB
+	user := c.Locals("user").(*jwt.Claims)
+
+	var req schema.TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	method, err := loadTOTPMethod(db, user.UserID, true)
+	if err != nil {
+		return response.Fail(c, "NOT_ENABLED", "Two-factor authentication is not enabled", fiber.StatusBadRequest)
+	}
+
+	if err := verifySecondFactor(c.Context(), db, method, req.Code); err != nil {
+		if errors.Is(err, errInvalidSecondFactor) {
+			return response.Fail(c, "INVALID_CODE", "Invalid verification code", fiber.StatusUnauthorized)
+		}
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to verify code", fiber.StatusInternalServerError)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(method).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.UserID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to disable two-factor authentication", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Two-factor authentication disabled",
+	}, fiber.StatusOK)
+}
+
+// regenerateRecoveryCodesHandler replaces the recovery codes
+// @Summary Regenerate Recovery Codes
+// @Description Invalidate all recovery codes and issue new ones. Requires a current TOTP code.
+// @Tags PROFILE
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body schema.TOTPCodeRequest true "TOTP Code"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/2fa/recovery-codes [post]
+func regenerateRecoveryCodesHandler(c *fiber.Ctx) error {
+	db := database.DB
+	user := c.Locals("user").(*jwt.Claims)
+
+	var req schema.TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	method, err := loadTOTPMethod(db, user.UserID, true)
+	if err != nil {
+		return response.Fail(c, "NOT_ENABLED", "Two-factor authentication is not enabled", fiber.StatusBadRequest)
+	}
+
+	if err := verifyTOTPCode(c.Context(), method, strings.TrimSpace(req.Code)); err != nil {
+		if errors.Is(err, errInvalidSecondFactor) {
+			return response.Fail(c, "INVALID_CODE", "Invalid verification code", fiber.StatusUnauthorized)
+		}
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to verify code", fiber.StatusInternalServerError)
+	}
+
+	var codes []string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		codes, err = generateRecoveryCodes(tx, user.UserID)
+		return err
+	})
+	if err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to generate recovery codes", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"recovery_codes": codes,
+	}, fiber.StatusOK)
+}
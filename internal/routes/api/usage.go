@@ -0,0 +1,53 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/entitlements"
+	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+)
+
+// quotaFail answers an entitlements.Check that found the plan's limit
+// reached.
+func quotaFail(c *fiber.Ctx, quota *entitlements.QuotaError) error {
+	return response.Fail(c, "QUOTA_EXCEEDED", "Plan limit reached: "+quota.Error(), fiber.StatusPaymentRequired)
+}
+
+// organizationUsageHandler reports the organization's usage of its plan
+// @Summary Organization Usage
+// @Description List the limits of the organization's plan and how much of each is used. Members count pending invitations; service accounts do not take a seat. Attachment storage is in bytes and the API rate in requests per minute.
+// @Tags ORGANIZATION
+// @Produce json
+// @Security BearerAuth
+// @Param orgSlug path string true "Organization slug"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 403 {object} response.SWErrorResponse
+// @Failure 404 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /organizations/{orgSlug}/usage [get]
+func organizationUsageHandler(c *fiber.Ctx) error {
+	org := c.Locals("organization").(*models.Organization)
+	limits := entitlements.For(org.PlanType)
+
+	quotas := make([]schema.QuotaUsage, 0, len(entitlements.Resources))
+	for _, resource := range entitlements.Resources {
+		quota := schema.QuotaUsage{Resource: resource}
+		if limit := limits[resource]; limit != entitlements.Unlimited {
+			quota.Limit = &limit
+		}
+		used, err := entitlements.Usage(c.Context(), middleware.DB(c), org.ID, resource)
+		if err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to measure usage", fiber.StatusInternalServerError)
+		}
+		quota.Used = used
+		quotas = append(quotas, quota)
+	}
+
+	return response.OK(c, schema.OrganizationUsageResponse{
+		PlanType: org.PlanType,
+		Quotas:   quotas,
+	}, fiber.StatusOK)
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/entitlements"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+)
+
+func TestQuotaFail(t *testing.T) {
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		return quotaFail(c, &entitlements.QuotaError{Resource: entitlements.Members, Plan: "free", Limit: 5, Used: 5})
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusPaymentRequired {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusPaymentRequired)
+	}
+	var body response.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Success || body.Error == nil || body.Error.Code != "QUOTA_EXCEEDED" {
+		t.Fatalf("body = %+v, want a QUOTA_EXCEEDED error", body)
+	}
+}
@@ -10,17 +10,40 @@ import (
 
 	"github.com/phonsing-Hub/GoLang/internal/database/schema"
 	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/session"
 	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
 	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
 	"github.com/phonsing-Hub/GoLang/pkg/jwt"
 )
 
 func SetupUserhRoutes(router *fiber.App) {
 	userGroup := router.Group("/profile")
-	userGroup.Use(middleware.JWTAuthMiddleware())
+	userGroup.Use(middleware.JWTAuthMiddleware(middleware.ProfileAccess))
 	userGroup.Get("", profileHandler)
-	userGroup.Put("", updateProfileHandler)
-	userGroup.Put("/avatar", updateAvatarHandler)
+	userGroup.Put("", middleware.RequireVerifiedEmail(), updateProfileHandler)
+	userGroup.Put("/avatar", middleware.RequireVerifiedEmail(), updateAvatarHandler)
+
+	// Account security is only reachable with an interactive session
+	secure := userGroup.Group("", middleware.RequireSessionToken())
+	secure.Put("/password", changePasswordHandler)
+	secure.Post("/2fa/totp", enrollTOTPHandler)
+	secure.Post("/2fa/totp/confirm", confirmTOTPHandler)
+	secure.Delete("/2fa/totp", disableTOTPHandler)
+	secure.Post("/2fa/recovery-codes", regenerateRecoveryCodesHandler)
+	secure.Get("/auth-methods", listAuthMethodsHandler)
+	secure.Post("/auth-methods/:provider/link", linkAuthMethodHandler)
+	secure.Post("/auth-methods/:provider/callback", linkAuthMethodCallbackHandler)
+	secure.Put("/auth-methods/:id/primary", setPrimaryAuthMethodHandler)
+	secure.Delete("/auth-methods/:id", unlinkAuthMethodHandler)
+	secure.Get("/sessions", listSessionsHandler)
+	secure.Delete("/sessions/:id", revokeSessionHandler)
+	secure.Get("/tokens", listTokensHandler)
+	secure.Post("/tokens", createTokenHandler)
+	secure.Delete("/tokens/:id", revokeTokenHandler)
+	secure.Get("/impersonations", listImpersonationsHandler)
+	secure.Get("/apps", listAuthorizedAppsHandler)
+	secure.Delete("/apps/:id", revokeAuthorizedAppHandler)
 }
 
 // profileHandler retrieves the user's profile information
@@ -124,3 +147,60 @@ func updateAvatarHandler(c *fiber.Ctx) error {
 		"avatar": newFileName,
 	}, fiber.StatusOK)
 }
+
+// changePasswordHandler changes or sets the user's password
+// @Summary Change Password
+// @Description Change the password after checking the current one. Users without a password can set a first one after confirming a TOTP or recovery code, or within REAUTH_MAX_AGE of signing in. All other sessions are revoked.
+// @Tags PROFILE
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body schema.ChangePasswordRequest true "Change Password Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /profile/password [put]
+func changePasswordHandler(c *fiber.Ctx) error {
+	db := database.DB
+	user := c.Locals("user").(*jwt.Claims)
+
+	var req schema.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	// A stolen access token must not be enough to add a password
+	if err := reauthenticate(c, db, user, schema.ReauthRequest{Password: req.CurrentPassword, Code: req.Code}); err != nil {
+		return reauthFailure(c, err)
+	}
+
+	if err := auth.ValidatePassword(req.NewPassword, user.Email); err != nil {
+		return response.Fail(c, "WEAK_PASSWORD", err.Error(), fiber.StatusBadRequest)
+	}
+
+	hashedPassword, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to hash password", fiber.StatusInternalServerError)
+	}
+
+	if err := setPassword(db, user.UserID, hashedPassword); err != nil {
+		return response.Fail(c, "DATABASE_ERROR", "Failed to update password", fiber.StatusInternalServerError)
+	}
+
+	var keep []string
+	if user.SessionID != "" {
+		keep = append(keep, user.SessionID)
+	}
+	if err := session.RevokeAllSessions(c.Context(), db, user.UserID, keep...); err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to revoke other sessions", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Password updated successfully",
+	}, fiber.StatusOK)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/schema"
+	"github.com/phonsing-Hub/GoLang/internal/mailer"
+	"github.com/phonsing-Hub/GoLang/internal/utils/helper"
+	"github.com/phonsing-Hub/GoLang/internal/utils/response"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+const purposeVerifyEmail = "verify_email"
+
+// userStatusID resolves a user_statuses row by name, e.g. "active".
+func userStatusID(db *gorm.DB, name string) (uint, error) {
+	var status models.UserStatus
+	if err := db.Where("name = ?", name).First(&status).Error; err != nil {
+		return 0, fmt.Errorf("user status %q: %w", name, err)
+	}
+	return status.ID, nil
+}
+
+// sendVerificationEmail mails a signed link bound to the user's current
+// email, so the link stops working if the address changes.
+func sendVerificationEmail(user *models.User) error {
+	token, err := jwt.GeneratePurposeToken(purposeVerifyEmail, strconv.FormatUint(uint64(user.ID), 10), user.Email, config.Env.EmailVerifyTTL)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/verify-email?token=%s", config.Env.AppBaseURL, url.QueryEscape(token))
+	mailer.SendAsync(mailer.EmailVerificationMessage(user.Email, link, config.Env.EmailVerifyTTL))
+	return nil
+}
+
+// markEmailVerified flags the email as verified and activates users that
+// were waiting for verification.
+func markEmailVerified(db *gorm.DB, user *models.User) error {
+	updates := map[string]interface{}{
+		"is_email_verified": true,
+	}
+
+	pendingID, err := userStatusID(db, "pending_verification")
+	if err == nil && user.StatusID == pendingID {
+		activeID, err := userStatusID(db, "active")
+		if err != nil {
+			return err
+		}
+		updates["status_id"] = activeID
+	}
+
+	if err := db.Model(user).Updates(updates).Error; err != nil {
+		return err
+	}
+	user.IsEmailVerified = true
+	return nil
+}
+
+// verifyEmail confirms a user's email address
+// @Summary Verify Email
+// @Description Confirm the email address with the token from the verification email
+// @Tags AUTH
+// @Accept json
+// @Produce json
+// @Param request body schema.VerifyEmailRequest true "Verify Email Request"
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/verify-email [post]
+func verifyEmail(c *fiber.Ctx) error {
+	var req schema.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, "BODY_PARSE_ERROR", "Failed to parse request body", fiber.StatusBadRequest)
+	}
+
+	if err := helper.VLD.Struct(req); err != nil {
+		return response.Fail(c, "VALIDATION_FAILED", err.Error(), fiber.StatusBadRequest)
+	}
+
+	claims, err := jwt.ValidatePurposeToken(purposeVerifyEmail, req.Token)
+	if err != nil {
+		return response.Fail(c, "INVALID_TOKEN", "Verification token is invalid or expired", fiber.StatusBadRequest)
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", claims.Subject).First(&user).Error; err != nil || user.Email != claims.Data {
+		return response.Fail(c, "INVALID_TOKEN", "Verification token is invalid or expired", fiber.StatusBadRequest)
+	}
+
+	if !user.IsEmailVerified {
+		if err := markEmailVerified(database.DB, &user); err != nil {
+			return response.Fail(c, "DATABASE_ERROR", "Failed to verify email", fiber.StatusInternalServerError)
+		}
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Email verified successfully",
+		"user":    toUserInfo(&user),
+	}, fiber.StatusOK)
+}
+
+// resendVerification sends a new verification email
+// @Summary Resend Verification Email
+// @Description Send a new verification email to the authenticated user. Limited to one email per cooldown period.
+// @Tags AUTH
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.SWSuccessResponse
+// @Failure 400 {object} response.SWErrorResponse
+// @Failure 401 {object} response.SWErrorResponse
+// @Failure 429 {object} response.SWErrorResponse
+// @Failure 500 {object} response.SWErrorResponse
+// @Router /auth/resend-verification [post]
+func resendVerification(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var user models.User
+	if err := database.DB.Where("id = ?", claims.UserID).First(&user).Error; err != nil {
+		return response.Fail(c, "USER_NOT_FOUND", "User not found", fiber.StatusNotFound)
+	}
+	if user.IsEmailVerified {
+		return response.Fail(c, "ALREADY_VERIFIED", "Email is already verified", fiber.StatusBadRequest)
+	}
+
+	key := fmt.Sprintf("verify:resend:%d", user.ID)
+	allowed, err := cache.Default.SetNX(c.Context(), key, "1", config.Env.EmailResendDelay)
+	if err != nil {
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to send verification email", fiber.StatusInternalServerError)
+	}
+	if !allowed {
+		return response.Fail(c, "TOO_MANY_REQUESTS", "Please wait before requesting another verification email", fiber.StatusTooManyRequests)
+	}
+
+	if err := sendVerificationEmail(&user); err != nil {
+		log.Printf("resend-verification: user %d: %v", user.ID, err)
+		return response.Fail(c, "INTERNAL_ERROR", "Failed to send verification email", fiber.StatusInternalServerError)
+	}
+
+	return response.OK(c, fiber.Map{
+		"message": "Verification email sent",
+	}, fiber.StatusOK)
+}
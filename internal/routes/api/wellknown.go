@@ -0,0 +1,25 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+// SetupWellKnownRoutes registers discovery documents. They are mounted on the
+// root app because other services expect them outside of /api/v1.
+func SetupWellKnownRoutes(router *fiber.App) {
+	wellKnown := router.Group("/.well-known")
+	wellKnown.Get("/jwks.json", jwksHandler)
+}
+
+// jwksHandler publishes the public keys used to sign access tokens
+// @Summary JSON Web Key Set
+// @Description Public keys that verify access tokens, selected by the kid header. Served at /.well-known/jwks.json on the server root.
+// @Tags AUTH
+// @Produce json
+// @Success 200 {object} jwt.JWKSet
+// @Router /.well-known/jwks.json [get]
+func jwksHandler(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(jwt.JWKS())
+}
@@ -14,6 +14,9 @@ func SetupRoutes(app *fiber.App) {
 
 	api.SetupAuthRoutes(app)
 	api.SetupUserhRoutes(app)
+	api.SetupAdminRoutes(app)
+	api.SetupOrganizationRoutes(app)
+	api.SetupOAuthServerRoutes(app)
 
 }
 
@@ -21,3 +24,7 @@ func SetupRoutes(app *fiber.App) {
 func SetupMonitorRoute(app *fiber.App) {
 	app.Get("/monitoring", monitor.New())
 }
+
+func SetupWellKnownRoutes(app *fiber.App) {
+	api.SetupWellKnownRoutes(app)
+}
@@ -0,0 +1,295 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+// Prefixes of third-party app credentials and refresh tokens, so they can
+// be told apart from service accounts and picked up by secret scanners.
+const (
+	AppClientIDPrefix     = "app_"
+	AppClientSecretPrefix = "apps_"
+	AppRefreshTokenPrefix = "apr_"
+)
+
+// authorizationCodeTTL is how long an app has to redeem a code.
+const authorizationCodeTTL = time.Minute
+
+var ErrInvalidGrant = errors.New("invalid, expired or revoked authorization grant")
+
+// IsAppClient reports whether a client id belongs to a third-party app
+// rather than a service account.
+func IsAppClient(clientID string) bool {
+	return strings.HasPrefix(clientID, AppClientIDPrefix)
+}
+
+// NewAppCredentials generates a client id and, for confidential apps, a
+// secret. Only the returned hash of the secret should be stored.
+func NewAppCredentials(confidential bool) (clientID, secret, secretHash string, err error) {
+	id, err := auth.GenerateRandomToken(16)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to generate client id: %w", err)
+	}
+	if !confidential {
+		return AppClientIDPrefix + id, "", "", nil
+	}
+	secret, err = NewAppSecret()
+	if err != nil {
+		return "", "", "", err
+	}
+	return AppClientIDPrefix + id, secret, auth.HashToken(secret), nil
+}
+
+// NewAppSecret generates a client secret for a confidential app.
+func NewAppSecret() (string, error) {
+	secret, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate client secret: %w", err)
+	}
+	return AppClientSecretPrefix + secret, nil
+}
+
+// AuthenticateApp checks the credentials an app presents at the token
+// endpoint. Confidential apps must send their secret; public apps have none
+// and are only identified, PKCE protects their codes instead.
+func AuthenticateApp(db *gorm.DB, clientID, secret string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := db.Where("client_id = ?", clientID).First(&client).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidClient
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential {
+		if secret != "" {
+			return nil, ErrInvalidClient
+		}
+		return &client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+	return &client, nil
+}
+
+// AuthorizationCode is what a code stands for between consent and its
+// redemption at the token endpoint.
+type AuthorizationCode struct {
+	ClientID      string `json:"client_id"`
+	GrantID       uint   `json:"grant_id"`
+	RedirectURI   string `json:"redirect_uri"`
+	Scope         string `json:"scope"`
+	CodeChallenge string `json:"code_challenge"` // S256
+}
+
+// IssueAuthorizationCode stores the consent result and returns the code
+// the app receives on its redirect URI.
+func IssueAuthorizationCode(ctx context.Context, code AuthorizationCode) (string, error) {
+	value, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate authorization code: %w", err)
+	}
+	data, err := json.Marshal(code)
+	if err != nil {
+		return "", err
+	}
+	if err := cache.Default.Set(ctx, "oauth:code:"+auth.HashToken(value), string(data), authorizationCodeTTL); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// RedeemAuthorizationCode checks a code, which can be used once, against
+// the app, the redirect URI it was issued for and the PKCE verifier.
+func RedeemAuthorizationCode(ctx context.Context, value, clientID, redirectURI, verifier string) (*AuthorizationCode, error) {
+	key := "oauth:code:" + auth.HashToken(value)
+	data, err := cache.Default.Get(ctx, key)
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, ErrInvalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fresh, err := cache.Default.SetNX(ctx, key+":used", "1", authorizationCodeTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrInvalidGrant
+	}
+	_ = cache.Default.Delete(ctx, key)
+
+	var code AuthorizationCode
+	if err := json.Unmarshal([]byte(data), &code); err != nil {
+		return nil, ErrInvalidGrant
+	}
+	if code.ClientID != clientID || code.RedirectURI != redirectURI || !VerifyPKCE(verifier, code.CodeChallenge) {
+		return nil, ErrInvalidGrant
+	}
+	return &code, nil
+}
+
+// VerifyPKCE checks a code verifier against an S256 code challenge
+// (RFC 7636 section 4.6).
+func VerifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// IssueAppTokens issues an access token and a refresh token for scope
+// under grant. grant.User and grant.Client must be loaded. Suspended and
+// inactive users get ErrUserDisabled.
+func IssueAppTokens(db *gorm.DB, grant *models.OAuthGrant, scope string) (*TokenPair, error) {
+	if err := CheckUserActive(db, grant.UserID); err != nil {
+		return nil, err
+	}
+
+	secret, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := AppRefreshTokenPrefix + secret
+
+	now := time.Now()
+	record := models.OAuthRefreshToken{
+		GrantID:   grant.ID,
+		TokenHash: auth.HashToken(refreshToken),
+		Scopes:    scope,
+		ExpiresAt: now.Add(config.Env.JWTRefreshTTL),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	if err := db.Model(grant).Update("last_used_at", now).Error; err != nil {
+		return nil, err
+	}
+
+	accessToken, err := jwt.GenerateClientToken(grant.User.ID, grant.User.Email, grant.Client.ClientID, scope, config.Env.JWTAccessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(config.Env.JWTAccessTTL.Seconds()),
+	}, nil
+}
+
+// FindAppRefreshToken returns an active refresh token with its grant,
+// client and user.
+func FindAppRefreshToken(db *gorm.DB, refreshToken string) (*models.OAuthRefreshToken, error) {
+	var record models.OAuthRefreshToken
+	err := db.Preload("Grant.User").Preload("Grant.Client").
+		Where("token_hash = ?", auth.HashToken(refreshToken)).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.RotatedAt != nil || record.RevokedAt != nil || record.Grant.RevokedAt != nil || time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidGrant
+	}
+	return &record, nil
+}
+
+// RotateAppToken exchanges an app's refresh token for a new pair with the
+// same scope. Presenting a token that has already been rotated out revokes
+// the whole grant, as it has most likely leaked.
+func RotateAppToken(ctx context.Context, db *gorm.DB, client *models.OAuthClient, refreshToken string) (*TokenPair, *models.OAuthRefreshToken, error) {
+	var current models.OAuthRefreshToken
+	err := db.Preload("Grant.User").Preload("Grant.Client").
+		Where("token_hash = ?", auth.HashToken(refreshToken)).First(&current).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidGrant
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if current.Grant.OAuthClientID != client.ID {
+		return nil, nil, ErrInvalidGrant
+	}
+
+	if current.RotatedAt != nil {
+		if err := RevokeGrant(ctx, db, &current.Grant); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidGrant
+	}
+	if current.RevokedAt != nil || current.Grant.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return nil, nil, ErrInvalidGrant
+	}
+	if err := CheckUserActive(db, current.Grant.UserID); err != nil {
+		return nil, nil, err
+	}
+
+	var pair *TokenPair
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Only one of two concurrent refreshes with the same token wins.
+		res := tx.Model(&models.OAuthRefreshToken{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", current.ID).
+			Update("rotated_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvalidGrant
+		}
+		pair, err = IssueAppTokens(tx, &current.Grant, current.Scopes)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, &current, nil
+}
+
+// RevokeAppRefreshToken revokes one refresh token of client. Unknown
+// tokens and tokens of other apps are ignored, as RFC 7009 asks.
+func RevokeAppRefreshToken(db *gorm.DB, client *models.OAuthClient, refreshToken string) error {
+	return db.Model(&models.OAuthRefreshToken{}).
+		Where("token_hash = ? AND revoked_at IS NULL", auth.HashToken(refreshToken)).
+		Where("grant_id IN (?)", db.Model(&models.OAuthGrant{}).Select("id").Where("oauth_client_id = ?", client.ID)).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeGrant withdraws a user's consent: the app's refresh tokens are
+// revoked and its access tokens for the user are rejected from now on.
+// grant.Client must be loaded.
+func RevokeGrant(ctx context.Context, db *gorm.DB, grant *models.OAuthGrant) error {
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.OAuthRefreshToken{}).
+			Where("grant_id = ? AND revoked_at IS NULL", grant.ID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(grant).Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+	return RevokeClientTokens(ctx, grant.Client.ClientID, grant.UserID)
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	"gorm.io/gorm"
+)
+
+// Prefixes of service account credentials, so they can be picked up by
+// secret scanners.
+const (
+	ClientIDPrefix     = "sa_"
+	ClientSecretPrefix = "sas_"
+)
+
+// IsServiceAccountClient reports whether a client id belongs to a service
+// account rather than a third-party app.
+func IsServiceAccountClient(clientID string) bool {
+	return strings.HasPrefix(clientID, ClientIDPrefix)
+}
+
+var ErrInvalidClient = errors.New("invalid client credentials or disabled client")
+
+// NewClientCredentials generates a client id and secret. Only the returned
+// hash of the secret should be stored.
+func NewClientCredentials() (clientID, secret, secretHash string, err error) {
+	id, err := auth.GenerateRandomToken(16)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to generate client id: %w", err)
+	}
+	secret, err = NewClientSecret()
+	if err != nil {
+		return "", "", "", err
+	}
+	return ClientIDPrefix + id, secret, auth.HashToken(secret), nil
+}
+
+// NewClientSecret generates a client secret.
+func NewClientSecret() (string, error) {
+	secret, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate client secret: %w", err)
+	}
+	return ClientSecretPrefix + secret, nil
+}
+
+// AuthenticateClient checks service account credentials and returns the
+// account with its bot user. Use is recorded at most once per
+// lastSeenInterval.
+func AuthenticateClient(ctx context.Context, db *gorm.DB, clientID, secret string) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := db.Preload("User").Where("client_id = ?", clientID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidClient
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(account.SecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+	if account.DisabledAt != nil {
+		return nil, ErrInvalidClient
+	}
+
+	due, err := cache.Default.SetNX(ctx, fmt.Sprintf("client:seen:%d", account.ID), "1", lastSeenInterval)
+	if err == nil && due {
+		if err := db.Model(&account).Update("last_used_at", time.Now()).Error; err != nil {
+			log.Printf("session: failed to update last use of client %d: %v", account.ID, err)
+		}
+	}
+	return &account, nil
+}
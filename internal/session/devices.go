@@ -0,0 +1,105 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/mailer"
+	"gorm.io/gorm"
+)
+
+// lastSeenInterval limits how often a request updates last_seen_at.
+const lastSeenInterval = time.Minute
+
+// OnNewDevice is called in the background after a login from a device the
+// user has not used before. It emails the user by default.
+var OnNewDevice = func(user models.User, device Device) {
+	mailer.SendAsync(mailer.NewDeviceMessage(user.Email, device.UserAgent, device.IP, time.Now()))
+}
+
+// isNewDevice reports whether the device is unknown for a user who has
+// logged in before. The very first login is not reported.
+func isNewDevice(db *gorm.DB, userID uint, device Device) (bool, error) {
+	var total int64
+	if err := db.Model(&models.UserSession{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return false, err
+	}
+	if total == 0 {
+		return false, nil
+	}
+
+	query := db.Model(&models.UserSession{}).Where("user_id = ?", userID)
+	if device.ID != "" {
+		query = query.Where("device_id = ?", device.ID)
+	} else {
+		query = query.Where("user_agent = ? AND ip_address = ?", truncate(device.UserAgent, 500), device.IP)
+	}
+	var known int64
+	if err := query.Count(&known).Error; err != nil {
+		return false, err
+	}
+	return known == 0, nil
+}
+
+// touchSession records a refresh on the session, creating the record for
+// sessions that started before sessions were tracked.
+func touchSession(tx *gorm.DB, current *models.RefreshToken, device Device) error {
+	now := time.Now()
+	res := tx.Model(&models.UserSession{}).Where("id = ?", current.FamilyID).Updates(map[string]interface{}{
+		"user_agent":   truncate(device.UserAgent, 500),
+		"ip_address":   device.IP,
+		"expires_at":   now.Add(config.Env.JWTRefreshTTL),
+		"last_seen_at": now,
+	})
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+	return tx.Create(&models.UserSession{
+		ID:         current.FamilyID,
+		UserID:     current.UserID,
+		DeviceID:   device.ID,
+		UserAgent:  truncate(device.UserAgent, 500),
+		IPAddress:  device.IP,
+		ExpiresAt:  now.Add(config.Env.JWTRefreshTTL),
+		LastSeenAt: now,
+		CreatedAt:  current.CreatedAt,
+	}).Error
+}
+
+// Touch reports whether the session is still alive and records activity
+// on it at most once per lastSeenInterval.
+func Touch(ctx context.Context, db *gorm.DB, sessionID string) (bool, error) {
+	var record models.UserSession
+	err := db.Select("id", "revoked_at", "expires_at").Where("id = ?", sessionID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if record.RevokedAt != nil || time.Now().After(record.ExpiresAt) {
+		return false, nil
+	}
+
+	due, err := cache.Default.SetNX(ctx, "session:seen:"+sessionID, "1", lastSeenInterval)
+	if err == nil && due {
+		if err := db.Model(&record).Update("last_seen_at", time.Now()).Error; err != nil {
+			log.Printf("session: failed to update last seen for %s: %v", sessionID, err)
+		}
+	}
+	return true, nil
+}
+
+// Active returns the user's sessions that can still be refreshed, most
+// recently used first.
+func Active(db *gorm.DB, userID uint) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	err := db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
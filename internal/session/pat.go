@@ -0,0 +1,99 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	pkgjwt "github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+// PATPrefix marks personal access tokens so they can be told apart from
+// JWTs and picked up by secret scanners.
+const PATPrefix = "pat_"
+
+var ErrInvalidPAT = errors.New("invalid, expired or revoked personal access token")
+
+// IsPAT reports whether a bearer token is a personal access token.
+func IsPAT(token string) bool {
+	return strings.HasPrefix(token, PATPrefix)
+}
+
+// CreatePAT stores a new personal access token and returns the record and
+// the plaintext token, which cannot be recovered later.
+func CreatePAT(db *gorm.DB, userID uint, name string, scopes []string, expiresAt *time.Time) (*models.PersonalAccessToken, string, error) {
+	secret, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := PATPrefix + secret
+
+	record := models.PersonalAccessToken{
+		UserID:      userID,
+		Name:        name,
+		TokenPrefix: token[:len(PATPrefix)+8],
+		TokenHash:   auth.HashToken(token),
+		Scopes:      strings.Join(scopes, " "),
+		ExpiresAt:   expiresAt,
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to store token: %w", err)
+	}
+	return &record, token, nil
+}
+
+// AuthenticatePAT resolves a personal access token to claims carrying the
+// token's scopes, and records its use at most once per lastSeenInterval.
+// Tokens of suspended or inactive users fail with ErrUserDisabled.
+func AuthenticatePAT(ctx context.Context, db *gorm.DB, token string) (*pkgjwt.Claims, error) {
+	var record models.PersonalAccessToken
+	err := db.Preload("User").Where("token_hash = ?", auth.HashToken(token)).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidPAT
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.RevokedAt != nil || (record.ExpiresAt != nil && time.Now().After(*record.ExpiresAt)) {
+		return nil, ErrInvalidPAT
+	}
+	if err := CheckUserActive(db, record.UserID); err != nil {
+		return nil, err
+	}
+
+	due, err := cache.Default.SetNX(ctx, fmt.Sprintf("pat:seen:%d", record.ID), "1", lastSeenInterval)
+	if err == nil && due {
+		if err := db.Model(&record).Update("last_used_at", time.Now()).Error; err != nil {
+			log.Printf("session: failed to update last use of token %d: %v", record.ID, err)
+		}
+	}
+
+	claims := &pkgjwt.Claims{
+		UserID: record.UserID,
+		Email:  record.User.Email,
+		Scope:  record.Scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  fmt.Sprintf("%d", record.UserID),
+			IssuedAt: jwt.NewNumericDate(record.CreatedAt),
+		},
+	}
+	if record.ExpiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*record.ExpiresAt)
+	}
+	return claims, nil
+}
+
+// RevokeUserPATs revokes every personal access token of a user
+func RevokeUserPATs(db *gorm.DB, userID uint) error {
+	return db.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
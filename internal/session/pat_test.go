@@ -0,0 +1,70 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+)
+
+func TestAuthenticatePAT(t *testing.T) {
+	db, user := setupDB(t)
+	ctx := context.Background()
+
+	_, token, err := CreatePAT(db, user.ID, "ci", []string{"project:read", "ticket:write"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsPAT(token) {
+		t.Fatalf("token %q lacks the %s prefix", token, PATPrefix)
+	}
+
+	claims, err := AuthenticatePAT(ctx, db, token)
+	if err != nil {
+		t.Fatalf("AuthenticatePAT: %v", err)
+	}
+	if claims.UserID != user.ID || !claims.HasScope("ticket:write") || claims.HasScope("org:write") {
+		t.Fatalf("claims = %+v", claims)
+	}
+
+	if err := RevokeUserPATs(db, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AuthenticatePAT(ctx, db, token); !errors.Is(err, ErrInvalidPAT) {
+		t.Fatalf("revoked token: err = %v, want ErrInvalidPAT", err)
+	}
+}
+
+func TestAuthenticatePATRefused(t *testing.T) {
+	db, user := setupDB(t)
+	ctx := context.Background()
+
+	expired := time.Now().Add(-time.Minute)
+	_, token, err := CreatePAT(db, user.ID, "old", []string{"project:read"}, &expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AuthenticatePAT(ctx, db, token); !errors.Is(err, ErrInvalidPAT) {
+		t.Fatalf("expired token: err = %v, want ErrInvalidPAT", err)
+	}
+	if _, err := AuthenticatePAT(ctx, db, PATPrefix+"unknown"); !errors.Is(err, ErrInvalidPAT) {
+		t.Fatalf("unknown token: err = %v, want ErrInvalidPAT", err)
+	}
+
+	_, token, err = CreatePAT(db, user.ID, "ci", []string{"project:read"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suspended models.UserStatus
+	if err := db.Where("name = ?", "suspended").First(&suspended).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(user).Update("status_id", suspended.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AuthenticatePAT(ctx, db, token); !errors.Is(err, ErrUserDisabled) {
+		t.Fatalf("token of a suspended user: err = %v, want ErrUserDisabled", err)
+	}
+}
@@ -0,0 +1,138 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+// Denylist entries only have to outlive the access tokens they cover, so
+// every key expires after at most one access token lifetime.
+
+func revokedTokenKey(jti string) string   { return "revoked:jti:" + jti }
+func revokedSessionKey(sid string) string { return "revoked:sid:" + sid }
+func revokedUserKey(userID uint) string   { return fmt.Sprintf("revoked:user:%d", userID) }
+func revokedClientKey(clientID string, userID uint) string {
+	return fmt.Sprintf("revoked:client:%s:%d", clientID, userID)
+}
+
+// RevokeAccessToken puts a single access token on the denylist until it expires.
+func RevokeAccessToken(ctx context.Context, claims *jwt.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return cache.Default.Set(ctx, revokedTokenKey(claims.ID), "1", ttl)
+}
+
+// RevokeSession ends a login: its refresh tokens are revoked and access
+// tokens already issued for it are rejected from now on.
+func RevokeSession(ctx context.Context, db *gorm.DB, sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	if err := RevokeFamily(db, sessionID); err != nil {
+		return err
+	}
+	return cache.Default.Set(ctx, revokedSessionKey(sessionID), "1", config.Env.JWTAccessTTL)
+}
+
+// RevokeAllSessions ends every login of a user, except the ones listed in keep.
+// Without keep, e.g. on logout-all, password reset or suspension, the
+// user's personal access tokens are revoked as well.
+func RevokeAllSessions(ctx context.Context, db *gorm.DB, userID uint, keep ...string) error {
+	query := db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now())
+	if len(keep) > 0 {
+		query = query.Where("family_id NOT IN ?", keep)
+	}
+
+	var families []string
+	if err := query.Distinct("family_id").Pluck("family_id", &families).Error; err != nil {
+		return err
+	}
+	for _, family := range families {
+		if err := RevokeSession(ctx, db, family); err != nil {
+			return err
+		}
+	}
+
+	// Tokens that are not tied to a session can only be caught by issue time.
+	// Impersonation tokens may outlive regular access tokens.
+	if len(keep) == 0 {
+		if err := RevokeUserPATs(db, userID); err != nil {
+			return err
+		}
+		return cache.Default.Set(ctx, revokedUserKey(userID), revocationCutoff(), max(config.Env.JWTAccessTTL, config.Env.ImpersonationTTL))
+	}
+	return nil
+}
+
+// RevokeClientTokens rejects every access token issued to an API client for
+// the user so far, e.g. when the user revokes an app.
+func RevokeClientTokens(ctx context.Context, clientID string, userID uint) error {
+	return cache.Default.Set(ctx, revokedClientKey(clientID, userID), revocationCutoff(), config.Env.JWTAccessTTL)
+}
+
+// IsRevoked reports whether an access token has been revoked directly,
+// through its session, by revoking its client, or by a logout-all of its
+// user.
+func IsRevoked(ctx context.Context, claims *jwt.Claims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := cache.Default.Exists(ctx, revokedTokenKey(claims.ID))
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	if claims.SessionID != "" {
+		revoked, err := cache.Default.Exists(ctx, revokedSessionKey(claims.SessionID))
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	if claims.ClientID != "" {
+		revoked, err := issuedBeforeCutoff(ctx, revokedClientKey(claims.ClientID, claims.UserID), claims)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	return issuedBeforeCutoff(ctx, revokedUserKey(claims.UserID), claims)
+}
+
+// revocationCutoff is the issue time up to which tokens are revoked: the
+// current second. Issue times only have second precision, so a token
+// issued in the same second as the revocation is revoked too, even if it
+// was issued just after it.
+func revocationCutoff() string {
+	return strconv.FormatInt(time.Now().Unix(), 10)
+}
+
+// issuedBeforeCutoff reports whether the token was issued no later than
+// the revocation cutoff stored under key, if any.
+func issuedBeforeCutoff(ctx context.Context, key string, claims *jwt.Claims) (bool, error) {
+	cutoff, err := cache.Default.Get(ctx, key)
+	if err == cache.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	revokedUntil, err := strconv.ParseInt(cutoff, 10, 64)
+	if err != nil || claims.IssuedAt == nil {
+		return true, nil
+	}
+	return claims.IssuedAt.Unix() <= revokedUntil, nil
+}
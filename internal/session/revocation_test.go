@@ -0,0 +1,83 @@
+package session
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	pkgjwt "github.com/phonsing-Hub/GoLang/pkg/jwt"
+)
+
+func issuedAt(userID uint, clientID string, at time.Time) *pkgjwt.Claims {
+	return &pkgjwt.Claims{
+		UserID:           userID,
+		ClientID:         clientID,
+		RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(at)},
+	}
+}
+
+func TestRevocationCutoffBoundary(t *testing.T) {
+	cache.Default = cache.NewMemoryStore()
+	ctx := context.Background()
+
+	// Revoked at the start of a second, so tokens issued later in the same
+	// second round down to the cutoff
+	cutoff := time.Now().Truncate(time.Second)
+	if err := cache.Default.Set(ctx, revokedUserKey(1), strconv.FormatInt(cutoff.Unix(), 10), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		revoked bool
+	}{
+		{"issued a second earlier", cutoff.Add(-time.Second), true},
+		{"issued in the same second", cutoff.Add(900 * time.Millisecond), true},
+		{"issued the next second", cutoff.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		revoked, err := IsRevoked(ctx, issuedAt(1, "", tt.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != tt.revoked {
+			t.Errorf("%s: revoked = %t, want %t", tt.name, revoked, tt.revoked)
+		}
+	}
+}
+
+func TestRevokeClientTokens(t *testing.T) {
+	cache.Default = cache.NewMemoryStore()
+	config.Env = &config.Config{JWTAccessTTL: time.Minute}
+	ctx := context.Background()
+
+	issued := time.Now()
+	if err := RevokeClientTokens(ctx, "app", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		claims  *pkgjwt.Claims
+		revoked bool
+	}{
+		{"token of the client", issuedAt(1, "app", issued), true},
+		{"token of another client", issuedAt(1, "other", issued), false},
+		{"token of another user", issuedAt(2, "app", issued), false},
+		{"token issued after", issuedAt(1, "app", issued.Add(2*time.Second)), false},
+	}
+	for _, tt := range tests {
+		revoked, err := IsRevoked(ctx, tt.claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != tt.revoked {
+			t.Errorf("%s: revoked = %t, want %t", tt.name, revoked, tt.revoked)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrDeviceMismatch      = errors.New("refresh token was issued to another device")
+)
+
+// Device describes the client a token pair is issued to.
+type Device struct {
+	ID        string
+	UserAgent string
+	IP        string
+}
+
+// TokenPair is a short-lived access token plus the opaque refresh token
+// that can be exchanged for the next pair.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+	SessionID    string
+}
+
+// Issue starts a new session for the user and returns its first pair.
+// OnNewDevice is called when the user has not logged in from the device
+// before.
+func Issue(db *gorm.DB, user *models.User, device Device) (*TokenPair, error) {
+	newDevice, err := isNewDevice(db, user.ID, device)
+	if err != nil {
+		return nil, err
+	}
+
+	var pair *TokenPair
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		record := models.UserSession{
+			ID:         uuid.NewString(),
+			UserID:     user.ID,
+			DeviceID:   device.ID,
+			UserAgent:  truncate(device.UserAgent, 500),
+			IPAddress:  device.IP,
+			ExpiresAt:  now.Add(config.Env.JWTRefreshTTL),
+			LastSeenAt: now,
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to store session: %w", err)
+		}
+		pair, err = issue(tx, user, record.ID, device)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if newDevice && OnNewDevice != nil {
+		go OnNewDevice(*user, device)
+	}
+	return pair, nil
+}
+
+// Rotate exchanges a refresh token for a new pair in the same family.
+// Presenting a token that has already been rotated out revokes the family,
+// as does refreshing for a suspended or inactive user.
+func Rotate(db *gorm.DB, refreshToken string, device Device) (*TokenPair, *models.User, error) {
+	var current models.RefreshToken
+	err := db.Preload("User").Where("token_hash = ?", auth.HashToken(refreshToken)).First(&current).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	if current.RotatedAt != nil {
+		if err := RevokeFamily(db, current.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+	if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if current.DeviceID != "" && current.DeviceID != device.ID {
+		if err := RevokeFamily(db, current.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrDeviceMismatch
+	}
+	if err := CheckUserActive(db, current.UserID); err != nil {
+		if errors.Is(err, ErrUserDisabled) {
+			if err := RevokeFamily(db, current.FamilyID); err != nil {
+				return nil, nil, err
+			}
+		}
+		return nil, nil, err
+	}
+
+	var pair *TokenPair
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Guard against two concurrent refreshes with the same token: only
+		// one of them may flip rotated_at, the other is treated as reuse.
+		res := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", current.ID).
+			Update("rotated_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		if device.ID == "" {
+			device.ID = current.DeviceID
+		}
+		if err := touchSession(tx, &current, device); err != nil {
+			return err
+		}
+		pair, err = issue(tx, &current.User, current.FamilyID, device)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if err := RevokeFamily(db, current.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, &current.User, nil
+}
+
+// RevokeFamily revokes every refresh token issued from the same login
+// together with its session record.
+func RevokeFamily(db *gorm.DB, familyID string) error {
+	now := time.Now()
+	if err := db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return db.Model(&models.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+func issue(db *gorm.DB, user *models.User, familyID string, device Device) (*TokenPair, error) {
+	refreshToken, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: auth.HashToken(refreshToken),
+		DeviceID:  device.ID,
+		UserAgent: truncate(device.UserAgent, 500),
+		IPAddress: device.IP,
+		ExpiresAt: time.Now().Add(config.Env.JWTRefreshTTL),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	accessToken, err := jwt.GenerateToken(user.ID, user.Email, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(config.Env.JWTAccessTTL.Seconds()),
+		SessionID:    familyID,
+	}, nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
+
+// AuthTime returns when the user logged in to start the session, i.e. when
+// the first refresh token of the family was issued.
+func AuthTime(db *gorm.DB, familyID string) (time.Time, error) {
+	var first models.RefreshToken
+	err := db.Where("family_id = ?", familyID).Order("created_at").First(&first).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+	return first.CreatedAt, nil
+}
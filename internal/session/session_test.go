@@ -0,0 +1,82 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database/dbtest"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"gorm.io/gorm"
+)
+
+// setupDB returns the test database and a new user, with tokens signed
+// with a test secret.
+func setupDB(t *testing.T) (*gorm.DB, *models.User) {
+	t.Helper()
+	db := dbtest.Open(t)
+	cache.Default = cache.NewMemoryStore()
+	config.Env = &config.Config{JWTSecret: "test-secret", JWTAccessTTL: time.Minute, JWTRefreshTTL: time.Hour}
+	OnNewDevice = nil
+
+	user := &models.User{Email: dbtest.Unique("jane") + "@example.com"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db, user
+}
+
+func TestRotateReuseRevokesFamily(t *testing.T) {
+	db, user := setupDB(t)
+	device := Device{ID: "laptop"}
+
+	first, err := Issue(db, user, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := Rotate(db, first.RefreshToken, device)
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if second.SessionID != first.SessionID || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("rotation did not issue a new token in the same family: %+v", second)
+	}
+
+	// Presenting the rotated token again looks like theft
+	if _, _, err := Rotate(db, first.RefreshToken, device); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuse: err = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, _, err := Rotate(db, second.RefreshToken, device); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("latest token after reuse: err = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	var live int64
+	db.Model(&models.RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", first.SessionID).Count(&live)
+	if live != 0 {
+		t.Fatalf("%d refresh tokens of the family are still live", live)
+	}
+	var record models.UserSession
+	if err := db.First(&record, "id = ?", first.SessionID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if record.RevokedAt == nil {
+		t.Fatal("the session was not revoked")
+	}
+}
+
+func TestRotateOtherDeviceRevokesFamily(t *testing.T) {
+	db, user := setupDB(t)
+
+	pair, err := Issue(db, user, Device{ID: "laptop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Rotate(db, pair.RefreshToken, Device{ID: "phone"}); !errors.Is(err, ErrDeviceMismatch) {
+		t.Fatalf("err = %v, want ErrDeviceMismatch", err)
+	}
+	if _, _, err := Rotate(db, pair.RefreshToken, Device{ID: "laptop"}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("after a mismatch: err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
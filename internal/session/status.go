@@ -0,0 +1,31 @@
+package session
+
+import (
+	"errors"
+
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"gorm.io/gorm"
+)
+
+var ErrUserDisabled = errors.New("user is suspended or inactive")
+
+// disabledStatuses are the user statuses that may not hold tokens. Users
+// pending email verification can still sign in.
+var disabledStatuses = []string{"suspended", "inactive"}
+
+// CheckUserActive returns ErrUserDisabled when the user may not hold
+// tokens of any kind.
+func CheckUserActive(db *gorm.DB, userID uint) error {
+	var count int64
+	err := db.Model(&models.User{}).
+		Joins("JOIN user_statuses ON user_statuses.id = users.status_id").
+		Where("users.id = ? AND user_statuses.name IN ?", userID, disabledStatuses).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserDisabled
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+// Package settings defines the typed settings documents of organizations
+// and the per-project overrides of them. Documents are stored as JSON with a
+// version number and are migrated to the current version when read.
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Version is the version of the documents written by this code.
+const Version = 2
+
+// TicketStatus is a status new projects start out with.
+type TicketStatus struct {
+	Name  string `json:"name" validate:"required,max=100"`
+	Color string `json:"color,omitempty" validate:"omitempty,hexcolor"`
+}
+
+// WorkingHours are the hours the team works, used for SLAs and reports.
+type WorkingHours struct {
+	Timezone string   `json:"timezone" validate:"required,timezone"`
+	Days     []string `json:"days" validate:"required,min=1,unique,dive,oneof=mon tue wed thu fri sat sun"`
+	Start    string   `json:"start" validate:"required,datetime=15:04"`
+	End      string   `json:"end" validate:"required,datetime=15:04"`
+}
+
+// Check reports rules the validator tags cannot express.
+func (w *WorkingHours) Check() error {
+	start, errStart := time.Parse("15:04", w.Start)
+	end, errEnd := time.Parse("15:04", w.End)
+	if errStart == nil && errEnd == nil && !end.After(start) {
+		return errors.New("working_hours.end must be after working_hours.start")
+	}
+	return nil
+}
+
+// Organization holds the settings of an organization.
+type Organization struct {
+	Version               int            `json:"version"`
+	DefaultProjectType    string         `json:"default_project_type" validate:"oneof=kanban scrum"`
+	AllowedEmailDomains   []string       `json:"allowed_email_domains" validate:"max=50,dive,fqdn"` // empty allows any domain
+	Require2FA            bool           `json:"require_2fa"`
+	MagicLinkEnabled      bool           `json:"magic_link_enabled"`
+	DefaultTicketStatuses []TicketStatus `json:"default_ticket_statuses" validate:"min=1,max=20,dive"`
+	WorkingHours          WorkingHours   `json:"working_hours"`
+}
+
+// Check reports rules the validator tags cannot express.
+func (o *Organization) Check() error {
+	return o.WorkingHours.Check()
+}
+
+// EmailAllowed reports whether email belongs to one of the allowed domains.
+func (o *Organization) EmailAllowed(email string) bool {
+	if len(o.AllowedEmailDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	return slices.ContainsFunc(o.AllowedEmailDomains, func(domain string) bool {
+		return strings.EqualFold(domain, email[at+1:])
+	})
+}
+
+// Defaults returns the settings of an organization that never changed them.
+func Defaults() Organization {
+	return Organization{
+		Version:             Version,
+		DefaultProjectType:  "kanban",
+		AllowedEmailDomains: []string{},
+		DefaultTicketStatuses: []TicketStatus{
+			{Name: "To Do", Color: "#6c757d"},
+			{Name: "In Progress", Color: "#007bff"},
+			{Name: "Done", Color: "#28a745"},
+		},
+		WorkingHours: WorkingHours{
+			Timezone: "UTC",
+			Days:     []string{"mon", "tue", "wed", "thu", "fri"},
+			Start:    "09:00",
+			End:      "17:00",
+		},
+	}
+}
+
+// ProjectOverrides holds the settings a project sets itself. Nil fields
+// are inherited from the organization.
+type ProjectOverrides struct {
+	ProjectType           *string        `json:"project_type,omitempty" validate:"omitempty,oneof=kanban scrum"`
+	DefaultTicketStatuses []TicketStatus `json:"default_ticket_statuses,omitempty" validate:"omitempty,min=1,max=20,dive"`
+	WorkingHours          *WorkingHours  `json:"working_hours,omitempty"`
+}
+
+// Check reports rules the validator tags cannot express.
+func (p *ProjectOverrides) Check() error {
+	if p.WorkingHours != nil {
+		return p.WorkingHours.Check()
+	}
+	return nil
+}
+
+// Project holds the effective settings of a project.
+type Project struct {
+	ProjectType           string         `json:"project_type"`
+	DefaultTicketStatuses []TicketStatus `json:"default_ticket_statuses"`
+	WorkingHours          WorkingHours   `json:"working_hours"`
+}
+
+// Effective applies a project's overrides to its organization's settings.
+// Personal projects pass Defaults().
+func Effective(org Organization, overrides ProjectOverrides) Project {
+	project := Project{
+		ProjectType:           org.DefaultProjectType,
+		DefaultTicketStatuses: org.DefaultTicketStatuses,
+		WorkingHours:          org.WorkingHours,
+	}
+	if overrides.ProjectType != nil {
+		project.ProjectType = *overrides.ProjectType
+	}
+	if len(overrides.DefaultTicketStatuses) > 0 {
+		project.DefaultTicketStatuses = overrides.DefaultTicketStatuses
+	}
+	if overrides.WorkingHours != nil {
+		project.WorkingHours = *overrides.WorkingHours
+	}
+	return project
+}
+
+// migrations upgrade a document from the version they are keyed by to the
+// next one.
+var migrations = map[int]func(doc map[string]json.RawMessage) map[string]json.RawMessage{
+	// Version 1 documents had no version key and were free-form; only
+	// magic_link_enabled was ever read.
+	1: func(doc map[string]json.RawMessage) map[string]json.RawMessage {
+		migrated := map[string]json.RawMessage{}
+		if value, ok := doc["magic_link_enabled"]; ok {
+			migrated["magic_link_enabled"] = value
+		}
+		return migrated
+	},
+}
+
+// ParseOrganization reads a stored settings document, migrating documents
+// written with an older version. Keys it does not set keep their defaults.
+func ParseOrganization(raw string) (Organization, error) {
+	if raw == "" || raw == "null" {
+		return Defaults(), nil
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return Organization{}, fmt.Errorf("settings: malformed document: %w", err)
+	}
+	version := 1
+	if value, ok := doc["version"]; ok {
+		if err := json.Unmarshal(value, &version); err != nil {
+			return Organization{}, fmt.Errorf("settings: malformed version: %w", err)
+		}
+	}
+	if version < 1 {
+		return Organization{}, fmt.Errorf("settings: invalid document version %d", version)
+	}
+	if version > Version {
+		return Organization{}, fmt.Errorf("settings: document version %d is newer than %d", version, Version)
+	}
+	for ; version < Version; version++ {
+		doc = migrations[version](doc)
+	}
+	delete(doc, "version")
+
+	settings := Defaults()
+	if err := decode(doc, &settings); err != nil {
+		return Organization{}, err
+	}
+	return settings, nil
+}
+
+// ParseProject reads the stored overrides of a project.
+func ParseProject(raw string) (ProjectOverrides, error) {
+	var overrides ProjectOverrides
+	if raw == "" || raw == "null" {
+		return overrides, nil
+	}
+	if err := strictUnmarshal([]byte(raw), &overrides); err != nil {
+		return ProjectOverrides{}, err
+	}
+	return overrides, nil
+}
+
+// PatchOrganization applies a JSON merge patch to settings. Nested objects
+// are merged, keys set to null go back to their default and unknown keys
+// are rejected. The result
+// still has to be validated.
+func PatchOrganization(settings Organization, patch []byte) (Organization, error) {
+	result := Defaults()
+	if err := applyPatch(settings, patch, &Organization{}, &result); err != nil {
+		return Organization{}, err
+	}
+	result.Version = Version
+	return result, nil
+}
+
+// PatchProject applies a JSON merge patch to a project's overrides. Nested
+// objects are merged, keys set to null are inherited again and unknown
+// keys are rejected. The result
+// still has to be validated.
+func PatchProject(overrides ProjectOverrides, patch []byte) (ProjectOverrides, error) {
+	var result ProjectOverrides
+	if err := applyPatch(overrides, patch, &ProjectOverrides{}, &result); err != nil {
+		return ProjectOverrides{}, err
+	}
+	return result, nil
+}
+
+// Encode returns the document to store.
+func Encode(document any) (string, error) {
+	raw, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("settings: %w", err)
+	}
+	return string(raw), nil
+}
+
+// applyPatch merges patch into current as RFC 7386 describes and decodes
+// the result into dst. scratch is a zero value of dst's type; decoding the
+// patch alone into it catches unknown keys that are set to null.
+func applyPatch(current any, patch []byte, scratch, dst any) error {
+	changes, err := unmarshalValue(patch)
+	if _, ok := changes.(map[string]any); err != nil || !ok {
+		return errors.New("settings: patch must be a JSON object")
+	}
+	if err := strictUnmarshal(patch, scratch); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	doc, err := unmarshalValue(raw)
+	if err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	merged, err := json.Marshal(mergePatch(doc, changes))
+	if err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	return strictUnmarshal(merged, dst)
+}
+
+// mergePatch applies patch to target. Objects are merged key by key at
+// every level and a null removes the key; any other value, arrays
+// included, replaces the target.
+func mergePatch(target, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	doc, ok := target.(map[string]any)
+	if !ok {
+		doc = map[string]any{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(doc, key)
+		} else {
+			doc[key] = mergePatch(doc[key], value)
+		}
+	}
+	return doc
+}
+
+// unmarshalValue decodes any JSON value, keeping numbers as written.
+func unmarshalValue(raw []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func decode(doc map[string]json.RawMessage, dst any) error {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	return strictUnmarshal(raw, dst)
+}
+
+func strictUnmarshal(raw []byte, dst any) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	return nil
+}
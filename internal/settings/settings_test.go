@@ -0,0 +1,106 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOrganizationVersions(t *testing.T) {
+	tests := []struct {
+		raw       string
+		magicLink bool
+		wantErr   bool
+	}{
+		{raw: `{"magic_link_enabled": true, "theme": "dark"}`, magicLink: true},
+		{raw: `{"version": 1, "magic_link_enabled": true}`, magicLink: true},
+		{raw: `{"version": 2, "magic_link_enabled": true}`, magicLink: true},
+		{raw: `{"version": 0}`, wantErr: true},
+		{raw: `{"version": -1}`, wantErr: true},
+		{raw: `{"version": 3}`, wantErr: true},
+		{raw: `{"version": "2"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseOrganization(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseOrganization(%s): want an error", tt.raw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseOrganization(%s): %v", tt.raw, err)
+			continue
+		}
+		if got.MagicLinkEnabled != tt.magicLink {
+			t.Errorf("ParseOrganization(%s): magic_link_enabled = %t, want %t", tt.raw, got.MagicLinkEnabled, tt.magicLink)
+		}
+	}
+}
+
+func TestPatchOrganizationMergesNestedObjects(t *testing.T) {
+	current := Defaults()
+	current.WorkingHours = WorkingHours{Timezone: "Asia/Bangkok", Days: []string{"mon", "tue"}, Start: "10:00", End: "19:00"}
+	current.MagicLinkEnabled = true
+
+	got, err := PatchOrganization(current, []byte(`{"working_hours": {"start": "08:00"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WorkingHours{Timezone: "Asia/Bangkok", Days: []string{"mon", "tue"}, Start: "08:00", End: "19:00"}
+	if !reflect.DeepEqual(got.WorkingHours, want) {
+		t.Errorf("working_hours = %+v, want %+v", got.WorkingHours, want)
+	}
+	if !got.MagicLinkEnabled {
+		t.Error("magic_link_enabled was reset by a patch that did not mention it")
+	}
+}
+
+func TestPatchOrganizationNestedNull(t *testing.T) {
+	current := Defaults()
+	current.WorkingHours = WorkingHours{Timezone: "Asia/Bangkok", Days: []string{"sat"}, Start: "10:00", End: "19:00"}
+
+	got, err := PatchOrganization(current, []byte(`{"working_hours": {"days": null}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WorkingHours{Timezone: "Asia/Bangkok", Days: Defaults().WorkingHours.Days, Start: "10:00", End: "19:00"}
+	if !reflect.DeepEqual(got.WorkingHours, want) {
+		t.Errorf("working_hours = %+v, want %+v", got.WorkingHours, want)
+	}
+}
+
+func TestPatchProject(t *testing.T) {
+	kanban := "kanban"
+	current := ProjectOverrides{
+		ProjectType:  &kanban,
+		WorkingHours: &WorkingHours{Timezone: "UTC", Days: []string{"mon"}, Start: "09:00", End: "17:00"},
+	}
+
+	got, err := PatchProject(current, []byte(`{"working_hours": {"end": "18:00", "timezone": null}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &WorkingHours{Days: []string{"mon"}, Start: "09:00", End: "18:00"}
+	if !reflect.DeepEqual(got.WorkingHours, want) {
+		t.Errorf("working_hours = %+v, want %+v", got.WorkingHours, want)
+	}
+	if got.ProjectType == nil || *got.ProjectType != kanban {
+		t.Errorf("project_type = %v, want it kept", got.ProjectType)
+	}
+
+	got, err = PatchProject(current, []byte(`{"working_hours": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.WorkingHours != nil {
+		t.Errorf("working_hours = %+v, want it inherited again", got.WorkingHours)
+	}
+}
+
+func TestPatchRejects(t *testing.T) {
+	for _, patch := range []string{`[]`, `null`, `"x"`, `{"unknown": 1}`, `{"working_hours": {"lunch": "12:00"}}`} {
+		if _, err := PatchOrganization(Defaults(), []byte(patch)); err == nil {
+			t.Errorf("PatchOrganization(%s): want an error", patch)
+		}
+	}
+}
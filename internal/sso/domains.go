@@ -0,0 +1,59 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+)
+
+// verificationPrefix starts the value of the TXT record that proves
+// control of a domain.
+const verificationPrefix = "sso-domain-verification="
+
+// lookupTXT resolves TXT records; tests replace it.
+var lookupTXT = net.DefaultResolver.LookupTXT
+
+// NewVerificationToken generates the token an organization publishes to
+// verify a domain.
+func NewVerificationToken() (string, error) {
+	return auth.GenerateRandomToken(24)
+}
+
+// VerificationRecord is the name and value of the TXT record that verifies
+// domain with token.
+func VerificationRecord(domain, token string) (name, value string) {
+	return "_sso-verification." + domain, verificationPrefix + token
+}
+
+// VerifyDomain reports whether the verification record for token is
+// published. A missing record is not an error.
+func VerifyDomain(ctx context.Context, domain, token string) (bool, error) {
+	name, value := VerificationRecord(domain, token)
+	records, err := lookupTXT(ctx, name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, record := range records {
+		if strings.TrimSpace(record) == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// EmailDomain returns the lower case domain of an email address, or ""
+// when it has none.
+func EmailDomain(email string) string {
+	_, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok {
+		return ""
+	}
+	return domain
+}
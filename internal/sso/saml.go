@@ -0,0 +1,288 @@
+package sso
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crewjam/saml"
+	xrv "github.com/mattermost/xml-roundtrip-validator"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"github.com/phonsing-Hub/GoLang/internal/cache"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/pkg/auth"
+)
+
+var (
+	ErrInvalidMetadata   = errors.New("sso: invalid identity provider metadata")
+	ErrInvalidRelayState = errors.New("sso: invalid or expired relay state")
+	ErrInvalidResponse   = errors.New("sso: invalid SAML response")
+	ErrMissingEmail      = errors.New("sso: assertion has no email address")
+)
+
+// flowTTL is how long a user has to finish the login at the identity provider.
+const flowTTL = 10 * time.Minute
+
+// Identity is the asserted user mapped onto User fields.
+type Identity struct {
+	Subject     string
+	Email       string
+	FirstName   string
+	LastName    string
+	DisplayName string
+}
+
+// AttributeMapping names the SAML attributes that hold each User field.
+// Empty fields fall back to the common names used by Okta, Azure AD and
+// Google Workspace.
+type AttributeMapping struct {
+	Email       string `json:"email,omitempty"`
+	FirstName   string `json:"first_name,omitempty"`
+	LastName    string `json:"last_name,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+var defaultAttributes = map[string][]string{
+	"email": {"email", "mail", "emailAddress",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+		"urn:oid:0.9.2342.19200300.100.1.3"},
+	"first_name": {"firstName", "givenName", "first_name",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname",
+		"urn:oid:2.5.4.42"},
+	"last_name": {"lastName", "sn", "surname", "last_name",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/surname",
+		"urn:oid:2.5.4.4"},
+	"display_name": {"displayName", "name",
+		"http://schemas.microsoft.com/identity/claims/displayname",
+		"urn:oid:2.16.840.1.113730.3.1.241"},
+}
+
+// ParseMetadata parses identity provider metadata, which is either an
+// EntityDescriptor or an EntitiesDescriptor wrapping one.
+func ParseMetadata(data []byte) (*saml.EntityDescriptor, error) {
+	if err := xrv.Validate(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
+	var entity saml.EntityDescriptor
+	if err := xml.Unmarshal(data, &entity); err == nil {
+		if len(entity.IDPSSODescriptors) == 0 {
+			return nil, fmt.Errorf("%w: no IDPSSODescriptor", ErrInvalidMetadata)
+		}
+		return &entity, nil
+	}
+
+	var entities saml.EntitiesDescriptor
+	if err := xml.Unmarshal(data, &entities); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+	for i, e := range entities.EntityDescriptors {
+		if len(e.IDPSSODescriptors) > 0 {
+			return &entities.EntityDescriptors[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no IDPSSODescriptor", ErrInvalidMetadata)
+}
+
+// MetadataURL is the SP entity ID and metadata location of an organization.
+func MetadataURL(orgSlug string) string {
+	return fmt.Sprintf("%s/auth/sso/saml/%s/metadata", strings.TrimRight(config.Env.APIBaseURL, "/"), url.PathEscape(orgSlug))
+}
+
+// ACSURL is where the identity provider posts its response.
+func ACSURL(orgSlug string) string {
+	return fmt.Sprintf("%s/auth/sso/saml/%s/acs", strings.TrimRight(config.Env.APIBaseURL, "/"), url.PathEscape(orgSlug))
+}
+
+// spKeyPair is the optional key pair from SAML_SP_CERT_FILE and
+// SAML_SP_KEY_FILE.
+var spKeyPair = sync.OnceValues(func() (*tls.Certificate, error) {
+	if config.Env.SAMLSPCertFile == "" || config.Env.SAMLSPKeyFile == "" {
+		return nil, nil
+	}
+	pair, err := tls.LoadX509KeyPair(config.Env.SAMLSPCertFile, config.Env.SAMLSPKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SAML service provider key pair: %w", err)
+	}
+	return &pair, nil
+})
+
+// ServiceProvider builds the SAML service provider of an organization.
+func ServiceProvider(org *models.Organization, cfg *models.OrganizationSSO) (*saml.ServiceProvider, error) {
+	idp, err := ParseMetadata([]byte(cfg.IdPMetadata))
+	if err != nil {
+		return nil, err
+	}
+	metadataURL, err := url.Parse(MetadataURL(org.Slug))
+	if err != nil {
+		return nil, err
+	}
+	acsURL, err := url.Parse(ACSURL(org.Slug))
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idp,
+		AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+	}
+
+	pair, err := spKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	if pair != nil {
+		key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("SAML service provider key must be an RSA key")
+		}
+		cert, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+		sp.Key = key
+		sp.Certificate = cert
+		sp.SignatureMethod = dsig.RSASHA256SignatureMethod
+	}
+	return sp, nil
+}
+
+// flowState links the relay state to the AuthnRequest it was sent with, so
+// only responses to our own requests are accepted.
+type flowState struct {
+	OrganizationID uint   `json:"organization_id"`
+	RequestID      string `json:"request_id"`
+}
+
+// Begin creates an AuthnRequest and returns the identity provider URL to
+// send the user to (HTTP-Redirect binding; the response comes back by POST).
+func Begin(ctx context.Context, sp *saml.ServiceProvider, orgID uint) (string, error) {
+	relayState, err := auth.GenerateRandomToken(24)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := sp.MakeAuthenticationRequest(sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", err
+	}
+	redirect, err := req.Redirect(relayState, sp)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(flowState{OrganizationID: orgID, RequestID: req.ID})
+	if err != nil {
+		return "", err
+	}
+	if err := cache.Default.Set(ctx, "saml:relay:"+relayState, string(data), flowTTL); err != nil {
+		return "", err
+	}
+	return redirect.String(), nil
+}
+
+// Complete validates a posted SAMLResponse: the signature against the
+// identity provider's certificates, issuer, audience, destination, time
+// conditions and that it answers the request from Begin. The relay state
+// can be used once.
+func Complete(ctx context.Context, sp *saml.ServiceProvider, orgID uint, mapping AttributeMapping, samlResponse, relayState string) (*Identity, error) {
+	key := "saml:relay:" + relayState
+	data, err := cache.Default.Get(ctx, key)
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, ErrInvalidRelayState
+	}
+	if err != nil {
+		return nil, err
+	}
+	fresh, err := cache.Default.SetNX(ctx, key+":used", "1", flowTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrInvalidRelayState
+	}
+	_ = cache.Default.Delete(ctx, key)
+
+	var fs flowState
+	if err := json.Unmarshal([]byte(data), &fs); err != nil || fs.OrganizationID != orgID {
+		return nil, ErrInvalidRelayState
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	assertion, err := sp.ParseXMLResponse(raw, []string{fs.RequestID})
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			err = invalid.PrivateErr
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	return MapAssertion(assertion, mapping)
+}
+
+// MapAssertion extracts the identity from a validated assertion.
+func MapAssertion(assertion *saml.Assertion, mapping AttributeMapping) (*Identity, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidResponse)
+	}
+	nameID := assertion.Subject.NameID
+
+	values := map[string]string{}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			if len(attr.Values) == 0 {
+				continue
+			}
+			values[attr.Name] = attr.Values[0].Value
+			if attr.FriendlyName != "" {
+				values[attr.FriendlyName] = attr.Values[0].Value
+			}
+		}
+	}
+	lookup := func(field, configured string) string {
+		names := defaultAttributes[field]
+		if configured != "" {
+			names = []string{configured}
+		}
+		for _, name := range names {
+			if v := strings.TrimSpace(values[name]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	identity := &Identity{
+		Subject:     nameID.Value,
+		Email:       strings.ToLower(lookup("email", mapping.Email)),
+		FirstName:   lookup("first_name", mapping.FirstName),
+		LastName:    lookup("last_name", mapping.LastName),
+		DisplayName: lookup("display_name", mapping.DisplayName),
+	}
+	if identity.Email == "" && nameID.Format == string(saml.EmailAddressNameIDFormat) {
+		identity.Email = strings.ToLower(nameID.Value)
+	}
+	if identity.Email == "" {
+		return nil, ErrMissingEmail
+	}
+	return identity, nil
+}
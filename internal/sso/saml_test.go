@@ -0,0 +1,307 @@
+package sso
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+
+	"github.com/phonsing-Hub/GoLang/internal/config"
+	"github.com/phonsing-Hub/GoLang/internal/database/models"
+)
+
+const testOrgID = 7
+
+// newIdentityProvider is a SAML identity provider with a freshly generated
+// key and self-signed certificate.
+func newIdentityProvider(t *testing.T) *saml.IdentityProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &saml.IdentityProvider{
+		Key:         key,
+		Certificate: cert,
+		MetadataURL: url.URL{Scheme: "https", Host: "idp.example", Path: "/metadata"},
+		SSOURL:      url.URL{Scheme: "https", Host: "idp.example", Path: "/sso"},
+	}
+}
+
+// spProvider serves the metadata of our service provider to the identity
+// provider
+type spProvider struct {
+	metadata *saml.EntityDescriptor
+}
+
+func (p spProvider) GetServiceProvider(r *http.Request, id string) (*saml.EntityDescriptor, error) {
+	return p.metadata, nil
+}
+
+// newServiceProvider builds the organization's service provider trusting
+// idp's metadata.
+func newServiceProvider(t *testing.T, idp *saml.IdentityProvider) *saml.ServiceProvider {
+	t.Helper()
+	config.Env = &config.Config{APIBaseURL: "https://api.example/api/v1"}
+
+	metadata, err := xml.Marshal(idp.Metadata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp, err := ServiceProvider(&models.Organization{ID: testOrgID, Slug: "acme"}, &models.OrganizationSSO{IdPMetadata: string(metadata)})
+	if err != nil {
+		t.Fatalf("ServiceProvider: %v", err)
+	}
+	return sp
+}
+
+// signIn runs Begin and lets signer answer the request for session. It
+// returns the posted SAMLResponse and RelayState.
+func signIn(t *testing.T, sp *saml.ServiceProvider, signer *saml.IdentityProvider, session *saml.Session) (string, string) {
+	t.Helper()
+	authURL, err := Begin(context.Background(), sp, testOrgID)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	httpReq, err := http.NewRequest(http.MethodGet, authURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer.ServiceProviderProvider = spProvider{metadata: sp.Metadata()}
+	req, err := saml.NewIdpAuthnRequest(signer, httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("identity provider rejected the AuthnRequest: %v", err)
+	}
+	if err := (saml.DefaultAssertionMaker{}).MakeAssertion(req, session); err != nil {
+		t.Fatal(err)
+	}
+	form, err := req.PostBinding()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.SAMLResponse, form.RelayState
+}
+
+func janeSession() *saml.Session {
+	return &saml.Session{
+		ID:            "session-1",
+		CreateTime:    time.Now(),
+		ExpireTime:    time.Now().Add(time.Hour),
+		NameID:        "00u1jane",
+		UserGivenName: "Jane",
+		UserSurname:   "Doe",
+		CustomAttributes: []saml.Attribute{
+			{Name: "email", Values: []saml.AttributeValue{{Type: "xs:string", Value: "Jane@Acme.example"}}},
+			{Name: "displayName", Values: []saml.AttributeValue{{Type: "xs:string", Value: "Jane D."}}},
+		},
+	}
+}
+
+func TestCompleteValidResponse(t *testing.T) {
+	idp := newIdentityProvider(t)
+	sp := newServiceProvider(t, idp)
+	samlResponse, relayState := signIn(t, sp, idp, janeSession())
+
+	identity, err := Complete(context.Background(), sp, testOrgID, AttributeMapping{}, samlResponse, relayState)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	want := Identity{
+		Subject:     "00u1jane",
+		Email:       "jane@acme.example",
+		FirstName:   "Jane",
+		LastName:    "Doe",
+		DisplayName: "Jane D.",
+	}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+
+	// The relay state can be used once
+	if _, err := Complete(context.Background(), sp, testOrgID, AttributeMapping{}, samlResponse, relayState); !errors.Is(err, ErrInvalidRelayState) {
+		t.Fatalf("replayed response: err = %v, want ErrInvalidRelayState", err)
+	}
+}
+
+func TestCompleteTamperedResponse(t *testing.T) {
+	idp := newIdentityProvider(t)
+	sp := newServiceProvider(t, idp)
+	samlResponse, relayState := signIn(t, sp, idp, janeSession())
+
+	raw, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Replace(raw, []byte("Jane@Acme.example"), []byte("ceo@acme.example"), 1)
+	if bytes.Equal(raw, tampered) {
+		t.Fatal("email not found in the response")
+	}
+
+	_, err = Complete(context.Background(), sp, testOrgID, AttributeMapping{}, base64.StdEncoding.EncodeToString(tampered), relayState)
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("err = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestCompleteWrongCertificate(t *testing.T) {
+	idp := newIdentityProvider(t)
+	sp := newServiceProvider(t, idp)
+
+	// Same entity, but signing with a key the metadata does not name
+	impostor := newIdentityProvider(t)
+	samlResponse, relayState := signIn(t, sp, impostor, janeSession())
+
+	_, err := Complete(context.Background(), sp, testOrgID, AttributeMapping{}, samlResponse, relayState)
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("err = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestCompleteOtherOrganization(t *testing.T) {
+	idp := newIdentityProvider(t)
+	sp := newServiceProvider(t, idp)
+	samlResponse, relayState := signIn(t, sp, idp, janeSession())
+
+	if _, err := Complete(context.Background(), sp, testOrgID+1, AttributeMapping{}, samlResponse, relayState); !errors.Is(err, ErrInvalidRelayState) {
+		t.Fatalf("err = %v, want ErrInvalidRelayState", err)
+	}
+}
+
+func assertionWith(nameID saml.NameID, attributes map[string]string) *saml.Assertion {
+	statement := saml.AttributeStatement{}
+	for name, value := range attributes {
+		statement.Attributes = append(statement.Attributes, saml.Attribute{
+			Name:   name,
+			Values: []saml.AttributeValue{{Value: value}},
+		})
+	}
+	return &saml.Assertion{
+		Subject:             &saml.Subject{NameID: &nameID},
+		AttributeStatements: []saml.AttributeStatement{statement},
+	}
+}
+
+func TestMapAssertion(t *testing.T) {
+	tests := []struct {
+		name      string
+		assertion *saml.Assertion
+		mapping   AttributeMapping
+		want      Identity
+		wantErr   error
+	}{
+		{
+			name: "azure claim names",
+			assertion: assertionWith(saml.NameID{Value: "aad-1"}, map[string]string{
+				"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress": "Sam@Corp.example",
+				"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname":    "Sam",
+				"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/surname":      "Smith",
+				"http://schemas.microsoft.com/identity/claims/displayname":           "Sam Smith",
+			}),
+			want: Identity{Subject: "aad-1", Email: "sam@corp.example", FirstName: "Sam", LastName: "Smith", DisplayName: "Sam Smith"},
+		},
+		{
+			name: "configured mapping wins over the defaults",
+			assertion: assertionWith(saml.NameID{Value: "u-2"}, map[string]string{
+				"email":     "shared@corp.example",
+				"corpEmail": "lee@corp.example",
+				"nickname":  "Lee",
+			}),
+			mapping: AttributeMapping{Email: "corpEmail", DisplayName: "nickname"},
+			want:    Identity{Subject: "u-2", Email: "lee@corp.example", DisplayName: "Lee"},
+		},
+		{
+			name:      "email name id",
+			assertion: assertionWith(saml.NameID{Value: "Kim@Corp.example", Format: string(saml.EmailAddressNameIDFormat)}, nil),
+			want:      Identity{Subject: "Kim@Corp.example", Email: "kim@corp.example"},
+		},
+		{
+			name:      "no email",
+			assertion: assertionWith(saml.NameID{Value: "u-3"}, map[string]string{"givenName": "Pat"}),
+			wantErr:   ErrMissingEmail,
+		},
+		{
+			name:      "no subject",
+			assertion: &saml.Assertion{},
+			wantErr:   ErrInvalidResponse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := MapAssertion(tt.assertion, tt.mapping)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *identity != tt.want {
+				t.Fatalf("identity = %+v, want %+v", *identity, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyDomain(t *testing.T) {
+	defer func(lookup func(context.Context, string) ([]string, error)) { lookupTXT = lookup }(lookupTXT)
+	records := map[string][]string{
+		"_sso-verification.acme.example": {"v=spf1 -all", "sso-domain-verification=token-1"},
+	}
+	lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		if r, ok := records[name]; ok {
+			return r, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	tests := []struct {
+		domain, token string
+		want          bool
+	}{
+		{"acme.example", "token-1", true},
+		{"acme.example", "token-2", false},
+		{"other.example", "token-1", false},
+	}
+	for _, tt := range tests {
+		got, err := VerifyDomain(context.Background(), tt.domain, tt.token)
+		if err != nil {
+			t.Fatalf("VerifyDomain(%s, %s): %v", tt.domain, tt.token, err)
+		}
+		if got != tt.want {
+			t.Errorf("VerifyDomain(%s, %s) = %t, want %t", tt.domain, tt.token, got, tt.want)
+		}
+	}
+}
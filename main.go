@@ -1,17 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	_ "github.com/phonsing-Hub/GoLang/docs"
+	"github.com/phonsing-Hub/GoLang/internal/cache"
 	"github.com/phonsing-Hub/GoLang/internal/config"
 	"github.com/phonsing-Hub/GoLang/internal/database"
+	"github.com/phonsing-Hub/GoLang/internal/mailer"
 	"github.com/phonsing-Hub/GoLang/internal/middleware"
+	"github.com/phonsing-Hub/GoLang/internal/oauth"
 	"github.com/phonsing-Hub/GoLang/internal/routes"
 	_ "github.com/phonsing-Hub/GoLang/internal/routes/api"
+	"github.com/phonsing-Hub/GoLang/pkg/jwt"
 )
 
 // @title GoLang API
@@ -25,9 +30,30 @@ import (
 
 func main() {
 	config.LoadEnv()
+	if err := jwt.LoadKeys(); err != nil {
+		log.Fatalf("failed to load JWT keys: %v", err)
+	}
 	if err := database.Init(config.Env.DBUrl); err != nil {
 		log.Fatalf("failed to connect database: %v", err)
 	}
+	manager, err := database.NewManager(context.Background(), &database.Config{
+		Redis: database.RedisConfig{
+			Host:     config.Env.RedisHost,
+			Port:     config.Env.RedisPort,
+			Password: config.Env.RedisPassword,
+			DB:       config.Env.RedisDB,
+		},
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize database manager: %v", err)
+	}
+	cache.Init(manager.Cache)
+	if err := mailer.Init(); err != nil {
+		log.Fatalf("failed to configure mailer: %v", err)
+	}
+	if err := oauth.Init(); err != nil {
+		log.Fatalf("failed to configure oauth providers: %v", err)
+	}
 	app := fiber.New()
 	app.Static("/static", "./static/uploads")
 
@@ -38,11 +64,12 @@ func main() {
 	})
 	app_v1.Use(cors.New(cors.Config{
 		AllowOrigins: config.Env.CORSAllowOrigins,
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Device-ID",
+		AllowMethods: "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 	}))
 	routes.SetupRoutes(app_v1)
 	routes.SetupMonitorRoute(app)
+	routes.SetupWellKnownRoutes(app)
 
 	app.Mount("/api/v1", app_v1)
 	app.Listen(fmt.Sprintf(":%s", config.Env.AppPort))
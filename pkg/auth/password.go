@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+)
+
+// Hasher is one password hashing scheme. Hashes are stored in a self
+// describing format (PHC strings for argon2id, $2b$ for bcrypt), so the
+// scheme and its parameters can be told from the hash alone.
+type Hasher interface {
+	// ID names the scheme, e.g. "argon2id".
+	ID() string
+	// Recognizes reports whether encoded was produced by this scheme.
+	Recognizes(encoded string) bool
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether encoded was made with parameters that
+	// differ from the ones new hashes get.
+	NeedsRehash(encoded string) bool
+}
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// defaultHasher hashes new passwords. The other hashers are only kept to
+// verify existing hashes until they are upgraded on login.
+var defaultHasher = sync.OnceValue(func() Hasher {
+	return newArgon2idHasher()
+})
+
+func hashers() []Hasher {
+	return []Hasher{defaultHasher(), bcryptHasher{}}
+}
+
+func hasherFor(encoded string) (Hasher, error) {
+	for _, h := range hashers() {
+		if h.Recognizes(encoded) {
+			return h, nil
+		}
+	}
+	return nil, ErrUnknownHash
+}
+
+// HashPassword hashes the password with the default scheme, argon2id.
+func HashPassword(password string) (string, error) {
+	return defaultHasher().Hash(password)
+}
+
+// CheckPasswordHash checks if the provided password matches the hashed password.
+func CheckPasswordHash(password, hash string) bool {
+	ok, _ := VerifyPassword(password, hash)
+	return ok
+}
+
+// VerifyPassword checks the password and reports whether the hash should be
+// replaced by HashPassword(password) because it uses an older scheme or
+// outdated parameters.
+func VerifyPassword(password, hash string) (ok bool, needsRehash bool) {
+	h, err := hasherFor(hash)
+	if err != nil {
+		return false, false
+	}
+	ok, err = h.Verify(password, hash)
+	if err != nil || !ok {
+		return false, false
+	}
+	return true, h.ID() != defaultHasher().ID() || h.NeedsRehash(hash)
+}
+
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("not-a-real-password")
+	return hash
+})
+
+// CheckPasswordDummy takes as long as CheckPasswordHash but always fails.
+// Use it when there is no hash to compare against, so response timing does
+// not reveal whether an account exists.
+func CheckPasswordDummy(password string) bool {
+	CheckPasswordHash(password, dummyHash())
+	return false
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/phonsing-Hub/GoLang/internal/config"
+)
+
+var (
+	ErrPasswordTooShort = errors.New("password is too short")
+	ErrPasswordTooWeak  = errors.New("password is too easy to guess, use a longer password or more kinds of characters")
+	ErrPasswordBreached = errors.New("password appears in a list of breached passwords, choose a different one")
+)
+
+// ValidatePassword applies the password policy: a minimum length, a minimum
+// estimated entropy, and no match in the breached password list. Passwords
+// containing the user's email name are rejected as well.
+func ValidatePassword(password, email string) error {
+	minLength, minEntropy := 10, 50
+	if config.Env != nil {
+		minLength, minEntropy = config.Env.PasswordMinLength, config.Env.PasswordMinEntropy
+	}
+
+	if utf8.RuneCountInString(password) < minLength {
+		return fmt.Errorf("%w, use at least %d characters", ErrPasswordTooShort, minLength)
+	}
+	if name, _, ok := strings.Cut(strings.ToLower(email), "@"); ok && len(name) >= 4 &&
+		strings.Contains(strings.ToLower(password), name) {
+		return ErrPasswordTooWeak
+	}
+	if PasswordEntropy(password) < float64(minEntropy) {
+		return ErrPasswordTooWeak
+	}
+	if isBreached(password) {
+		return ErrPasswordBreached
+	}
+	return nil
+}
+
+// PasswordEntropy estimates the strength of a password in bits from the
+// kinds of characters it uses. Characters that repeat or continue a
+// sequence of the previous one (aaa, abc, 321) do not add to it.
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	var counted int
+	prev := rune(-1)
+	for _, r := range password {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLower(r):
+			lower = true
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+		if d := r - prev; d < -1 || d > 1 {
+			counted++
+		}
+		prev = r
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(counted) * math.Log2(float64(pool))
+}
+
+// breachedList holds the upper case SHA-1 of every password in
+// PASSWORD_BREACHED_LIST. Lines are either plain passwords or SHA-1 hashes
+// in the Have I Been Pwned format (HASH or HASH:COUNT).
+var breachedList = sync.OnceValue(func() map[string]struct{} {
+	if config.Env == nil || config.Env.PasswordBreachedList == "" {
+		return nil
+	}
+	list, err := loadBreachedList(config.Env.PasswordBreachedList)
+	if err != nil {
+		log.Printf("auth: breached password list disabled: %v", err)
+		return nil
+	}
+	return list
+})
+
+func loadBreachedList(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1Hex(hash) {
+			list[strings.ToUpper(hash)] = struct{}{}
+			continue
+		}
+		list[sha1Hex(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return list, nil
+}
+
+func isBreached(password string) bool {
+	list := breachedList()
+	if list == nil {
+		return false
+	}
+	_, found := list[sha1Hex(password)]
+	return found
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -4,16 +4,75 @@ import (
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/phonsing-Hub/GoLang/internal/config"
+	"strings"
 	"time"
 )
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid,omitempty"` // refresh token family the access token was issued for
+	// Scope limits delegated credentials such as personal access tokens.
+	// Tokens from an interactive login have no scope and full access.
+	Scope string `json:"scope,omitempty"`
+	// ClientID is set on tokens issued to an API client instead of a login,
+	// e.g. for organization service accounts.
+	ClientID string `json:"client_id,omitempty"`
+	// Act names the real caller when the token is used on behalf of the
+	// user, e.g. by an admin impersonating them (RFC 8693 actor claim).
+	Act *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor identifies who is acting on behalf of the token's user.
+type Actor struct {
+	Subject string `json:"sub"`
+	UserID  uint   `json:"user_id"`
+	Email   string `json:"email,omitempty"`
+}
+
+// Scopes returns the space separated scope claim as a list.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope reports whether the token may be used for scope. Delegated
+// tokens are limited to the scopes they list, so an empty scope grants
+// nothing.
+func (c *Claims) HasScope(scope string) bool {
+	if !c.IsDelegated() {
+		return true
+	}
+	for _, s := range c.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSession reports whether the token comes from an interactive login
+// rather than from a delegated credential, an API client or an
+// impersonation.
+func (c *Claims) IsSession() bool {
+	return c.SessionID != "" && c.Scope == "" && c.ClientID == "" && c.Act == nil
+}
+
+// IsDelegated reports whether the token was handed to a personal access
+// token, an app or a service account, which are limited to their scopes.
+// Any token that is neither a login session nor an impersonation counts as
+// delegated.
+func (c *Claims) IsDelegated() bool {
+	return !c.IsSession() && !c.IsImpersonated()
+}
+
+// IsImpersonated reports whether someone else is acting as the user.
+func (c *Claims) IsImpersonated() bool {
+	return c.Act != nil
+}
+
 // var SecretKey = []byte(config.Env.JWTSecret)
 
 func getSecretKey() ([]byte, error) {
@@ -25,37 +84,96 @@ func getSecretKey() ([]byte, error) {
     return []byte(config.Env.JWTSecret), nil
 }
 
-func GenerateToken(userID uint, email string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
-	SecretKey, _ := getSecretKey()
+// GenerateToken issues a short-lived access token. Its lifetime is
+// config.Env.JWTAccessTTL; long-lived sessions are kept alive with refresh tokens.
+func GenerateToken(userID uint, email string, sessionID string) (string, error) {
+	expirationTime := time.Now().Add(config.Env.JWTAccessTTL)
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "your-api-issuer",
+			Issuer:    config.Env.JWTIssuer,
+			Subject:   fmt.Sprintf("%d", userID),
+		},
+	}
+	if config.Env.JWTAudience != "" {
+		claims.Audience = jwt.ClaimStrings{config.Env.JWTAudience}
+	}
+	tokenString, err := sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return tokenString, nil
+}
+
+// GenerateClientToken issues an access token to an API client acting as
+// userID. It has no session and cannot be refreshed.
+func GenerateClientToken(userID uint, email, clientID, scope string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID:   userID,
+		Email:    email,
+		Scope:    scope,
+		ClientID: clientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    config.Env.JWTIssuer,
 			Subject:   fmt.Sprintf("%d", userID),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(SecretKey)
+	if config.Env.JWTAudience != "" {
+		claims.Audience = jwt.ClaimStrings{config.Env.JWTAudience}
+	}
+	tokenString, err := sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
 	return tokenString, nil
 }
 
+// GenerateImpersonationToken issues an access token for userID that is
+// used by actor and returns it with its id. It has no session, so it
+// cannot be refreshed and ends at the latest after ttl.
+func GenerateImpersonationToken(userID uint, email string, actor Actor, ttl time.Duration) (string, string, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID: userID,
+		Email:  email,
+		Act:    &actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    config.Env.JWTIssuer,
+			Subject:   fmt.Sprintf("%d", userID),
+		},
+	}
+	if config.Env.JWTAudience != "" {
+		claims.Audience = jwt.ClaimStrings{config.Env.JWTAudience}
+	}
+	tokenString, err := sign(claims)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return tokenString, claims.ID, nil
+}
+
 func ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
-	SecretKey, _ := getSecretKey()
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return SecretKey, nil
-	})
+	options := []jwt.ParserOption{jwt.WithIssuer(config.Env.JWTIssuer)}
+	if config.Env.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(config.Env.JWTAudience))
+	}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, options...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
@@ -0,0 +1,172 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is one asymmetric key of the key set. Verify-only keys, e.g.
+// the public half of a key that another instance signs with, have no Private.
+type SigningKey struct {
+	KID     string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+	Retired bool
+}
+
+// KeySet holds every configured key, indexed by kid.
+type KeySet struct {
+	keys    map[string]*SigningKey
+	order   []string
+	signing *SigningKey
+}
+
+// NewKeySet builds a key set. signingKID selects the key used for new
+// tokens; retired kids are neither accepted nor published.
+func NewKeySet(keys []*SigningKey, signingKID string, retired []string) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*SigningKey)}
+	for _, key := range keys {
+		if _, dup := ks.keys[key.KID]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", key.KID)
+		}
+		ks.keys[key.KID] = key
+		ks.order = append(ks.order, key.KID)
+	}
+	for _, kid := range retired {
+		if key, ok := ks.keys[kid]; ok {
+			key.Retired = true
+		}
+	}
+
+	if signingKID == "" && len(ks.order) > 0 {
+		signingKID = ks.order[0]
+	}
+	if signingKID != "" {
+		key, ok := ks.keys[signingKID]
+		if !ok {
+			return nil, fmt.Errorf("signing key %q is not configured", signingKID)
+		}
+		if key.Private == nil {
+			return nil, fmt.Errorf("signing key %q has no private key", signingKID)
+		}
+		if key.Retired {
+			return nil, fmt.Errorf("signing key %q is retired", signingKID)
+		}
+		ks.signing = key
+	}
+	return ks, nil
+}
+
+// Signing returns the key new tokens are signed with, or nil.
+func (ks *KeySet) Signing() *SigningKey {
+	return ks.signing
+}
+
+// Lookup returns a key that may be used for validation.
+func (ks *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	key, ok := ks.keys[kid]
+	if !ok || key.Retired {
+		return nil, false
+	}
+	return key, true
+}
+
+// LoadKeyFile reads a PEM encoded RSA or Ed25519 key. Both private keys
+// (PKCS#1 or PKCS#8) and public keys (PKIX) are accepted.
+func LoadKeyFile(kid, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %q: %w", kid, err)
+	}
+	return ParseKey(kid, data)
+}
+
+// ParseKey parses a PEM encoded key, see LoadKeyFile.
+func ParseKey(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM block found", kid)
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %q: unsupported PEM block %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", kid, err)
+	}
+
+	key := &SigningKey{KID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("key %q: unsupported key type %T", kid, parsed)
+	}
+	return key, nil
+}
+
+// JWK is the public part of a key as published in the JWKS document.
+type JWK struct {
+	KID string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is the RFC 7517 document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that are currently accepted.
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, kid := range ks.order {
+		key := ks.keys[kid]
+		if key.Retired {
+			continue
+		}
+		jwk := JWK{KID: key.KID, Alg: key.Method.Alg(), Use: "sig"}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,101 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+)
+
+func newKey(t *testing.T, kid string) *SigningKey {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SigningKey{KID: kid, Method: jwt.SigningMethodEdDSA, Private: private, Public: public}
+}
+
+func useKeys(t *testing.T, keys []*SigningKey, signingKID string, retired ...string) {
+	t.Helper()
+	// NewKeySet marks retired keys on the shared records
+	for _, key := range keys {
+		key.Retired = false
+	}
+	ks, err := NewKeySet(keys, signingKID, retired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySet = ks
+}
+
+func TestKeyRotation(t *testing.T) {
+	config.Env = &config.Config{JWTSecret: "test-secret", JWTIssuer: "test", JWTAccessTTL: time.Minute}
+	t.Cleanup(func() { keySet = nil })
+	old, current := newKey(t, "2025-01"), newKey(t, "2025-06")
+
+	useKeys(t, []*SigningKey{old}, "")
+	before, err := GenerateToken(1, "jane@example.com", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new signing key is added; tokens of the old one stay valid
+	useKeys(t, []*SigningKey{old, current}, current.KID)
+	after, err := GenerateToken(1, "jane@example.com", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, token := range map[string]string{"old key": before, "new key": after} {
+		if _, err := ValidateToken(token); err != nil {
+			t.Errorf("token signed with the %s: %v", name, err)
+		}
+	}
+	if kids := len(JWKS().Keys); kids != 2 {
+		t.Errorf("JWKS publishes %d keys, want 2", kids)
+	}
+
+	// Once retired, the old key is neither accepted nor published
+	useKeys(t, []*SigningKey{old, current}, current.KID, old.KID)
+	if _, err := ValidateToken(before); err == nil {
+		t.Error("a token signed with a retired key was accepted")
+	}
+	if _, err := ValidateToken(after); err != nil {
+		t.Errorf("token signed with the current key: %v", err)
+	}
+	if set := JWKS(); len(set.Keys) != 1 || set.Keys[0].KID != current.KID {
+		t.Errorf("JWKS = %+v, want only %s", set, current.KID)
+	}
+}
+
+func TestSharedSecretRefusedWithKeys(t *testing.T) {
+	config.Env = &config.Config{JWTSecret: "test-secret", JWTIssuer: "test", JWTAccessTTL: time.Minute}
+	t.Cleanup(func() { keySet = nil })
+
+	keySet = nil
+	hs256, err := GenerateToken(1, "jane@example.com", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(hs256); err != nil {
+		t.Fatalf("HS256 without asymmetric keys: %v", err)
+	}
+
+	useKeys(t, []*SigningKey{newKey(t, "k1")}, "")
+	if _, err := ValidateToken(hs256); err == nil {
+		t.Error("an HS256 token was accepted once asymmetric keys are configured")
+	}
+}
+
+func TestNewKeySetRefusesRetiredSigningKey(t *testing.T) {
+	key := newKey(t, "k1")
+	if _, err := NewKeySet([]*SigningKey{key}, "k1", []string{"k1"}); err == nil {
+		t.Error("a retired key was accepted for signing")
+	}
+	if _, err := NewKeySet([]*SigningKey{key, newKey(t, "k1")}, "", nil); err == nil {
+		t.Error("duplicate key ids were accepted")
+	}
+}
@@ -0,0 +1,100 @@
+package jwt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/phonsing-Hub/GoLang/internal/config"
+)
+
+// keySet is nil until LoadKeys finds asymmetric keys; access tokens are
+// then signed with HS256 and JWT_SECRET.
+var keySet *KeySet
+
+// LoadKeys loads the keys listed in JWT_KEY_FILES ("kid=path,kid=path").
+// JWT_SIGNING_KID picks the key for new tokens and JWT_RETIRED_KIDS lists
+// keys that are no longer accepted.
+func LoadKeys() error {
+	if config.Env.JWTKeyFiles == "" {
+		keySet = nil
+		return nil
+	}
+
+	var keys []*SigningKey
+	for _, entry := range splitList(config.Env.JWTKeyFiles) {
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || path == "" {
+			return fmt.Errorf("invalid JWT_KEY_FILES entry %q, expected kid=path", entry)
+		}
+		key, err := LoadKeyFile(kid, path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	ks, err := NewKeySet(keys, config.Env.JWTSigningKID, splitList(config.Env.JWTRetiredKIDs))
+	if err != nil {
+		return err
+	}
+	keySet = ks
+	return nil
+}
+
+// JWKS returns the public keys other services can verify our tokens with.
+func JWKS() JWKSet {
+	if keySet == nil {
+		return JWKSet{Keys: []JWK{}}
+	}
+	return keySet.JWKS()
+}
+
+func sign(claims jwt.Claims) (string, error) {
+	if keySet == nil {
+		SecretKey, err := getSecretKey()
+		if err != nil {
+			return "", err
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(SecretKey)
+	}
+
+	key := keySet.Signing()
+	if key == nil {
+		return "", fmt.Errorf("no signing key configured")
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.Private)
+}
+
+// keyFunc picks the verification key by kid. Once asymmetric keys are
+// configured HS256 tokens are refused, which also rules out alg confusion.
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	if keySet == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return getSecretKey()
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := keySet.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown or retired key id %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
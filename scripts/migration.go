@@ -7,6 +7,7 @@ import (
 	"github.com/phonsing-Hub/GoLang/internal/config"
 	"github.com/phonsing-Hub/GoLang/internal/database"
 	"github.com/phonsing-Hub/GoLang/internal/database/models"
+	"github.com/phonsing-Hub/GoLang/internal/database/policies"
 	"github.com/phonsing-Hub/GoLang/internal/database/views"
 )
 
@@ -44,6 +45,13 @@ func main() {
 		log.Printf("View created: %s", name)
 	}
 
+	// row-level security, only enforced when TENANT_RLS is enabled
+	log.Println("Managing tenant isolation policies...")
+	if err := policies.Apply(database.DB, config.Env.TenantRLS); err != nil {
+		log.Fatalf("failed to manage policies: %v", err)
+	}
+	log.Printf("Policies managed on %d tables (enforced: %t)", len(policies.Tables), config.Env.TenantRLS)
+
 	//default userstatus
 	defaultStatuses := []*models.UserStatus{
 		{Name: "active", DisplayName: "Active", Description: "User is active", Color: "#28a745", IsActive: true, Position: 1},
@@ -55,6 +63,17 @@ func main() {
 		log.Fatalf("failed to create default user statuses: %v", err)
 	}
 
+	// default member statuses, active must stay ID 1 (the column default)
+	defaultMemberStatuses := []*models.MemberStatus{
+		{Name: "active", DisplayName: "Active", Description: "Member is active", Color: "#28a745", IsActive: true, Position: 1},
+		{Name: "invited", DisplayName: "Invited", Description: "Member has been invited and not yet accepted", Color: "#17a2b8", IsActive: true, Position: 2},
+		{Name: "suspended", DisplayName: "Suspended", Description: "Member is suspended", Color: "#dc3545", IsActive: true, Position: 3},
+		{Name: "inactive", DisplayName: "Inactive", Description: "Member is inactive", Color: "#6c757d", IsActive: true, Position: 4},
+	}
+	if err := database.DB.Create(defaultMemberStatuses).Error; err != nil {
+		log.Fatalf("failed to create default member statuses: %v", err)
+	}
+
 	log.Println("Migration completed successfully")
 }
 